@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func TestSimulatedBackend(t *testing.T) {
@@ -81,3 +82,54 @@ func TestSimulatedBackend(t *testing.T) {
 	}
 
 }
+
+// Tests that reverted calls and gas estimations report the revert reason.
+func TestSimulatedBackendRevert(t *testing.T) {
+	// Contract reverting with Error("foo") if called with value, otherwise
+	// incrementing a counter and returning its new value
+	var (
+		contract = common.HexToAddress("0xc0")
+		code     = common.FromHex("3415605c577f08c379a000000000000000000000000000000000000000000000000000000000600052602060045260036024527f666f6f000000000000000000000000000000000000000000000000000000000060445260646000fd5b6000546001018060005560005260206000a060206000f3")
+	)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}, 8000000)
+	defer sim.Close()
+
+	res, err := sim.CallContract(context.Background(), ethereum.CallMsg{To: &contract}, nil)
+	if err != nil {
+		t.Fatalf("failed to call contract: %v", err)
+	}
+	if new(big.Int).SetBytes(res).Uint64() != 1 {
+		t.Fatalf("call result mismatch: have %x, want 1", res)
+	}
+	want := "0x08c379a000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000003666f6f0000000000000000000000000000000000000000000000000000000000"
+
+	calls := map[string]func() error{
+		"call": func() error {
+			_, err := sim.CallContract(context.Background(), ethereum.CallMsg{To: &contract, Value: big.NewInt(1)}, nil)
+			return err
+		},
+		"pending call": func() error {
+			_, err := sim.PendingCallContract(context.Background(), ethereum.CallMsg{To: &contract, Value: big.NewInt(1)})
+			return err
+		},
+		"estimate": func() error {
+			_, err := sim.EstimateGas(context.Background(), ethereum.CallMsg{To: &contract, Value: big.NewInt(1)})
+			return err
+		},
+	}
+	for name, call := range calls {
+		err := call()
+		if err == nil {
+			t.Errorf("%s: expected revert error", name)
+			continue
+		}
+		if err.Error() != "execution reverted: foo" {
+			t.Errorf("%s: error message mismatch: have %q, want %q", name, err, "execution reverted: foo")
+		}
+		if de, ok := err.(rpc.DataError); !ok {
+			t.Errorf("%s: error carries no data: %T", name, err)
+		} else if de.ErrorData() != want {
+			t.Errorf("%s: error data mismatch: have %v, want %v", name, de.ErrorData(), want)
+		}
+	}
+}
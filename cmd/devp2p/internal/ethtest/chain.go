@@ -0,0 +1,208 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Names of the fixture files written by Chain.Write and read by LoadChain.
+const (
+	GenesisFile = "genesis.json"
+	ChainFile   = "chain.rlp"
+)
+
+var (
+	// testKey is the account funded in generated fixtures. The transaction
+	// tests of the suite are signed with it.
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testFunds   = new(big.Int).Mul(big.NewInt(1000000), big.NewInt(params.Ether))
+
+	// recipient is the receiver of the transfers contained in generated fixtures.
+	recipient = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+)
+
+// Chain is the block chain the target node is expected to serve.
+type Chain struct {
+	genesis *core.Genesis
+	blocks  []*types.Block // blocks[0] is the genesis block
+}
+
+// GenerateChain creates a chain of n blocks on top of a fresh genesis using
+// core.GenerateChain. Every block contains one value transfer from the test
+// account, so that body queries return non-empty results. The blocks are
+// not sealed, the target node must be run with fake proof-of-work.
+func GenerateChain(n int) *Chain {
+	config := *params.AllEthashProtocolChanges
+	genesis := &core.Genesis{
+		Config:     &config,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: params.MinimumDifficulty,
+		Alloc:      core.GenesisAlloc{testAddress: {Balance: testFunds}},
+	}
+	db := rawdb.NewMemoryDatabase()
+	gblock := genesis.MustCommit(db)
+
+	signer := types.NewEIP155Signer(config.ChainID)
+	blocks, _ := core.GenerateChain(&config, gblock, ethash.NewFaker(), db, n, func(i int, b *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(testAddress), recipient, big.NewInt(1), params.TxGas, big.NewInt(params.GWei), nil), signer, testKey)
+		if err != nil {
+			panic(err)
+		}
+		b.AddTx(tx)
+	})
+	return &Chain{genesis: genesis, blocks: append([]*types.Block{gblock}, blocks...)}
+}
+
+// LoadChain reads a chain fixture from the given genesis and chain files.
+func LoadChain(genesisFile, chainFile string) (*Chain, error) {
+	genesis := new(core.Genesis)
+	data, err := ioutil.ReadFile(genesisFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	blocks := []*types.Block{genesis.ToBlock(nil)}
+
+	fh, err := os.Open(chainFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	stream := rlp.NewStream(fh, 0)
+	for i := 1; ; i++ {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("at block %d: %v", i, err)
+		}
+		if block.ParentHash() != blocks[len(blocks)-1].Hash() {
+			return nil, fmt.Errorf("block %d (%x) does not extend the chain", block.NumberU64(), block.Hash())
+		}
+		blocks = append(blocks, block)
+	}
+	return &Chain{genesis: genesis, blocks: blocks}, nil
+}
+
+// Write stores the chain as a genesis specification and an RLP block stream
+// in the given directory. The files can be imported into a node with
+// 'geth init' and 'geth import'.
+func (c *Chain) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	genesis, err := json.MarshalIndent(c.genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, GenesisFile), genesis, 0644); err != nil {
+		return err
+	}
+	fh, err := os.Create(filepath.Join(dir, ChainFile))
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	for _, block := range c.blocks[1:] {
+		if err := rlp.Encode(fh, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns the number of blocks in the chain, including genesis.
+func (c *Chain) Len() int {
+	return len(c.blocks)
+}
+
+// Genesis returns the genesis block.
+func (c *Chain) Genesis() *types.Block {
+	return c.blocks[0]
+}
+
+// Head returns the last block of the chain.
+func (c *Chain) Head() *types.Block {
+	return c.blocks[len(c.blocks)-1]
+}
+
+// TD returns the total difficulty of the chain.
+func (c *Chain) TD() *big.Int {
+	td := new(big.Int)
+	for _, block := range c.blocks {
+		td.Add(td, block.Difficulty())
+	}
+	return td
+}
+
+// ChainID returns the chain ID used for signing transactions.
+func (c *Chain) ChainID() *big.Int {
+	return c.genesis.Config.ChainID
+}
+
+// Headers answers a header query against the local chain, mirroring the
+// serving rules of the eth protocol.
+func (c *Chain) Headers(req *GetBlockHeaders) []*types.Header {
+	var origin *types.Block
+	if req.Origin.Hash != (common.Hash{}) {
+		for _, block := range c.blocks {
+			if block.Hash() == req.Origin.Hash {
+				origin = block
+				break
+			}
+		}
+	} else if req.Origin.Number < uint64(len(c.blocks)) {
+		origin = c.blocks[req.Origin.Number]
+	}
+	var headers []*types.Header
+	if origin == nil {
+		return headers
+	}
+	for number := int64(origin.NumberU64()); uint64(len(headers)) < req.Amount; {
+		if number < 0 || number >= int64(len(c.blocks)) {
+			break
+		}
+		headers = append(headers, c.blocks[number].Header())
+		if req.Reverse {
+			number -= int64(req.Skip) + 1
+		} else {
+			number += int64(req.Skip) + 1
+		}
+	}
+	return headers
+}
@@ -0,0 +1,511 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ethtest implements a conformance test suite for the eth wire protocol.
+// The suite connects to a node over RLPx and runs scripted conversations against
+// it, checking the answers against a locally known chain.
+package ethtest
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// timeout is the maximum time to wait for a single answer of the node.
+	timeout = 10 * time.Second
+
+	// maxBodies is the number of bodies requested by the body test.
+	maxBodies = 16
+)
+
+var (
+	errTimeout      = errors.New("timeout")
+	errDisconnected = errors.New("node disconnected")
+)
+
+// Test is a single conformance test.
+type Test struct {
+	Name string
+	Fn   func(*Suite) error
+}
+
+// Result is the outcome of a single test.
+type Result struct {
+	Name     string
+	Err      error // nil if the test passed
+	Duration time.Duration
+}
+
+// Suite runs conformance tests against a single node.
+type Suite struct {
+	Dest  *enode.Node
+	chain *Chain
+
+	nonce    uint64   // next nonce of the test account
+	txPrice  *big.Int // gas price of the last transaction sent, bumped for replacements
+	txSigner types.Signer
+}
+
+// NewSuite creates a test suite for the given node. The node is expected to
+// serve the given chain.
+func NewSuite(dest *enode.Node, chain *Chain) *Suite {
+	s := &Suite{
+		Dest:     dest,
+		chain:    chain,
+		txPrice:  big.NewInt(params.GWei),
+		txSigner: types.NewEIP155Signer(chain.ChainID()),
+	}
+	// Find the next nonce of the test account by counting its transfers.
+	for _, block := range chain.blocks {
+		for _, tx := range block.Transactions() {
+			if from, err := types.Sender(s.txSigner, tx); err == nil && from == testAddress {
+				s.nonce++
+			}
+		}
+	}
+	return s
+}
+
+// AllTests returns all tests of the suite in the order they should be run.
+func (s *Suite) AllTests() []Test {
+	return []Test{
+		{"Status", (*Suite).TestStatus},
+		{"GetBlockHeaders", (*Suite).TestGetBlockHeaders},
+		{"GetBlockBodies", (*Suite).TestGetBlockBodies},
+		{"Transaction", (*Suite).TestTransaction},
+		{"TransactionPropagation", (*Suite).TestTransactionPropagation},
+		{"MalformedHeaderQuery", (*Suite).TestMalformedHeaderQuery},
+		{"UnknownMessageCode", (*Suite).TestUnknownMessageCode},
+		{"RepeatedStatus", (*Suite).TestRepeatedStatus},
+	}
+}
+
+// Run executes the given tests sequentially and collects their results.
+func (s *Suite) Run(tests []Test) []Result {
+	results := make([]Result, len(tests))
+	for i, test := range tests {
+		start := time.Now()
+		results[i] = Result{Name: test.Name, Err: test.Fn(s), Duration: time.Since(start)}
+	}
+	return results
+}
+
+// TestStatus performs the protocol and status handshakes and checks that the
+// node is on the expected chain.
+func (s *Suite) TestStatus() error {
+	conn, status, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	if status.Head != s.chain.Head().Hash() {
+		return fmt.Errorf("wrong head block %x, want %x", status.Head, s.chain.Head().Hash())
+	}
+	if status.TD.Cmp(s.chain.TD()) != 0 {
+		return fmt.Errorf("wrong total difficulty %v, want %v", status.TD, s.chain.TD())
+	}
+	return nil
+}
+
+// TestGetBlockHeaders runs a set of header queries and compares the answers
+// with the local chain.
+func (s *Suite) TestGetBlockHeaders() error {
+	conn, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	head := s.chain.Head().NumberU64()
+	queries := []*GetBlockHeaders{
+		{Origin: HashOrNumber{Number: 0}, Amount: 8},
+		{Origin: HashOrNumber{Number: 1}, Amount: 4, Skip: 2},
+		{Origin: HashOrNumber{Hash: s.chain.Head().Hash()}, Amount: 4, Reverse: true},
+		{Origin: HashOrNumber{Number: head / 2}, Amount: 3, Skip: 1, Reverse: true},
+		{Origin: HashOrNumber{Number: head}, Amount: 10},
+		{Origin: HashOrNumber{Number: head + 100}, Amount: 1},
+	}
+	for _, query := range queries {
+		if err := conn.send(getBlockHeadersMsg, query); err != nil {
+			return err
+		}
+		var headers []*types.Header
+		if err := conn.expect(blockHeadersMsg, &headers); err != nil {
+			return err
+		}
+		want := s.chain.Headers(query)
+		if len(headers) != len(want) {
+			return fmt.Errorf("query %+v: got %d headers, want %d", *query, len(headers), len(want))
+		}
+		for i := range headers {
+			if headers[i].Hash() != want[i].Hash() {
+				return fmt.Errorf("query %+v: header %d mismatch: got %x, want %x", *query, i, headers[i].Hash(), want[i].Hash())
+			}
+		}
+	}
+	return nil
+}
+
+// TestGetBlockBodies requests the bodies of the first blocks of the chain and
+// compares their contents with the local chain.
+func (s *Suite) TestGetBlockBodies() error {
+	conn, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	var (
+		hashes []common.Hash
+		blocks []*types.Block
+	)
+	for _, block := range s.chain.blocks[1:] {
+		if len(hashes) == maxBodies {
+			break
+		}
+		hashes = append(hashes, block.Hash())
+		blocks = append(blocks, block)
+	}
+	if err := conn.send(getBlockBodiesMsg, hashes); err != nil {
+		return err
+	}
+	var bodies []*BlockBody
+	if err := conn.expect(blockBodiesMsg, &bodies); err != nil {
+		return err
+	}
+	if len(bodies) != len(blocks) {
+		return fmt.Errorf("got %d bodies, want %d", len(bodies), len(blocks))
+	}
+	for i, body := range bodies {
+		if hash := types.DeriveSha(types.Transactions(body.Transactions)); hash != blocks[i].TxHash() {
+			return fmt.Errorf("body %d: transaction root mismatch: got %x, want %x", i, hash, blocks[i].TxHash())
+		}
+		if hash := types.CalcUncleHash(body.Uncles); hash != blocks[i].UncleHash() {
+			return fmt.Errorf("body %d: uncle hash mismatch: got %x, want %x", i, hash, blocks[i].UncleHash())
+		}
+	}
+	return nil
+}
+
+// TestTransaction sends a valid transaction and checks that the node keeps
+// the connection alive.
+func (s *Suite) TestTransaction() error {
+	conn, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	tx, err := s.nextTx()
+	if err != nil {
+		return err
+	}
+	if err := conn.send(txMsg, []*types.Transaction{tx}); err != nil {
+		return err
+	}
+	return conn.ping()
+}
+
+// TestTransactionPropagation sends a transaction over one connection and
+// expects the node to relay it over another one. The node only relays
+// transactions if it considers itself synchronised.
+func (s *Suite) TestTransactionPropagation() error {
+	sender, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer sender.Close(p2p.DiscRequested)
+
+	receiver, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer receiver.Close(p2p.DiscRequested)
+
+	tx, err := s.nextTx()
+	if err != nil {
+		return err
+	}
+	if err := sender.send(txMsg, []*types.Transaction{tx}); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		var txs []*types.Transaction
+		if err := receiver.expect(txMsg, &txs); err != nil {
+			return fmt.Errorf("transaction %x not propagated: %v", tx.Hash(), err)
+		}
+		for _, relayed := range txs {
+			if relayed.Hash() == tx.Hash() {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("transaction %x not propagated", tx.Hash())
+}
+
+// TestMalformedHeaderQuery sends a header query which cannot be decoded and
+// expects the node to disconnect.
+func (s *Suite) TestMalformedHeaderQuery() error {
+	conn, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	if err := conn.send(getBlockHeadersMsg, []string{"not", "a", "header", "query"}); err != nil {
+		return err
+	}
+	return conn.expectDisconnect()
+}
+
+// TestUnknownMessageCode sends a message with a code outside of the eth
+// protocol range and expects the node to disconnect.
+func (s *Suite) TestUnknownMessageCode() error {
+	conn, _, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	if err := conn.send(baseProtocolLength+0x20, []uint{}); err != nil {
+		return err
+	}
+	return conn.expectDisconnect()
+}
+
+// TestRepeatedStatus sends a second status message after the handshake and
+// expects the node to disconnect.
+func (s *Suite) TestRepeatedStatus() error {
+	conn, status, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close(p2p.DiscRequested)
+
+	if err := conn.send(statusMsg, status); err != nil {
+		return err
+	}
+	return conn.expectDisconnect()
+}
+
+// nextTx creates a transfer from the test account. Every call bumps the gas
+// price so that a transaction replaces the previous one in the node's pool.
+func (s *Suite) nextTx() (*types.Transaction, error) {
+	s.txPrice = new(big.Int).Div(new(big.Int).Mul(s.txPrice, big.NewInt(12)), big.NewInt(10))
+	tx := types.NewTransaction(s.nonce, recipient, big.NewInt(1), params.TxGas, s.txPrice, nil)
+	return types.SignTx(tx, s.txSigner, testKey)
+}
+
+// connect dials the node and performs the protocol and status handshakes.
+// It returns the status announced by the node.
+func (s *Suite) connect() (*conn, *Status, error) {
+	c, err := s.dial()
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial failed: %v", err)
+	}
+	if err := c.hello(); err != nil {
+		c.Close(p2p.DiscProtocolError)
+		return nil, nil, fmt.Errorf("protocol handshake failed: %v", err)
+	}
+	status, err := c.status(s.chain)
+	if err != nil {
+		c.Close(p2p.DiscProtocolError)
+		return nil, nil, fmt.Errorf("status handshake failed: %v", err)
+	}
+	return c, status, nil
+}
+
+// dial opens an encrypted connection to the node.
+func (s *Suite) dial() (*conn, error) {
+	addr := &net.TCPAddr{IP: s.Dest.IP(), Port: s.Dest.TCP()}
+	fd, err := net.DialTimeout("tcp", addr.String(), timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{RLPXConn: p2p.NewRLPXConn(fd)}
+	if c.key, err = crypto.GenerateKey(); err != nil {
+		fd.Close()
+		return nil, err
+	}
+	c.SetDeadline(time.Now().Add(timeout))
+	if _, err := c.Handshake(c.key, s.Dest.Pubkey()); err != nil {
+		fd.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// conn is a connection to the node under test.
+type conn struct {
+	*p2p.RLPXConn
+	key *ecdsa.PrivateKey
+}
+
+// hello performs the base protocol handshake, advertising only eth/63.
+func (c *conn) hello() error {
+	ours := &Hello{
+		Version: 5,
+		Name:    "devp2p-ethtest",
+		Caps:    []p2p.Cap{{Name: "eth", Version: ethVersion}},
+		ID:      crypto.FromECDSAPub(&c.key.PublicKey)[1:],
+	}
+	if err := c.send(helloMsg, ours); err != nil {
+		return err
+	}
+	var theirs Hello
+	if err := c.expect(helloMsg, &theirs); err != nil {
+		return err
+	}
+	for _, cap := range theirs.Caps {
+		if cap.Name == "eth" && cap.Version == ethVersion {
+			c.SetSnappy(theirs.Version >= 5)
+			return nil
+		}
+	}
+	return fmt.Errorf("node does not support eth/%d, caps %v", ethVersion, theirs.Caps)
+}
+
+// status performs the eth handshake. The node's network ID is echoed back,
+// the announced head is the head of the local chain.
+func (c *conn) status(chain *Chain) (*Status, error) {
+	var theirs Status
+	if err := c.expect(statusMsg, &theirs); err != nil {
+		return nil, err
+	}
+	if theirs.ProtocolVersion != ethVersion {
+		return nil, fmt.Errorf("wrong protocol version %d, want %d", theirs.ProtocolVersion, ethVersion)
+	}
+	if theirs.Genesis != chain.Genesis().Hash() {
+		return nil, fmt.Errorf("wrong genesis %x, want %x", theirs.Genesis, chain.Genesis().Hash())
+	}
+	if theirs.TD == nil {
+		return nil, errors.New("missing total difficulty")
+	}
+	ours := &Status{
+		ProtocolVersion: ethVersion,
+		NetworkID:       theirs.NetworkID,
+		TD:              chain.TD(),
+		Head:            chain.Head().Hash(),
+		Genesis:         chain.Genesis().Hash(),
+	}
+	if err := c.send(statusMsg, ours); err != nil {
+		return nil, err
+	}
+	return &theirs, nil
+}
+
+// send writes a single message to the node.
+func (c *conn) send(code uint64, data interface{}) error {
+	c.SetDeadline(time.Now().Add(timeout))
+	return p2p.Send(c, code, data)
+}
+
+// read reads the next message which is not handled on the base protocol
+// level. Pings are answered and disconnects are turned into errors.
+func (c *conn) read() (p2p.Msg, error) {
+	for {
+		c.SetDeadline(time.Now().Add(timeout))
+		msg, err := c.ReadMsg()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return msg, errTimeout
+			}
+			return msg, err
+		}
+		switch msg.Code {
+		case pingMsg:
+			msg.Discard()
+			if err := c.send(pongMsg, []uint{}); err != nil {
+				return msg, err
+			}
+		case discMsg:
+			var reason [1]p2p.DiscReason
+			rlp.Decode(msg.Payload, &reason)
+			return msg, fmt.Errorf("%v: %v", errDisconnected, reason[0])
+		default:
+			return msg, nil
+		}
+	}
+}
+
+// expect reads the next message and decodes it into data. Messages of other
+// types are skipped, except for eth protocol announcements which are ignored.
+func (c *conn) expect(code uint64, data interface{}) error {
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return err
+		}
+		if msg.Code != code {
+			msg.Discard()
+			switch msg.Code {
+			case txMsg, newBlockHashesMsg, newBlockMsg, pongMsg:
+				continue
+			}
+			return fmt.Errorf("unexpected message code %#x, want %#x", msg.Code, code)
+		}
+		if err := msg.Decode(data); err != nil {
+			return fmt.Errorf("invalid message %#x: %v", msg.Code, err)
+		}
+		return nil
+	}
+}
+
+// ping checks that the node is still responsive.
+func (c *conn) ping() error {
+	if err := c.send(pingMsg, []uint{}); err != nil {
+		return err
+	}
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return err
+		}
+		msg.Discard()
+		if msg.Code == pongMsg {
+			return nil
+		}
+	}
+}
+
+// expectDisconnect waits for the node to drop the connection.
+func (c *conn) expectDisconnect() error {
+	for {
+		msg, err := c.read()
+		switch {
+		case err == errTimeout:
+			return errors.New("node did not disconnect")
+		case err != nil:
+			return nil // disconnect message or closed connection
+		}
+		msg.Discard()
+	}
+}
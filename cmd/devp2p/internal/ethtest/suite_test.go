@@ -0,0 +1,97 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// Tests that a written chain fixture can be loaded back.
+func TestChainRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := GenerateChain(16)
+	if err := chain.Write(dir); err != nil {
+		t.Fatalf("can't write chain: %v", err)
+	}
+	loaded, err := LoadChain(filepath.Join(dir, GenesisFile), filepath.Join(dir, ChainFile))
+	if err != nil {
+		t.Fatalf("can't load chain: %v", err)
+	}
+	if loaded.Len() != chain.Len() {
+		t.Fatalf("chain length mismatch: have %d, want %d", loaded.Len(), chain.Len())
+	}
+	if loaded.Head().Hash() != chain.Head().Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", loaded.Head().Hash(), chain.Head().Hash())
+	}
+}
+
+// Tests that an in-process node passes the suite. Transaction propagation is
+// excluded because the node does not consider itself synchronised.
+func TestSuiteAgainstNode(t *testing.T) {
+	chain := GenerateChain(32)
+
+	var ethservice *eth.Ethereum
+	stack, err := node.New(&node.Config{
+		P2P: p2p.Config{
+			ListenAddr:  "127.0.0.1:0",
+			NoDiscovery: true,
+			MaxPeers:    10,
+		},
+	})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		config := &eth.Config{Genesis: chain.genesis, NetworkId: 1337}
+		config.Ethash.PowMode = ethash.ModeFake
+		ethservice, err = eth.New(ctx, config)
+		return ethservice, err
+	})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	defer stack.Stop()
+
+	if _, err := ethservice.BlockChain().InsertChain(chain.blocks[1:]); err != nil {
+		t.Fatalf("can't import chain: %v", err)
+	}
+	suite := NewSuite(stack.Server().Self(), chain)
+	var tests []Test
+	for _, test := range suite.AllTests() {
+		if test.Name != "TransactionPropagation" {
+			tests = append(tests, test)
+		}
+	}
+	for _, result := range suite.Run(tests) {
+		if result.Err != nil {
+			t.Errorf("test %s failed: %v", result.Name, result.Err)
+		}
+	}
+}
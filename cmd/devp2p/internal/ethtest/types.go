@@ -0,0 +1,110 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Base protocol message codes.
+const (
+	helloMsg = 0x00
+	discMsg  = 0x01
+	pingMsg  = 0x02
+	pongMsg  = 0x03
+
+	// baseProtocolLength is the number of message codes reserved by the base
+	// protocol. Codes of the eth protocol are offset by this amount.
+	baseProtocolLength = 0x10
+)
+
+// eth protocol message codes, already offset past the base protocol.
+const (
+	statusMsg          = baseProtocolLength + 0x00
+	newBlockHashesMsg  = baseProtocolLength + 0x01
+	txMsg              = baseProtocolLength + 0x02
+	getBlockHeadersMsg = baseProtocolLength + 0x03
+	blockHeadersMsg    = baseProtocolLength + 0x04
+	getBlockBodiesMsg  = baseProtocolLength + 0x05
+	blockBodiesMsg     = baseProtocolLength + 0x06
+	newBlockMsg        = baseProtocolLength + 0x07
+)
+
+// ethVersion is the eth protocol version spoken by the test suite.
+const ethVersion = 63
+
+// Hello is the RLPx protocol handshake.
+type Hello struct {
+	Version    uint64
+	Name       string
+	Caps       []p2p.Cap
+	ListenPort uint64
+	ID         []byte // secp256k1 public key
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// Status is the eth protocol handshake.
+type Status struct {
+	ProtocolVersion uint32
+	NetworkID       uint64
+	TD              *big.Int
+	Head            common.Hash
+	Genesis         common.Hash
+}
+
+// String implements fmt.Stringer.
+func (s *Status) String() string {
+	return fmt.Sprintf("[version %d, network %d, td %v, head %x, genesis %x]",
+		s.ProtocolVersion, s.NetworkID, s.TD, s.Head, s.Genesis)
+}
+
+// HashOrNumber is a combined field for specifying an origin block.
+type HashOrNumber struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+// EncodeRLP encodes only one of the two contained union fields.
+func (hn *HashOrNumber) EncodeRLP(w io.Writer) error {
+	if hn.Hash == (common.Hash{}) {
+		return rlp.Encode(w, hn.Number)
+	}
+	return rlp.Encode(w, hn.Hash)
+}
+
+// GetBlockHeaders is the eth protocol header query.
+type GetBlockHeaders struct {
+	Origin  HashOrNumber
+	Amount  uint64
+	Skip    uint64
+	Reverse bool
+}
+
+// BlockBody is the content of a single block as returned by GetBlockBodies.
+type BlockBody struct {
+	Transactions []*types.Transaction
+	Uncles       []*types.Header
+}
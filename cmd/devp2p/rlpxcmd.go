@@ -0,0 +1,129 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rlpxCommand = cli.Command{
+		Name:  "rlpx",
+		Usage: "RLPx Commands",
+		Subcommands: []cli.Command{
+			rlpxEthTestCommand,
+			rlpxGenChainCommand,
+		},
+	}
+	rlpxEthTestCommand = cli.Command{
+		Name:      "eth-test",
+		Usage:     "Runs tests against a node",
+		ArgsUsage: "<node> <chaindir>",
+		Action:    rlpxEthTest,
+		Flags:     []cli.Flag{testPatternFlag},
+	}
+	rlpxGenChainCommand = cli.Command{
+		Name:      "gen-chain",
+		Usage:     "Generates the chain fixture used by eth-test",
+		ArgsUsage: "<chaindir>",
+		Action:    rlpxGenChain,
+		Flags:     []cli.Flag{chainLengthFlag},
+	}
+)
+
+var (
+	testPatternFlag = cli.StringFlag{
+		Name:  "run",
+		Usage: "Pattern of test names to run (default: all)",
+	}
+	chainLengthFlag = cli.IntFlag{
+		Name:  "blocks",
+		Usage: "Number of blocks to generate",
+		Value: 128,
+	}
+)
+
+func rlpxEthTest(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("need node and chain directory as command-line arguments")
+	}
+	n, err := parseNode(ctx.Args()[0])
+	if err != nil {
+		return err
+	}
+	dir := ctx.Args()[1]
+	chain, err := ethtest.LoadChain(filepath.Join(dir, ethtest.GenesisFile), filepath.Join(dir, ethtest.ChainFile))
+	if err != nil {
+		return fmt.Errorf("can't load chain: %v", err)
+	}
+	suite := ethtest.NewSuite(n, chain)
+	tests, err := filterTests(suite.AllTests(), ctx.String(testPatternFlag.Name))
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, result := range suite.Run(tests) {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("-- FAIL %s (%v): %v\n", result.Name, result.Duration, result.Err)
+		} else {
+			fmt.Printf("-- PASS %s (%v)\n", result.Name, result.Duration)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d/%d tests failed", failed, len(tests))
+	}
+	fmt.Printf("%d tests passed\n", len(tests))
+	return nil
+}
+
+func rlpxGenChain(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need chain directory as command-line argument")
+	}
+	dir := ctx.Args()[0]
+	if err := ethtest.GenerateChain(ctx.Int(chainLengthFlag.Name)).Write(dir); err != nil {
+		return err
+	}
+	fmt.Printf("Chain written to %s. Initialize the node with 'geth init %s' and\n",
+		dir, filepath.Join(dir, ethtest.GenesisFile))
+	fmt.Printf("'geth import %s', then run it with --fakepow.\n", filepath.Join(dir, ethtest.ChainFile))
+	return nil
+}
+
+// filterTests returns the tests whose name matches the given pattern.
+func filterTests(tests []ethtest.Test, pattern string) ([]ethtest.Test, error) {
+	if pattern == "" {
+		return tests, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid test pattern: %v", err)
+	}
+	var result []ethtest.Test
+	for _, test := range tests {
+		if re.MatchString(test.Name) {
+			result = append(result, test)
+		}
+	}
+	return result, nil
+}
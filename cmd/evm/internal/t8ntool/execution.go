@@ -0,0 +1,211 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+	"golang.org/x/crypto/sha3"
+)
+
+// Prestate is the state and environment the transactions are applied on.
+type Prestate struct {
+	Env stEnv             `json:"env"`
+	Pre core.GenesisAlloc `json:"pre"`
+}
+
+// ExecutionResult is the outcome of applying a set of transactions to a pre-state.
+type ExecutionResult struct {
+	StateRoot   common.Hash    `json:"stateRoot"`
+	TxRoot      common.Hash    `json:"txRoot"`
+	ReceiptRoot common.Hash    `json:"receiptRoot"`
+	LogsHash    common.Hash    `json:"logsHash"`
+	Bloom       types.Bloom    `json:"logsBloom"`
+	Receipts    types.Receipts `json:"receipts"`
+	Rejected    []int          `json:"rejected,omitempty"`
+}
+
+// ommer is an uncle of the block being assembled, at the given distance from it.
+type ommer struct {
+	Delta   uint64         `json:"delta"`
+	Address common.Address `json:"address"`
+}
+
+//go:generate gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
+
+type stEnv struct {
+	Coinbase    common.Address                      `json:"currentCoinbase"   gencodec:"required"`
+	Difficulty  *big.Int                            `json:"currentDifficulty" gencodec:"required"`
+	GasLimit    uint64                              `json:"currentGasLimit"   gencodec:"required"`
+	Number      uint64                              `json:"currentNumber"     gencodec:"required"`
+	Timestamp   uint64                              `json:"currentTimestamp"  gencodec:"required"`
+	BlockHashes map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
+	Ommers      []ommer                             `json:"ommers,omitempty"`
+}
+
+type stEnvMarshaling struct {
+	Coinbase   common.UnprefixedAddress
+	Difficulty *math.HexOrDecimal256
+	GasLimit   math.HexOrDecimal64
+	Number     math.HexOrDecimal64
+	Timestamp  math.HexOrDecimal64
+}
+
+// Apply applies a set of transactions to a pre-state. Transactions which can't
+// be included are rejected, leaving the state untouched.
+func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
+	txs types.Transactions, miningReward int64,
+	getTracerFn func(txIndex int, txHash common.Hash) (tracer vm.Tracer, err error)) (*state.StateDB, *ExecutionResult, error) {
+
+	var (
+		statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), pre.Pre)
+		chain   = &envChain{env: &pre.Env}
+		header  = chain.header()
+		gaspool = new(core.GasPool).AddGas(pre.Env.GasLimit)
+		usedGas = new(uint64)
+
+		includedTxs types.Transactions
+		receipts    types.Receipts
+		rejected    []int
+	)
+	if chainConfig.DAOForkSupport && chainConfig.DAOForkBlock != nil && chainConfig.DAOForkBlock.Cmp(header.Number) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	for i, tx := range txs {
+		tracer, err := getTracerFn(len(includedTxs), tx.Hash())
+		if err != nil {
+			return nil, nil, err
+		}
+		vmConfig.Tracer = tracer
+		vmConfig.Debug = (tracer != nil)
+
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(includedTxs))
+		snapshot := statedb.Snapshot()
+
+		receipt, _, err := core.ApplyTransaction(chainConfig, chain, &pre.Env.Coinbase, gaspool, statedb, header, tx, usedGas, vmConfig)
+		if err != nil {
+			statedb.RevertToSnapshot(snapshot)
+			log.Info("Rejected transaction", "index", i, "hash", tx.Hash(), "error", err)
+			rejected = append(rejected, i)
+			continue
+		}
+		includedTxs = append(includedTxs, tx)
+		receipts = append(receipts, receipt)
+	}
+	// Add mining reward to the coinbase and the ommers, like ethash does
+	if miningReward >= 0 {
+		var (
+			blockReward = big.NewInt(miningReward)
+			minerReward = new(big.Int).Set(blockReward)
+			perOmmer    = new(big.Int).Div(blockReward, big.NewInt(32))
+		)
+		for _, ommer := range pre.Env.Ommers {
+			// Add 1/32th for each ommer included
+			minerReward.Add(minerReward, perOmmer)
+
+			// Add (8-delta)/8 to the ommer's coinbase
+			reward := big.NewInt(8)
+			reward.Sub(reward, new(big.Int).SetUint64(ommer.Delta))
+			reward.Mul(reward, blockReward)
+			reward.Div(reward, big.NewInt(8))
+			statedb.AddBalance(ommer.Address, reward)
+		}
+		statedb.AddBalance(pre.Env.Coinbase, minerReward)
+	}
+	// Commit block
+	root, err := statedb.Commit(chainConfig.IsEIP158(header.Number))
+	if err != nil {
+		return nil, nil, NewError(ErrorEVM, fmt.Errorf("could not commit state: %v", err))
+	}
+	execRs := &ExecutionResult{
+		StateRoot:   root,
+		TxRoot:      types.DeriveSha(includedTxs),
+		ReceiptRoot: types.DeriveSha(receipts),
+		Bloom:       types.CreateBloom(receipts),
+		LogsHash:    rlpHash(statedb.Logs()),
+		Receipts:    receipts,
+		Rejected:    rejected,
+	}
+	return statedb, execRs, nil
+}
+
+// envChain is a chain context made up of the block hashes provided in the
+// environment, so BLOCKHASH can be served without an actual chain.
+type envChain struct {
+	env *stEnv
+}
+
+// header returns the header of the block the transactions are applied in.
+func (c *envChain) header() *types.Header {
+	header := &types.Header{
+		Number:     new(big.Int).SetUint64(c.env.Number),
+		Coinbase:   c.env.Coinbase,
+		Difficulty: c.env.Difficulty,
+		GasLimit:   c.env.GasLimit,
+		Time:       c.env.Timestamp,
+	}
+	if c.env.Number > 0 {
+		header.ParentHash = c.hash(c.env.Number - 1)
+	}
+	return header
+}
+
+// hash returns the hash of the given ancestor block, or the zero hash if not
+// provided by the environment.
+func (c *envChain) hash(number uint64) common.Hash {
+	return c.env.BlockHashes[math.HexOrDecimal64(number)]
+}
+
+// Engine implements core.ChainContext, no consensus engine is needed as the
+// coinbase is set explicitly.
+func (c *envChain) Engine() consensus.Engine {
+	return nil
+}
+
+// GetHeader implements core.ChainContext, returning a stub header linking the
+// ancestor at the given number to its parent. Only the numbers are relevant.
+func (c *envChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number >= c.env.Number {
+		return nil
+	}
+	header := &types.Header{Number: new(big.Int).SetUint64(number)}
+	if number > 0 {
+		header.ParentHash = c.hash(number - 1)
+	}
+	return header
+}
+
+func rlpHash(x interface{}) (h common.Hash) {
+	hw := sha3.NewLegacyKeccak256()
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
@@ -0,0 +1,257 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	ErrorEVM      = 2
+	ErrorVMConfig = 3
+	ErrorJson     = 10
+	ErrorIO       = 11
+
+	stdinSelector = "stdin"
+)
+
+// NumberedError is an error carrying the exit code the tool should terminate
+// with, so callers can tell the kind of failure apart.
+type NumberedError struct {
+	errorCode int
+	err       error
+}
+
+// NewError wraps an error with an exit code.
+func NewError(errorCode int, err error) *NumberedError {
+	return &NumberedError{errorCode, err}
+}
+
+func (n *NumberedError) Error() string {
+	return fmt.Sprintf("ERROR(%d): %v", n.errorCode, n.err.Error())
+}
+
+// Code returns the exit code of the error.
+func (n *NumberedError) Code() int {
+	return n.errorCode
+}
+
+// input is the combined input of the tool when read from stdin.
+type input struct {
+	Alloc core.GenesisAlloc  `json:"alloc,omitempty"`
+	Env   *stEnv             `json:"env,omitempty"`
+	Txs   types.Transactions `json:"txs,omitempty"`
+}
+
+// Main is the entry point of the state transition tool.
+func Main(ctx *cli.Context) error {
+	// Configure the go-ethereum logger
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.Int(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+
+	// If the tracer is enabled, traces of each transaction are written to
+	// separate files
+	getTracer := func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+		return nil, nil
+	}
+	var prevFile *os.File
+	if ctx.Bool(TraceFlag.Name) {
+		defer func() {
+			if prevFile != nil {
+				prevFile.Close()
+			}
+		}()
+		logConfig := &vm.LogConfig{
+			DisableStack:  ctx.Bool(TraceDisableStackFlag.Name),
+			DisableMemory: ctx.Bool(TraceDisableMemoryFlag.Name),
+		}
+		getTracer = func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+			if prevFile != nil {
+				prevFile.Close()
+			}
+			traceFile, err := os.Create(fmt.Sprintf("trace-%d-%v.jsonl", txIndex, txHash.String()))
+			if err != nil {
+				return nil, NewError(ErrorIO, fmt.Errorf("failed creating trace-file: %v", err))
+			}
+			prevFile = traceFile
+			return vm.NewJSONLogger(logConfig, traceFile), nil
+		}
+	}
+
+	// Load the inputs, any of them selecting stdin reads all of them from it
+	var (
+		prestate  Prestate
+		allocStr  = ctx.String(InputAllocFlag.Name)
+		envStr    = ctx.String(InputEnvFlag.Name)
+		txStr     = ctx.String(InputTxsFlag.Name)
+		inputData = &input{}
+	)
+	if allocStr == stdinSelector || envStr == stdinSelector || txStr == stdinSelector {
+		decoder := json.NewDecoder(os.Stdin)
+		if err := decoder.Decode(inputData); err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed unmarshaling stdin: %v", err))
+		}
+	}
+	if allocStr != stdinSelector {
+		if err := readFile(allocStr, "alloc", &inputData.Alloc); err != nil {
+			return err
+		}
+	}
+	prestate.Pre = inputData.Alloc
+
+	if envStr != stdinSelector {
+		var env stEnv
+		if err := readFile(envStr, "env", &env); err != nil {
+			return err
+		}
+		inputData.Env = &env
+	}
+	if inputData.Env == nil {
+		return NewError(ErrorJson, errors.New("missing env"))
+	}
+	prestate.Env = *inputData.Env
+
+	if txStr != stdinSelector {
+		if err := readFile(txStr, "txs", &inputData.Txs); err != nil {
+			return err
+		}
+	}
+
+	// Configure the chain rules and the EVM
+	chainConfig, err := getChainConfig(ctx.String(ForknameFlag.Name), ctx.Int64(ChainIDFlag.Name))
+	if err != nil {
+		return err
+	}
+	vmConfig := vm.Config{}
+
+	// Run the transactions and output the results
+	statedb, result, err := prestate.Apply(vmConfig, chainConfig, inputData.Txs, ctx.Int64(RewardFlag.Name), getTracer)
+	if err != nil {
+		return err
+	}
+	return dispatchOutput(ctx, result, collectAlloc(statedb))
+}
+
+// readFile decodes the JSON content of a file into the given value.
+func readFile(path, desc string, dest interface{}) error {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed reading %s file: %v", desc, err))
+	}
+	if err := json.Unmarshal(blob, dest); err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed unmarshaling %s file: %v", desc, err))
+	}
+	return nil
+}
+
+// getChainConfig returns the chain rules of the named fork, with the given
+// chain id.
+func getChainConfig(fork string, chainID int64) (*params.ChainConfig, error) {
+	config, ok := tests.Forks[fork]
+	if !ok {
+		return nil, NewError(ErrorVMConfig, tests.UnsupportedForkError{Name: fork})
+	}
+	cpy := *config
+	cpy.ChainID = big.NewInt(chainID)
+	return &cpy, nil
+}
+
+// collectAlloc converts the post-state into an allocation, the same format the
+// pre-state is read in.
+func collectAlloc(statedb *state.StateDB) core.GenesisAlloc {
+	alloc := make(core.GenesisAlloc)
+	for addr, account := range statedb.RawDump(false, false, true).Accounts {
+		balance, _ := new(big.Int).SetString(account.Balance, 10)
+		genesisAccount := core.GenesisAccount{
+			Code:    common.FromHex(account.Code),
+			Balance: balance,
+			Nonce:   account.Nonce,
+		}
+		if len(account.Storage) > 0 {
+			genesisAccount.Storage = make(map[common.Hash]common.Hash)
+			for key, value := range account.Storage {
+				genesisAccount.Storage[key] = common.HexToHash(value)
+			}
+		}
+		alloc[addr] = genesisAccount
+	}
+	return alloc
+}
+
+// dispatchOutput writes the output data to either stderr, stdout or files. The
+// outputs directed to stdout or stderr are combined into a single object.
+func dispatchOutput(ctx *cli.Context, result *ExecutionResult, alloc core.GenesisAlloc) error {
+	stdOutObject := make(map[string]interface{})
+	stdErrObject := make(map[string]interface{})
+
+	dispatch := func(fName, name string, obj interface{}) error {
+		switch fName {
+		case "stdout":
+			stdOutObject[name] = obj
+		case "stderr":
+			stdErrObject[name] = obj
+		default:
+			b, err := json.MarshalIndent(obj, "", " ")
+			if err != nil {
+				return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+			}
+			if err = ioutil.WriteFile(fName, b, 0644); err != nil {
+				return NewError(ErrorIO, fmt.Errorf("failed writing output: %v", err))
+			}
+		}
+		return nil
+	}
+	if err := dispatch(ctx.String(OutputAllocFlag.Name), "alloc", alloc); err != nil {
+		return err
+	}
+	if err := dispatch(ctx.String(OutputResultFlag.Name), "result", result); err != nil {
+		return err
+	}
+	if len(stdOutObject) > 0 {
+		b, err := json.MarshalIndent(stdOutObject, "", " ")
+		if err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+		}
+		os.Stdout.Write(b)
+		os.Stdout.Write([]byte("\n"))
+	}
+	if len(stdErrObject) > 0 {
+		b, err := json.MarshalIndent(stdErrObject, "", " ")
+		if err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+		}
+		os.Stderr.Write(b)
+		os.Stderr.Write([]byte("\n"))
+	}
+	return nil
+}
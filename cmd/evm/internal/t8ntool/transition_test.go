@@ -0,0 +1,87 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Tests that state transitions produce the expected post-state and results from
+// the alloc, env and txs fixtures, in the format the tool outputs to stdout. The
+// fixtures contain a successful transaction and one rejected for its nonce.
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		dir    string
+		fork   string
+		reward int64
+		expect string
+	}{
+		{"testdata/1", "Istanbul", 2000000000000000000, "exp.json"},
+		{"testdata/1", "Istanbul", -1, "exp_noreward.json"},
+	}
+	for i, tt := range tests {
+		var (
+			prestate Prestate
+			txs      types.Transactions
+		)
+		if err := readFile(filepath.Join(tt.dir, "alloc.json"), "alloc", &prestate.Pre); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if err := readFile(filepath.Join(tt.dir, "env.json"), "env", &prestate.Env); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if err := readFile(filepath.Join(tt.dir, "txs.json"), "txs", &txs); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		chainConfig, err := getChainConfig(tt.fork, 1)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		noTracer := func(txIndex int, txHash common.Hash) (vm.Tracer, error) { return nil, nil }
+		statedb, result, err := prestate.Apply(vm.Config{}, chainConfig, txs, tt.reward, noTracer)
+		if err != nil {
+			t.Fatalf("test %d: state transition failed: %v", i, err)
+		}
+		// Compare the outputs to the expectations as generic JSON values
+		blob, err := json.Marshal(map[string]interface{}{"alloc": collectAlloc(statedb), "result": result})
+		if err != nil {
+			t.Fatalf("test %d: failed to encode output: %v", i, err)
+		}
+		var have, want interface{}
+		if err := json.Unmarshal(blob, &have); err != nil {
+			t.Fatalf("test %d: failed to decode output: %v", i, err)
+		}
+		expect, err := ioutil.ReadFile(filepath.Join(tt.dir, tt.expect))
+		if err != nil {
+			t.Fatalf("test %d: failed to read expectations: %v", i, err)
+		}
+		if err := json.Unmarshal(expect, &want); err != nil {
+			t.Fatalf("test %d: failed to decode expectations: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("test %d: output mismatch:\nhave %s\nwant %s", i, blob, expect)
+		}
+	}
+}
@@ -181,7 +181,12 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	}
 	// Configure GraphQL if requested
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
-		utils.RegisterGraphQLService(stack, cfg.Node.GraphQLEndpoint(), cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPTimeouts)
+		// Share the HTTP-RPC listener if configured on the same endpoint
+		endpoint := cfg.Node.GraphQLEndpoint()
+		if endpoint == cfg.Node.HTTPEndpoint() {
+			endpoint = ""
+		}
+		utils.RegisterGraphQLService(stack, endpoint, cfg.Node.GraphQLPathPrefix, cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPTimeouts)
 	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
@@ -495,6 +495,10 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMTraceIndexFlag = cli.BoolFlag{
+		Name:  "vmtraceindex",
+		Usage: "Index internal transactions by address for debug_getInternalTransactions",
+	}
 	InsecureUnlockAllowedFlag = cli.BoolFlag{
 		Name:  "allow-insecure-unlock",
 		Usage: "Allow insecure account unlocking when account-related RPCs are exposed by http",
@@ -503,6 +507,18 @@ var (
 		Name:  "rpc.gascap",
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas",
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in an HTTP/WS-RPC batch (0 = unlimited)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size in bytes of an HTTP/WS-RPC response or batch response (0 = unlimited)",
+	}
+	RPCConnCallsFlag = cli.IntFlag{
+		Name:  "rpc.conncalls",
+		Usage: "Maximum number of concurrently executing requests per WS-RPC connection (0 = unlimited)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -554,6 +570,16 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "Path to a hex encoded secret for authenticating HTTP-RPC requests with JWT tokens (generated if missing)",
+		Value: "",
+	}
+	RPCPathPrefixFlag = cli.StringFlag{
+		Name:  "rpcprefix",
+		Usage: "URL path prefix under which the HTTP-RPC server is served",
+		Value: "",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -578,6 +604,16 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSJWTSecretFlag = cli.StringFlag{
+		Name:  "wsjwtsecret",
+		Usage: "Path to a hex encoded secret for authenticating WS-RPC connections with JWT tokens (generated if missing)",
+		Value: "",
+	}
+	WSPathPrefixFlag = cli.StringFlag{
+		Name:  "wsprefix",
+		Usage: "URL path prefix under which the WS-RPC server is served (shares the HTTP-RPC port if the endpoints match)",
+		Value: "",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable the GraphQL server",
@@ -602,6 +638,11 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLPathPrefixFlag = cli.StringFlag{
+		Name:  "graphql.prefix",
+		Usage: "URL path prefix under which GraphQL is served if sharing the HTTP-RPC port",
+		Value: "",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -667,6 +708,10 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	PeerPolicyFlag = cli.StringFlag{
+		Name:  "peerpolicy",
+		Usage: "JSON file with the policy restricting which peers may connect",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -950,6 +995,12 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.HTTPJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCPathPrefixFlag.Name) {
+		cfg.HTTPPathPrefix = ctx.GlobalString(RPCPathPrefixFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -968,6 +1019,23 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLPathPrefixFlag.Name) {
+		cfg.GraphQLPathPrefix = ctx.GlobalString(GraphQLPathPrefixFlag.Name)
+	}
+}
+
+// setRPCLimits configures the resource limits of the HTTP and WebSocket RPC
+// interfaces from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCLimits.ResponseSize = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCConnCallsFlag.Name) {
+		cfg.RPCLimits.ConnCalls = ctx.GlobalInt(RPCConnCallsFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -988,6 +1056,12 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSJWTSecretFlag.Name) {
+		cfg.WSJWTSecret = ctx.GlobalString(WSJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSPathPrefixFlag.Name) {
+		cfg.WSPathPrefix = ctx.GlobalString(WSPathPrefixFlag.Name)
+	}
 	if ctx.GlobalBool(StateDiffFlag.Name) {
 		cfg.WSModules = append(cfg.WSModules, "statediff")
 	}
@@ -1188,6 +1262,14 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
+	if file := ctx.GlobalString(PeerPolicyFlag.Name); file != "" {
+		policy := new(p2p.PeerPolicy)
+		if err := common.LoadJSON(file, policy); err != nil {
+			Fatalf("Option %q: %v", PeerPolicyFlag.Name, err)
+		}
+		cfg.PeerPolicy = policy
+	}
+
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
 		cfg.MaxPeers = 0
@@ -1204,6 +1286,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1496,6 +1579,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMTraceIndexFlag.Name) {
+		cfg.TraceIndex = ctx.GlobalBool(VMTraceIndexFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(EWASMInterpreterFlag.Name) {
 		cfg.EWASMInterpreter = ctx.GlobalString(EWASMInterpreterFlag.Name)
@@ -1618,17 +1704,18 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
-func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) {
+// If the endpoint is empty, the service is served on the HTTP endpoint of the node under the given prefix.
+func RegisterGraphQLService(stack *node.Node, endpoint, prefix string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) {
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		// Try to construct the GraphQL service backed by a full node
 		var ethServ *eth.Ethereum
 		if err := ctx.Service(&ethServ); err == nil {
-			return graphql.New(ethServ.APIBackend, endpoint, cors, vhosts, timeouts)
+			return graphql.New(ethServ.APIBackend, endpoint, prefix, cors, vhosts, timeouts)
 		}
 		// Try to construct the GraphQL service backed by a light node
 		var lesServ *les.LightEthereum
 		if err := ctx.Service(&lesServ); err == nil {
-			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts, timeouts)
+			return graphql.New(lesServ.ApiBackend, endpoint, prefix, cors, vhosts, timeouts)
 		}
 		// Well, this should not have happened, bail out
 		return nil, errors.New("no Ethereum service")
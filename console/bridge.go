@@ -20,11 +20,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/robertkrimen/otto"
@@ -350,6 +352,72 @@ func (b *bridge) SleepBlocks(call otto.FunctionCall) (response otto.Value) {
 	return otto.FalseValue()
 }
 
+// StepTransaction replays a transaction through debug_traceTransaction and runs
+// an interactive debugger over its execution steps. The optional second argument
+// may point to the combined-json output of solc to map the steps executing the
+// code of the given contract (the transaction recipient by default) to sources.
+func (b *bridge) StepTransaction(call otto.FunctionCall) (response otto.Value) {
+	const usage = "usage: debug.stepTransaction(<tx hash>[, {solc: <combined-json path>, contract: <name>, address: <code address>}])"
+
+	if !call.Argument(0).IsString() {
+		throwJSException(usage)
+	}
+	hash, _ := call.Argument(0).ToString()
+
+	var solc, contract, address string
+	if options := call.Argument(1); options.IsObject() {
+		for name, field := range map[string]*string{"solc": &solc, "contract": &contract, "address": &address} {
+			if value, _ := options.Object().Get(name); value.IsString() {
+				*field, _ = value.ToString()
+			} else if value.IsDefined() {
+				throwJSException(fmt.Sprintf("expected string as %s option", name))
+			}
+		}
+	} else if options.IsDefined() {
+		throwJSException(usage)
+	}
+	// Retrieve the transaction recipient and the execution steps to debug
+	var tx *struct {
+		To *common.Address `json:"to"`
+	}
+	if err := b.client.Call(&tx, "eth_getTransactionByHash", hash); err != nil {
+		throwJSException(err.Error())
+	}
+	if tx == nil {
+		throwJSException("transaction not found")
+	}
+	var trace debugTrace
+	if err := b.client.Call(&trace, "debug_traceTransaction", hash); err != nil {
+		throwJSException(err.Error())
+	}
+	// Load the source mapping if requested and start debugging
+	var source *sourceMap
+	if solc != "" {
+		code := tx.To
+		if address != "" {
+			if !common.IsHexAddress(address) {
+				throwJSException(fmt.Sprintf("invalid address %q", address))
+			}
+			addr := common.HexToAddress(address)
+			code = &addr
+		}
+		if code == nil {
+			throwJSException("address option required for contract creations")
+		}
+		combined, err := ioutil.ReadFile(solc)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		if source, err = newSourceMap(*code, combined, contract); err != nil {
+			throwJSException(err.Error())
+		}
+	}
+	if err := newDebugger(trace.StructLogs, tx.To, source, b.printer).run(b.prompter); err != nil {
+		throwJSException(err.Error())
+	}
+	return otto.TrueValue()
+}
+
 type jsonrpcCall struct {
 	ID     int64
 	Method string
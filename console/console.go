@@ -200,6 +200,14 @@ func (c *Console) init(preload []string) error {
 		obj.Set("sleep", bridge.Sleep)
 		obj.Set("clearHistory", c.clearHistory)
 	}
+	// The debug.stepTransaction debugger is offered by the console on top of debug.traceTransaction.
+	debug, err := c.jsre.Get("debug")
+	if err != nil {
+		return err
+	}
+	if obj := debug.Object(); obj != nil { // make sure the debug api is enabled over the interface
+		obj.Set("stepTransaction", bridge.StepTransaction)
+	}
 	// Preload any JavaScript files before starting the console
 	for _, path := range preload {
 		if err := c.jsre.Exec(path); err != nil {
@@ -0,0 +1,612 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/peterh/liner"
+)
+
+// debugPrompt is the prompt line prefix used by the transaction debugger.
+const debugPrompt = "debug> "
+
+// debugHelp lists the commands understood by the transaction debugger.
+const debugHelp = `Commands:
+  step, s [n]              execute the next n steps (default 1)
+  next, n                  step over calls and creations
+  out, o                   run until the current call frame returns
+  back [n]                 go back n steps (default 1)
+  continue, c              run until a breakpoint is hit or the trace ends
+  break, b <pc|op|depth> <value>
+                           set a breakpoint on a program counter, an opcode or
+                           on entering a call depth
+  breaks                   list the breakpoints
+  delete, d <id>           delete a breakpoint
+  where, w                 print the current step
+  stack                    print the stack, topmost item first
+  memory, mem              print the memory
+  storage                  print the storage touched by the current contract
+  frames, bt               print the call frames
+  source, src              print the source location of the current step
+  help, h                  print this help
+  quit, q                  leave the debugger
+An empty line repeats the previous command.`
+
+// debugStep is a single execution step of a transaction, as returned by the
+// debug_traceTransaction RPC method.
+type debugStep struct {
+	Pc      uint64            `json:"pc"`
+	Op      string            `json:"op"`
+	Gas     uint64            `json:"gas"`
+	GasCost uint64            `json:"gasCost"`
+	Depth   int               `json:"depth"`
+	Error   json.RawMessage   `json:"error"`
+	Stack   []string          `json:"stack"`
+	Memory  []string          `json:"memory"`
+	Storage map[string]string `json:"storage"`
+}
+
+// failed reports whether the step raised an execution error.
+func (s *debugStep) failed() bool {
+	return len(s.Error) > 0 && string(s.Error) != "null"
+}
+
+// debugTrace is the result of the debug_traceTransaction RPC method.
+type debugTrace struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []debugStep `json:"structLogs"`
+}
+
+// debugFrame is a call frame reconstructed from the call depths of the steps.
+type debugFrame struct {
+	parent *debugFrame
+	op     string          // Opcode entering the frame, empty for the outermost one
+	code   *common.Address // Address of the code being executed, nil for creations
+	depth  int             // Call depth of the frame
+	entry  int             // Index of the first step executed in the frame
+}
+
+// breakpoint is a condition stopping the debugger when a step satisfies it.
+type breakpoint struct {
+	id    int
+	kind  string // One of "pc", "op" or "depth"
+	pc    uint64
+	op    string
+	depth int
+}
+
+// String implements fmt.Stringer.
+func (b *breakpoint) String() string {
+	switch b.kind {
+	case "pc":
+		return fmt.Sprintf("pc %d", b.pc)
+	case "op":
+		return fmt.Sprintf("op %s", b.op)
+	default:
+		return fmt.Sprintf("depth %d", b.depth)
+	}
+}
+
+// debugger is an interactive stepper over the execution steps of a transaction.
+type debugger struct {
+	steps  []debugStep
+	frames []*debugFrame // Call frame each step is executed in
+	source *sourceMap    // Optional Solidity source mapping
+	out    io.Writer
+
+	breaks []*breakpoint
+	nextID int
+	pos    int
+}
+
+// newDebugger creates a debugger over the given execution steps. The to address
+// is the recipient of the transaction, nil for contract creations.
+func newDebugger(steps []debugStep, to *common.Address, source *sourceMap, out io.Writer) *debugger {
+	d := &debugger{
+		steps:  steps,
+		frames: make([]*debugFrame, len(steps)),
+		source: source,
+		out:    out,
+		nextID: 1,
+	}
+	if len(steps) == 0 {
+		return d
+	}
+	frame := &debugFrame{code: to, depth: steps[0].Depth}
+	for i := range steps {
+		if i > 0 {
+			prev := &steps[i-1]
+			switch {
+			case steps[i].Depth > prev.Depth:
+				frame = &debugFrame{parent: frame, op: prev.Op, code: callee(prev), depth: steps[i].Depth, entry: i}
+			case steps[i].Depth < prev.Depth:
+				for frame.parent != nil && frame.depth > steps[i].Depth {
+					frame = frame.parent
+				}
+			}
+		}
+		d.frames[i] = frame
+	}
+	return d
+}
+
+// callee returns the address of the code a call step is about to execute, or
+// nil if it cannot be determined from the stack (i.e. contract creations).
+func callee(step *debugStep) *common.Address {
+	switch step.Op {
+	case "CALL", "CALLCODE", "DELEGATECALL", "STATICCALL":
+		if len(step.Stack) < 2 {
+			return nil
+		}
+		addr := common.HexToAddress(step.Stack[len(step.Stack)-2])
+		return &addr
+	}
+	return nil
+}
+
+// run executes the command loop of the debugger until the user quits or the
+// input is aborted.
+func (d *debugger) run(prompter UserPrompter) error {
+	if len(d.steps) == 0 {
+		fmt.Fprintln(d.out, "Transaction did not execute any EVM code")
+		return nil
+	}
+	fmt.Fprintf(d.out, "Debugging %d steps, type 'help' for the available commands\n", len(d.steps))
+	d.printStep()
+
+	var last string
+	for {
+		input, err := prompter.PromptInput(debugPrompt)
+		if err == liner.ErrPromptAborted || err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if input = strings.TrimSpace(input); input == "" {
+			input = last
+		}
+		if input == "" {
+			continue
+		}
+		last = input
+		if !d.execute(input) {
+			return nil
+		}
+	}
+}
+
+// execute runs a single debugger command, returning false if the debugger should
+// be left.
+func (d *debugger) execute(input string) bool {
+	fields := strings.Fields(input)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "step", "s":
+		count, err := countArg(args)
+		if err != nil {
+			fmt.Fprintln(d.out, err)
+			break
+		}
+		d.advance(func(int) bool {
+			count--
+			return count == 0
+		})
+	case "next", "n":
+		depth := d.steps[d.pos].Depth
+		d.advance(func(i int) bool { return d.steps[i].Depth <= depth })
+	case "out", "o":
+		depth := d.steps[d.pos].Depth
+		d.advance(func(i int) bool { return d.steps[i].Depth < depth })
+	case "continue", "c":
+		d.advance(func(int) bool { return false })
+	case "back":
+		count, err := countArg(args)
+		if err != nil {
+			fmt.Fprintln(d.out, err)
+			break
+		}
+		if d.pos -= count; d.pos < 0 {
+			d.pos = 0
+		}
+		d.printStep()
+	case "break", "b":
+		d.addBreakpoint(args)
+	case "breaks":
+		if len(d.breaks) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints")
+		}
+		for _, b := range d.breaks {
+			fmt.Fprintf(d.out, "%d: %v\n", b.id, b)
+		}
+	case "delete", "d":
+		d.deleteBreakpoint(args)
+	case "where", "w":
+		d.printStep()
+	case "stack":
+		d.printStack()
+	case "memory", "mem":
+		d.printMemory()
+	case "storage":
+		d.printStorage()
+	case "frames", "bt":
+		d.printFrames()
+	case "source", "src":
+		d.printSource()
+	case "help", "h":
+		fmt.Fprintln(d.out, debugHelp)
+	case "quit", "q":
+		return false
+	default:
+		fmt.Fprintf(d.out, "Unknown command %q, type 'help' for the available commands\n", cmd)
+	}
+	return true
+}
+
+// countArg parses the optional step count argument of a command.
+func countArg(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid step count %q", args[0])
+	}
+	return count, nil
+}
+
+// advance moves forward at least one step, until done reports true for the
+// current step, a breakpoint is hit or the end of the trace is reached.
+func (d *debugger) advance(done func(int) bool) {
+	for d.pos < len(d.steps)-1 {
+		d.pos++
+		if b := d.breakpointAt(d.pos); b != nil {
+			fmt.Fprintf(d.out, "Breakpoint %d (%v) hit\n", b.id, b)
+			break
+		}
+		if done(d.pos) {
+			break
+		}
+	}
+	if d.pos == len(d.steps)-1 {
+		fmt.Fprintln(d.out, "End of trace")
+	}
+	d.printStep()
+}
+
+// breakpointAt returns the first breakpoint matched by the given step, if any.
+func (d *debugger) breakpointAt(i int) *breakpoint {
+	step := &d.steps[i]
+	for _, b := range d.breaks {
+		switch b.kind {
+		case "pc":
+			if step.Pc == b.pc {
+				return b
+			}
+		case "op":
+			if step.Op == b.op {
+				return b
+			}
+		case "depth":
+			if step.Depth == b.depth && (i == 0 || d.steps[i-1].Depth != b.depth) {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// addBreakpoint parses and registers a new breakpoint.
+func (d *debugger) addBreakpoint(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(d.out, "usage: break <pc|op|depth> <value>")
+		return
+	}
+	b := &breakpoint{kind: args[0]}
+	switch b.kind {
+	case "pc":
+		pc, err := strconv.ParseUint(args[1], 0, 64)
+		if err != nil {
+			fmt.Fprintf(d.out, "invalid program counter %q\n", args[1])
+			return
+		}
+		b.pc = pc
+	case "op":
+		op := vm.StringToOp(strings.ToUpper(args[1]))
+		if op.String() != strings.ToUpper(args[1]) {
+			fmt.Fprintf(d.out, "unknown opcode %q\n", args[1])
+			return
+		}
+		b.op = op.String()
+	case "depth":
+		depth, err := strconv.Atoi(args[1])
+		if err != nil || depth <= 0 {
+			fmt.Fprintf(d.out, "invalid call depth %q\n", args[1])
+			return
+		}
+		b.depth = depth
+	default:
+		fmt.Fprintln(d.out, "usage: break <pc|op|depth> <value>")
+		return
+	}
+	b.id = d.nextID
+	d.nextID++
+	d.breaks = append(d.breaks, b)
+
+	fmt.Fprintf(d.out, "Breakpoint %d set on %v\n", b.id, b)
+}
+
+// deleteBreakpoint removes a breakpoint by its identifier.
+func (d *debugger) deleteBreakpoint(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(d.out, "usage: delete <id>")
+		return
+	}
+	id, err := strconv.Atoi(args[0])
+	if err == nil {
+		for i, b := range d.breaks {
+			if b.id == id {
+				d.breaks = append(d.breaks[:i], d.breaks[i+1:]...)
+				fmt.Fprintf(d.out, "Breakpoint %d deleted\n", id)
+				return
+			}
+		}
+	}
+	fmt.Fprintf(d.out, "No breakpoint %q\n", args[0])
+}
+
+// printStep prints a summary of the current step, along with its source location
+// if available.
+func (d *debugger) printStep() {
+	step := &d.steps[d.pos]
+
+	fmt.Fprintf(d.out, "[%d/%d] depth=%d pc=%d op=%s gas=%d cost=%d", d.pos+1, len(d.steps), step.Depth, step.Pc, step.Op, step.Gas, step.GasCost)
+	if step.failed() {
+		fmt.Fprintf(d.out, " error=%s", step.Error)
+	}
+	fmt.Fprintln(d.out)
+
+	if loc, err := d.location(); err == nil {
+		fmt.Fprintf(d.out, "  at %s\n", loc)
+	}
+}
+
+// printStack prints the stack of the current step, topmost item first.
+func (d *debugger) printStack() {
+	stack := d.steps[d.pos].Stack
+	if len(stack) == 0 {
+		fmt.Fprintln(d.out, "Stack is empty")
+		return
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(d.out, "%4d: 0x%s\n", len(stack)-i-1, stack[i])
+	}
+}
+
+// printMemory prints the memory of the current step in 32 byte words.
+func (d *debugger) printMemory() {
+	memory := d.steps[d.pos].Memory
+	if len(memory) == 0 {
+		fmt.Fprintln(d.out, "Memory is empty")
+		return
+	}
+	for i, word := range memory {
+		fmt.Fprintf(d.out, "0x%04x: %s\n", i*32, word)
+	}
+}
+
+// printStorage prints the storage slots accessed by the current contract up to
+// the current step.
+func (d *debugger) printStorage() {
+	storage := d.steps[d.pos].Storage
+	if len(storage) == 0 {
+		fmt.Fprintln(d.out, "No storage accessed")
+		return
+	}
+	keys := make([]string, 0, len(storage))
+	for key := range storage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(d.out, "0x%s: 0x%s\n", key, storage[key])
+	}
+}
+
+// printFrames prints the call frames of the current step, innermost first.
+func (d *debugger) printFrames() {
+	for frame := d.frames[d.pos]; frame != nil; frame = frame.parent {
+		code := "<creation>"
+		if frame.code != nil {
+			code = frame.code.Hex()
+		}
+		if frame.parent == nil {
+			fmt.Fprintf(d.out, "#%d %s (transaction)\n", frame.depth, code)
+		} else {
+			fmt.Fprintf(d.out, "#%d %s (%s, entered at step %d)\n", frame.depth, code, frame.op, frame.entry+1)
+		}
+	}
+}
+
+// printSource prints the source location of the current step.
+func (d *debugger) printSource() {
+	loc, err := d.location()
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	fmt.Fprintln(d.out, loc)
+}
+
+// location resolves the source location of the current step.
+func (d *debugger) location() (string, error) {
+	if d.source == nil {
+		return "", errors.New("no source map loaded")
+	}
+	frame := d.frames[d.pos]
+	if frame.code == nil || *frame.code != d.source.address {
+		return "", errors.New("no source map for the executing code")
+	}
+	return d.source.lookup(d.steps[d.pos].Pc)
+}
+
+// sourceEntry is a decoded item of a Solidity source mapping.
+type sourceEntry struct {
+	start, length, file int
+}
+
+// sourceMap maps the program counters of a contract's runtime code to locations
+// in its Solidity sources, based on the combined-json output of solc.
+type sourceMap struct {
+	address common.Address
+	indices map[uint64]int // Instruction index of each program counter
+	entries []sourceEntry  // Source location of each instruction
+	files   []string       // Source files referenced by the entries
+	sources map[int][]byte // Cache of the loaded source files
+}
+
+// newSourceMap parses the combined-json output of solc (containing at least the
+// bin-runtime and srcmap-runtime fields) and creates a source mapping for the
+// named contract deployed at the given address. If no name is given, the output
+// must contain a single deployable contract.
+func newSourceMap(address common.Address, combined []byte, name string) (*sourceMap, error) {
+	var output struct {
+		Contracts map[string]struct {
+			BinRuntime    string `json:"bin-runtime"`
+			SrcMapRuntime string `json:"srcmap-runtime"`
+		}
+		SourceList []string `json:"sourceList"`
+	}
+	if err := json.Unmarshal(combined, &output); err != nil {
+		return nil, fmt.Errorf("invalid solc output: %v", err)
+	}
+	if name == "" {
+		for contract, info := range output.Contracts {
+			if info.BinRuntime == "" {
+				continue
+			}
+			if name != "" {
+				return nil, errors.New("solc output contains multiple contracts, specify one")
+			}
+			name = contract
+		}
+	}
+	info, ok := output.Contracts[name]
+	if !ok || info.BinRuntime == "" {
+		return nil, fmt.Errorf("contract %q not found in solc output", name)
+	}
+	code, err := hex.DecodeString(info.BinRuntime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid runtime code of %s: %v", name, err)
+	}
+	entries, err := parseSourceMap(info.SrcMapRuntime)
+	if err != nil {
+		return nil, err
+	}
+	// Index the instructions of the code, as the mapping has an entry per instruction
+	indices := make(map[uint64]int)
+	for pc, index := uint64(0), 0; pc < uint64(len(code)); index++ {
+		indices[pc] = index
+
+		op := vm.OpCode(code[pc])
+		if op.IsPush() {
+			pc += uint64(op - vm.PUSH1 + 1)
+		}
+		pc++
+	}
+	return &sourceMap{
+		address: address,
+		indices: indices,
+		entries: entries,
+		files:   output.SourceList,
+		sources: make(map[int][]byte),
+	}, nil
+}
+
+// parseSourceMap decodes a compressed Solidity source mapping, where each entry
+// is in the form of s:l:f:j and omitted fields are inherited from the previous
+// entry.
+func parseSourceMap(srcmap string) ([]sourceEntry, error) {
+	var (
+		entries []sourceEntry
+		last    sourceEntry
+	)
+	for _, item := range strings.Split(srcmap, ";") {
+		fields := strings.Split(item, ":")
+		for i, target := range []*int{&last.start, &last.length, &last.file} {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			value, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid source map entry %q", item)
+			}
+			*target = value
+		}
+		entries = append(entries, last)
+	}
+	return entries, nil
+}
+
+// lookup returns the source location of the instruction at the given program
+// counter, formatted as file:line along with the source line itself.
+func (s *sourceMap) lookup(pc uint64) (string, error) {
+	index, ok := s.indices[pc]
+	if !ok || index >= len(s.entries) {
+		return "", fmt.Errorf("no source location for pc %d", pc)
+	}
+	entry := s.entries[index]
+	if entry.file < 0 || entry.file >= len(s.files) {
+		return "", errors.New("no source location, compiler generated code")
+	}
+	source, ok := s.sources[entry.file]
+	if !ok {
+		var err error
+		if source, err = ioutil.ReadFile(s.files[entry.file]); err != nil {
+			return "", err
+		}
+		s.sources[entry.file] = source
+	}
+	if entry.start < 0 || entry.start > len(source) {
+		return "", fmt.Errorf("source location out of bounds of %s", s.files[entry.file])
+	}
+	var (
+		line  = bytes.Count(source[:entry.start], []byte("\n")) + 1
+		begin = bytes.LastIndexByte(source[:entry.start], '\n') + 1
+		end   = bytes.IndexByte(source[entry.start:], '\n')
+	)
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += entry.start
+	}
+	return fmt.Sprintf("%s:%d: %s", s.files[entry.file], line, strings.TrimSpace(string(source[begin:end]))), nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// scriptedPrompter implements UserPrompter, feeding a fixed list of commands.
+type scriptedPrompter struct {
+	hookedPrompter
+	commands []string
+}
+
+func (p *scriptedPrompter) PromptInput(prompt string) (string, error) {
+	if len(p.commands) == 0 {
+		return "", io.EOF
+	}
+	command := p.commands[0]
+	p.commands = p.commands[1:]
+	return command, nil
+}
+
+// debugTestSteps is the trace of a transaction calling into a contract at 0xbb,
+// which writes a storage slot before returning to the caller.
+var debugTestSteps = []debugStep{
+	{Pc: 0, Op: "PUSH1", Depth: 1},
+	{Pc: 2, Op: "PUSH1", Depth: 1, Stack: []string{"00"}},
+	{Pc: 4, Op: "CALL", Depth: 1, Stack: []string{"00", "00", "00", "00", "00", "00000000000000000000000000000000000000000000000000000000000000bb", "ffff"}},
+	{Pc: 0, Op: "PUSH1", Depth: 2},
+	{Pc: 2, Op: "PUSH1", Depth: 2, Stack: []string{"01"}},
+	{Pc: 4, Op: "SSTORE", Depth: 2, Stack: []string{"01", "02"}, Storage: map[string]string{"02": "01"}},
+	{Pc: 5, Op: "STOP", Depth: 2, Storage: map[string]string{"02": "01"}},
+	{Pc: 5, Op: "POP", Depth: 1, Stack: []string{"01"}, Memory: []string{"2a"}},
+	{Pc: 6, Op: "STOP", Depth: 1, Memory: []string{"2a"}},
+}
+
+// Tests that the debugger moves through the steps as instructed.
+func TestDebuggerStepping(t *testing.T) {
+	to := common.HexToAddress("0xaa")
+
+	tests := []struct {
+		commands []string
+		pos      int
+		output   []string
+	}{
+		{[]string{"step"}, 1, nil},
+		{[]string{"step 3", "back"}, 2, nil},
+		{[]string{"s", ""}, 2, nil},
+		{[]string{"s 2", "next"}, 7, nil},
+		{[]string{"s 4", "out"}, 7, nil},
+		{[]string{"continue"}, 8, []string{"End of trace"}},
+		{[]string{"break op SSTORE", "c"}, 5, []string{"Breakpoint 1 set on op SSTORE", "Breakpoint 1 (op SSTORE) hit"}},
+		{[]string{"b depth 2", "c", "c"}, 8, []string{"Breakpoint 1 (depth 2) hit"}},
+		{[]string{"b pc 4", "b pc 5", "d 1", "c"}, 6, []string{"Breakpoint 1 deleted", "Breakpoint 2 (pc 5) hit"}},
+		{[]string{"b op FOO", "breaks"}, 0, []string{`unknown opcode "FOO"`, "No breakpoints"}},
+		{[]string{"s 5", "stack", "storage"}, 5, []string{"   0: 0x02\n   1: 0x01\n", "0x02: 0x01\n"}},
+		{[]string{"s 7", "memory"}, 7, []string{"0x0000: 2a\n"}},
+		{[]string{"s 5", "frames"}, 5, []string{
+			"#2 0x00000000000000000000000000000000000000bb (CALL, entered at step 4)\n#1 0x00000000000000000000000000000000000000AA (transaction)\n",
+		}},
+		{[]string{"quit", "step"}, 0, nil},
+	}
+	for i, tt := range tests {
+		out := new(bytes.Buffer)
+		d := newDebugger(debugTestSteps, &to, nil, out)
+		if err := d.run(&scriptedPrompter{commands: tt.commands}); err != nil {
+			t.Fatalf("test %d: failed to run debugger: %v", i, err)
+		}
+		if d.pos != tt.pos {
+			t.Errorf("test %d: position mismatch: have %d, want %d", i, d.pos, tt.pos)
+		}
+		for _, want := range tt.output {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("test %d: output missing %q:\n%s", i, want, out.String())
+			}
+		}
+	}
+}
+
+// Tests that program counters are mapped to Solidity sources based on the
+// combined-json output of solc.
+func TestDebuggerSourceMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debugger-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := "contract A {\n  function f() {\n    x = 1;\n  }\n}\n"
+	path := filepath.Join(dir, "A.sol")
+	if err := ioutil.WriteFile(path, []byte(source), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// PUSH1 1, PUSH1 0, SSTORE, STOP
+	combined := fmt.Sprintf(`{
+		"contracts": {
+			"%[1]s:A": {"bin-runtime": "6001600055", "srcmap-runtime": "0:50:0;34:6;;::-1"},
+			"%[1]s:I": {"bin-runtime": "", "srcmap-runtime": ""}
+		},
+		"sourceList": ["%[1]s"]
+	}`, path)
+
+	addr := common.HexToAddress("0xaa")
+	srcmap, err := newSourceMap(addr, []byte(combined), "")
+	if err != nil {
+		t.Fatalf("failed to create source map: %v", err)
+	}
+	for pc, want := range map[uint64]string{
+		0: path + ":1: contract A {",
+		2: path + ":3: x = 1;",
+		4: path + ":3: x = 1;",
+	} {
+		have, err := srcmap.lookup(pc)
+		if err != nil {
+			t.Errorf("pc %d: lookup failed: %v", pc, err)
+		} else if have != want {
+			t.Errorf("pc %d: location mismatch: have %q, want %q", pc, have, want)
+		}
+	}
+	if _, err := srcmap.lookup(5); err == nil {
+		t.Errorf("expected compiler generated code to have no location")
+	}
+	if _, err := srcmap.lookup(1); err == nil {
+		t.Errorf("expected push data to have no location")
+	}
+	if _, err := newSourceMap(addr, []byte(combined), "B"); err == nil {
+		t.Errorf("expected unknown contract to be rejected")
+	}
+	// Steps executing the mapped code should report their source location
+	out := new(bytes.Buffer)
+	steps := []debugStep{{Pc: 0, Op: "PUSH1", Depth: 1}, {Pc: 2, Op: "PUSH1", Depth: 1}}
+	d := newDebugger(steps, &addr, srcmap, out)
+	if err := d.run(&scriptedPrompter{commands: []string{"step", "source"}}); err != nil {
+		t.Fatalf("failed to run debugger: %v", err)
+	}
+	if want := "  at " + path + ":3: x = 1;\n" + path + ":3: x = 1;\n"; !strings.HasSuffix(out.String(), want) {
+		t.Errorf("source output mismatch: have %q, want suffix %q", out.String(), want)
+	}
+}
@@ -192,6 +192,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			TrieTimeLimit:  5 * time.Minute,
 		}
 	}
+	// Make sure the custom precompiles of the chain are all available
+	if err := vm.CheckPrecompiles(chainConfig); err != nil {
+		return nil, err
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -129,3 +131,166 @@ func WriteBloomBits(db ethdb.KeyValueWriter, bit uint, section uint64, head comm
 		log.Crit("Failed to store bloom bits", "err", err)
 	}
 }
+
+// InternalTxs is the encoded list of internal transactions touching an account
+// within a single block.
+type InternalTxs struct {
+	Number uint64      // Number of the block containing the transactions
+	Hash   common.Hash // Hash of the block containing the transactions
+	Blob   []byte      // Encoded internal transactions
+}
+
+// ReadInternalTxs retrieves the encoded internal transactions touching the given
+// address within a block range. Entries of blocks which have been reorged out
+// remain until the indexer replaces them, it's up to the caller to check
+// canonicality.
+func ReadInternalTxs(db ethdb.Iteratee, address common.Address, from, to uint64) []InternalTxs {
+	prefix := append(internalTxPrefix, address.Bytes()...)
+
+	it := db.NewIteratorWithStart(internalTxKey(address, from, common.Hash{}))
+	defer it.Release()
+
+	var entries []InternalTxs
+	for it.Next() {
+		key := it.Key()
+		if !bytes.HasPrefix(key, prefix) || len(key) != len(prefix)+8+common.HashLength {
+			break
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		entries = append(entries, InternalTxs{
+			Number: number,
+			Hash:   common.BytesToHash(key[len(prefix)+8:]),
+			Blob:   common.CopyBytes(it.Value()),
+		})
+	}
+	return entries
+}
+
+// WriteInternalTxs stores the encoded internal transactions touching the given
+// address within a block.
+func WriteInternalTxs(db ethdb.KeyValueWriter, address common.Address, number uint64, hash common.Hash, blob []byte) {
+	if err := db.Put(internalTxKey(address, number, hash), blob); err != nil {
+		log.Crit("Failed to store internal transactions", "err", err)
+	}
+}
+
+// DeleteInternalTxs removes the internal transactions touching the given address
+// within a block.
+func DeleteInternalTxs(db ethdb.KeyValueWriter, address common.Address, number uint64, hash common.Hash) {
+	if err := db.Delete(internalTxKey(address, number, hash)); err != nil {
+		log.Crit("Failed to delete internal transactions", "err", err)
+	}
+}
+
+// AccountTxs is the list of transactions touching an account within a single
+// block, either directly or through internal transactions.
+type AccountTxs struct {
+	Number  uint64      // Number of the block containing the transactions
+	Hash    common.Hash // Hash of the block containing the transactions
+	Indexes []uint64    // Positions of the transactions within the block
+}
+
+// ReadAccountTxs retrieves the positions of the transactions touching the given
+// address within a block range. Entries of blocks which have been reorged out
+// remain until the indexer replaces them, it's up to the caller to check
+// canonicality.
+func ReadAccountTxs(db ethdb.Iteratee, address common.Address, from, to uint64) []AccountTxs {
+	var entries []AccountTxs
+	IterateAccountTxs(db, address, from, to, func(entry AccountTxs) bool {
+		entries = append(entries, entry)
+		return true
+	})
+	return entries
+}
+
+// IterateAccountTxs iterates over the positions of the transactions touching the
+// given address within a block range in ascending block order, until the callback
+// returns false. Similarly to ReadAccountTxs, reorged entries are not filtered out.
+func IterateAccountTxs(db ethdb.Iteratee, address common.Address, from, to uint64, fn func(AccountTxs) bool) {
+	prefix := append(accountTxPrefix, address.Bytes()...)
+
+	it := db.NewIteratorWithStart(accountTxKey(address, from, common.Hash{}))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if !bytes.HasPrefix(key, prefix) || len(key) != len(prefix)+8+common.HashLength {
+			return
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			return
+		}
+		var indexes []uint64
+		if err := rlp.DecodeBytes(it.Value(), &indexes); err != nil {
+			log.Error("Invalid account transaction index entry", "number", number, "err", err)
+			continue
+		}
+		entry := AccountTxs{
+			Number:  number,
+			Hash:    common.BytesToHash(key[len(prefix)+8:]),
+			Indexes: indexes,
+		}
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+// WriteAccountTxs stores the positions of the transactions touching the given
+// address within a block.
+func WriteAccountTxs(db ethdb.KeyValueWriter, address common.Address, number uint64, hash common.Hash, indexes []uint64) {
+	data, err := rlp.EncodeToBytes(indexes)
+	if err != nil {
+		log.Crit("Failed to encode account transactions", "err", err)
+	}
+	if err := db.Put(accountTxKey(address, number, hash), data); err != nil {
+		log.Crit("Failed to store account transactions", "err", err)
+	}
+}
+
+// DeleteAccountTxs removes the positions of the transactions touching the given
+// address within a block.
+func DeleteAccountTxs(db ethdb.KeyValueWriter, address common.Address, number uint64, hash common.Hash) {
+	if err := db.Delete(accountTxKey(address, number, hash)); err != nil {
+		log.Crit("Failed to delete account transactions", "err", err)
+	}
+}
+
+// TracedBlock is the block indexed at a given height by the trace indexer, along
+// with the accounts it wrote internal and account transaction entries for. It
+// allows dropping the entries once a reorged out block is replaced.
+type TracedBlock struct {
+	Hash     common.Hash
+	Accounts []common.Address
+}
+
+// ReadTracedBlock retrieves the block indexed at the given height by the trace
+// indexer, or nil if none was.
+func ReadTracedBlock(db ethdb.KeyValueReader, number uint64) *TracedBlock {
+	data, _ := db.Get(tracedBlockKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	block := new(TracedBlock)
+	if err := rlp.DecodeBytes(data, block); err != nil {
+		log.Error("Invalid traced block entry", "number", number, "err", err)
+		return nil
+	}
+	return block
+}
+
+// WriteTracedBlock stores the block indexed at the given height by the trace
+// indexer.
+func WriteTracedBlock(db ethdb.KeyValueWriter, number uint64, block *TracedBlock) {
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Crit("Failed to encode traced block", "err", err)
+	}
+	if err := db.Put(tracedBlockKey(number), data); err != nil {
+		log.Crit("Failed to store traced block", "err", err)
+	}
+}
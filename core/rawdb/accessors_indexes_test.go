@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
@@ -106,3 +107,110 @@ func TestLookupStorage(t *testing.T) {
 		})
 	}
 }
+
+// Tests that internal transactions can be stored and retrieved by block range.
+func TestInternalTxStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		alice = common.BytesToAddress([]byte{0x01})
+		bob   = common.BytesToAddress([]byte{0x02})
+		hash  = func(n uint64) common.Hash { return common.BytesToHash([]byte{byte(n)}) }
+	)
+	for _, n := range []uint64{1, 5, 10, 256} {
+		WriteInternalTxs(db, alice, n, hash(n), []byte{byte(n)})
+	}
+	WriteInternalTxs(db, bob, 7, hash(7), []byte{0x07})
+
+	tests := []struct {
+		from, to uint64
+		numbers  []uint64
+	}{
+		{0, 1000, []uint64{1, 5, 10, 256}},
+		{2, 10, []uint64{5, 10}},
+		{6, 9, nil},
+		{256, 256, []uint64{256}},
+	}
+	for i, tt := range tests {
+		entries := ReadInternalTxs(db, alice, tt.from, tt.to)
+		if len(entries) != len(tt.numbers) {
+			t.Fatalf("test %d: entry count mismatch: have %d, want %d", i, len(entries), len(tt.numbers))
+		}
+		for j, entry := range entries {
+			if entry.Number != tt.numbers[j] || entry.Hash != hash(entry.Number) || !bytes.Equal(entry.Blob, []byte{byte(entry.Number)}) {
+				t.Errorf("test %d, entry %d: mismatch: have %d/%x/%x", i, j, entry.Number, entry.Hash, entry.Blob)
+			}
+		}
+	}
+	if entries := ReadInternalTxs(db, bob, 0, 1000); len(entries) != 1 || entries[0].Number != 7 {
+		t.Fatalf("other account entries mismatch: have %v", entries)
+	}
+}
+
+// Tests that the transaction positions of accounts can be stored and retrieved
+// by block range.
+func TestAccountTxStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		alice = common.BytesToAddress([]byte{0x01})
+		bob   = common.BytesToAddress([]byte{0x02})
+		hash  = func(n uint64) common.Hash { return common.BytesToHash([]byte{byte(n)}) }
+	)
+	for _, n := range []uint64{1, 5, 10, 256} {
+		WriteAccountTxs(db, alice, n, hash(n), []uint64{0, n})
+	}
+	WriteAccountTxs(db, bob, 7, hash(7), []uint64{3})
+
+	entries := ReadAccountTxs(db, alice, 2, 256)
+	if len(entries) != 3 {
+		t.Fatalf("entry count mismatch: have %d, want %d", len(entries), 3)
+	}
+	for i, entry := range entries {
+		if entry.Hash != hash(entry.Number) || len(entry.Indexes) != 2 || entry.Indexes[0] != 0 || entry.Indexes[1] != entry.Number {
+			t.Errorf("entry %d: mismatch: have %d/%x/%v", i, entry.Number, entry.Hash, entry.Indexes)
+		}
+	}
+	if entries := ReadAccountTxs(db, bob, 0, 1000); len(entries) != 1 || entries[0].Number != 7 || entries[0].Indexes[0] != 3 {
+		t.Fatalf("other account entries mismatch: have %v", entries)
+	}
+	// Check that iteration stops as soon as the callback requests it
+	var visited []uint64
+	IterateAccountTxs(db, alice, 0, 1000, func(entry AccountTxs) bool {
+		visited = append(visited, entry.Number)
+		return len(visited) < 2
+	})
+	if len(visited) != 2 || visited[0] != 1 || visited[1] != 5 {
+		t.Fatalf("iterated entries mismatch: have %v, want [1 5]", visited)
+	}
+}
+
+// Tests that the blocks indexed by the trace indexer can be stored and retrieved,
+// and that the index entries written for them can be deleted.
+func TestTracedBlockStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		alice = common.BytesToAddress([]byte{0x01})
+		bob   = common.BytesToAddress([]byte{0x02})
+		hash  = common.BytesToHash([]byte{0x03})
+	)
+	if block := ReadTracedBlock(db, 1); block != nil {
+		t.Fatalf("non existent traced block returned: %v", block)
+	}
+	WriteTracedBlock(db, 1, &TracedBlock{Hash: hash, Accounts: []common.Address{alice, bob}})
+	if block := ReadTracedBlock(db, 1); block == nil || block.Hash != hash || len(block.Accounts) != 2 || block.Accounts[0] != alice || block.Accounts[1] != bob {
+		t.Fatalf("traced block mismatch: have %v", block)
+	}
+	WriteInternalTxs(db, alice, 1, hash, []byte{0x01})
+	WriteAccountTxs(db, alice, 1, hash, []uint64{0})
+
+	DeleteInternalTxs(db, alice, 1, hash)
+	DeleteAccountTxs(db, alice, 1, hash)
+	if entries := ReadInternalTxs(db, alice, 0, 10); len(entries) != 0 {
+		t.Fatalf("deleted internal transactions returned: %v", entries)
+	}
+	if entries := ReadAccountTxs(db, alice, 0, 10); len(entries) != 0 {
+		t.Fatalf("deleted account transactions returned: %v", entries)
+	}
+}
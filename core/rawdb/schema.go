@@ -53,11 +53,16 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
+	internalTxPrefix  = []byte("x") // internalTxPrefix + address + num (uint64 big endian) + hash -> internal transactions
+	accountTxPrefix   = []byte("X") // accountTxPrefix + address + num (uint64 big endian) + hash -> transaction indexes
+	tracedBlockPrefix = []byte("y") // tracedBlockPrefix + num (uint64 big endian) -> hash and accounts of the trace indexed block
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	TraceIndexPrefix     = []byte("iT") // TraceIndexPrefix is the data table of the internal transaction indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -155,6 +160,21 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// internalTxKey = internalTxPrefix + address + num (uint64 big endian) + hash
+func internalTxKey(address common.Address, number uint64, hash common.Hash) []byte {
+	return append(append(append(internalTxPrefix, address.Bytes()...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// accountTxKey = accountTxPrefix + address + num (uint64 big endian) + hash
+func accountTxKey(address common.Address, number uint64, hash common.Hash) []byte {
+	return append(append(append(accountTxPrefix, address.Bytes()...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// tracedBlockKey = tracedBlockPrefix + num (uint64 big endian)
+func tracedBlockKey(number uint64) []byte {
+	return append(tracedBlockPrefix, encodeBlockNumber(number)...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
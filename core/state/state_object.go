@@ -227,6 +227,9 @@ func (s *stateObject) SetState(db Database, key, value common.Hash) {
 		key:      key,
 		prevalue: prev,
 	})
+	if s.db.tracer != nil {
+		s.db.tracer.CaptureStorageChange(s.address, key, prev, value)
+	}
 	s.setState(key, value)
 }
 
@@ -338,6 +341,9 @@ func (s *stateObject) SetBalance(amount *big.Int) {
 		account: &s.address,
 		prev:    new(big.Int).Set(s.data.Balance),
 	})
+	if s.db.tracer != nil {
+		s.db.tracer.CaptureBalanceChange(s.address, s.data.Balance, amount)
+	}
 	s.setBalance(amount)
 }
 
@@ -408,6 +414,9 @@ func (s *stateObject) SetNonce(nonce uint64) {
 		account: &s.address,
 		prev:    s.data.Nonce,
 	})
+	if s.db.tracer != nil {
+		s.db.tracer.CaptureNonceChange(s.address, s.data.Nonce, nonce)
+	}
 	s.setNonce(nonce)
 }
 
@@ -93,6 +93,9 @@ type StateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Optional tracer notified of the state modifications.
+	tracer Tracer
+
 	// Measurements gathered during execution for debugging purposes
 	AccountReads   time.Duration
 	AccountHashes  time.Duration
@@ -161,6 +164,10 @@ func (self *StateDB) AddLog(log *types.Log) {
 	log.Index = self.logSize
 	self.logs[self.thash] = append(self.logs[self.thash], log)
 	self.logSize++
+
+	if self.tracer != nil {
+		self.tracer.CaptureLog(log)
+	}
 }
 
 func (self *StateDB) GetLogs(hash common.Hash) []*types.Log {
@@ -193,6 +200,9 @@ func (self *StateDB) Preimages() map[common.Hash][]byte {
 // AddRefund adds gas to the refund counter
 func (self *StateDB) AddRefund(gas uint64) {
 	self.journal.append(refundChange{prev: self.refund})
+	if self.tracer != nil {
+		self.tracer.CaptureRefundChange(self.refund, self.refund+gas)
+	}
 	self.refund += gas
 }
 
@@ -203,6 +213,9 @@ func (self *StateDB) SubRefund(gas uint64) {
 	if gas > self.refund {
 		panic("Refund counter below zero")
 	}
+	if self.tracer != nil {
+		self.tracer.CaptureRefundChange(self.refund, self.refund-gas)
+	}
 	self.refund -= gas
 }
 
@@ -411,6 +424,9 @@ func (self *StateDB) Suicide(addr common.Address) bool {
 		prevbalance: new(big.Int).Set(stateObject.Balance()),
 	})
 	stateObject.markSuicided()
+	if self.tracer != nil && stateObject.data.Balance.Sign() != 0 {
+		self.tracer.CaptureBalanceChange(addr, stateObject.data.Balance, new(big.Int))
+	}
 	stateObject.data.Balance = new(big.Int)
 
 	return true
@@ -449,3 +449,69 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// recordingTracer is a state tracer collecting the reported changes as strings.
+type recordingTracer struct {
+	events []string
+}
+
+func (r *recordingTracer) CaptureBalanceChange(addr common.Address, prev, new *big.Int) {
+	r.events = append(r.events, fmt.Sprintf("balance %x %v->%v", addr[19:], prev, new))
+}
+
+func (r *recordingTracer) CaptureNonceChange(addr common.Address, prev, new uint64) {
+	r.events = append(r.events, fmt.Sprintf("nonce %x %d->%d", addr[19:], prev, new))
+}
+
+func (r *recordingTracer) CaptureStorageChange(addr common.Address, key, prev, new common.Hash) {
+	r.events = append(r.events, fmt.Sprintf("storage %x %x %x->%x", addr[19:], key[31:], prev[31:], new[31:]))
+}
+
+func (r *recordingTracer) CaptureLog(log *types.Log) {
+	r.events = append(r.events, fmt.Sprintf("log %x %d", log.Address[19:], log.Index))
+}
+
+func (r *recordingTracer) CaptureRefundChange(prev, new uint64) {
+	r.events = append(r.events, fmt.Sprintf("refund %d->%d", prev, new))
+}
+
+// Tests that the state modifications are reported to the tracer, and that noop
+// changes and copies of the state are not.
+func TestStateTracer(t *testing.T) {
+	sdb, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	addr := common.HexToAddress("aa")
+
+	tracer := new(recordingTracer)
+	sdb.SetTracer(tracer)
+
+	sdb.AddBalance(addr, big.NewInt(10))
+	sdb.SubBalance(addr, big.NewInt(3))
+	sdb.AddBalance(addr, new(big.Int))
+	sdb.SetNonce(addr, 1)
+	sdb.SetState(addr, common.HexToHash("01"), common.HexToHash("02"))
+	sdb.SetState(addr, common.HexToHash("01"), common.HexToHash("02"))
+	sdb.AddLog(&types.Log{Address: addr})
+	sdb.AddRefund(100)
+	sdb.SubRefund(40)
+
+	cpy := sdb.Copy()
+	cpy.AddBalance(addr, big.NewInt(1))
+
+	sdb.Suicide(addr)
+	sdb.SetTracer(nil)
+	sdb.AddBalance(addr, big.NewInt(1))
+
+	want := []string{
+		"balance aa 0->10",
+		"balance aa 10->7",
+		"nonce aa 0->1",
+		"storage aa 01 00->02",
+		"log aa 0",
+		"refund 0->100",
+		"refund 100->60",
+		"balance aa 7->0",
+	}
+	if !reflect.DeepEqual(tracer.events, want) {
+		t.Fatalf("event mismatch:\nhave %q\nwant %q", tracer.events, want)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tracer is notified of the modifications made to the state, as they happen.
+// Reverting to a snapshot is not reported; tracers interested in which changes
+// persist need to follow the call frames the changes were made in.
+//
+// Note that the values passed are the live state data; make copies if you need
+// to retain them beyond the current call.
+type Tracer interface {
+	CaptureBalanceChange(addr common.Address, prev, new *big.Int)
+	CaptureNonceChange(addr common.Address, prev, new uint64)
+	CaptureStorageChange(addr common.Address, key, prev, new common.Hash)
+	CaptureLog(log *types.Log)
+	CaptureRefundChange(prev, new uint64)
+}
+
+// SetTracer sets the tracer to notify of the state modifications, or removes it
+// if nil. The tracer is not carried over to copies of the state.
+func (self *StateDB) SetTracer(tracer Tracer) {
+	self.tracer = tracer
+}
@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -125,6 +126,35 @@ func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition
 	}
 }
 
+// ExecutionResult includes all output after executing a given EVM message, no
+// matter whether the execution itself was successful or not.
+type ExecutionResult struct {
+	UsedGas    uint64 // Total used gas, including the refunded gas
+	Err        error  // Any error encountered during the execution (listed in core/vm/errors.go)
+	ReturnData []byte // Data returned by the execution, or supplied with the REVERT opcode
+}
+
+// Failed returns whether the execution encountered an error.
+func (result *ExecutionResult) Failed() bool { return result.Err != nil }
+
+// Return is a helper function to help caller distinguish between revert reason
+// and function return. Return returns the data after execution if no error occurs.
+func (result *ExecutionResult) Return() []byte {
+	if result.Err != nil {
+		return nil
+	}
+	return common.CopyBytes(result.ReturnData)
+}
+
+// Revert returns the concrete revert reason if the execution is aborted by the
+// REVERT opcode. Note the reason can be empty if the contract returns nothing.
+func (result *ExecutionResult) Revert() []byte {
+	if result.Err != vm.ErrExecutionReverted {
+		return nil
+	}
+	return common.CopyBytes(result.ReturnData)
+}
+
 // ApplyMessage computes the new state by applying the given message
 // against the old state within the environment.
 //
@@ -136,6 +166,12 @@ func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, bool,
 	return NewStateTransition(evm, msg, gp).TransitionDb()
 }
 
+// ApplyMessageResult is the same as ApplyMessage, but returns the outcome of the
+// EVM execution in detail, including the error it failed with, if any.
+func ApplyMessageResult(evm *vm.EVM, msg Message, gp *GasPool) (*ExecutionResult, error) {
+	return NewStateTransition(evm, msg, gp).Execute()
+}
+
 // to returns the recipient of the message.
 func (st *StateTransition) to() common.Address {
 	if st.msg == nil || st.msg.To() == nil /* contract creation */ {
@@ -185,8 +221,27 @@ func (st *StateTransition) preCheck() error {
 // returning the result including the used gas. It returns an error if failed.
 // An error indicates a consensus issue.
 func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bool, err error) {
-	if err = st.preCheck(); err != nil {
-		return
+	result, err := st.Execute()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return result.ReturnData, result.UsedGas, result.Failed(), nil
+}
+
+// Execute will transition the state by applying the current message and
+// returning the outcome of the EVM execution. It returns an error if the message
+// could not be applied at all, which indicates a consensus issue.
+func (st *StateTransition) Execute() (*ExecutionResult, error) {
+	// Report the state modifications of the message to the tracer, if it's
+	// interested in them
+	if tracer, ok := st.evm.Tracer().(state.Tracer); ok {
+		if statedb, ok := st.state.(*state.StateDB); ok {
+			statedb.SetTracer(tracer)
+			defer statedb.SetTracer(nil)
+		}
+	}
+	if err := st.preCheck(); err != nil {
+		return nil, err
 	}
 	msg := st.msg
 	sender := vm.AccountRef(msg.From())
@@ -197,14 +252,15 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 	// Pay intrinsic gas
 	gas, err := IntrinsicGas(st.data, contractCreation, homestead, istanbul)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	if err = st.useGas(gas); err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 
 	var (
 		evm = st.evm
+		ret []byte
 		// vm errors do not effect consensus and are therefor
 		// not assigned to err, except for insufficient balance
 		// error.
@@ -223,13 +279,17 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		// sufficient balance to make the transfer happen. The first
 		// balance transfer may never fail.
 		if vmerr == vm.ErrInsufficientBalance {
-			return nil, 0, false, vmerr
+			return nil, vmerr
 		}
 	}
 	st.refundGas()
 	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
 
-	return ret, st.gasUsed(), vmerr != nil, err
+	return &ExecutionResult{
+		UsedGas:    st.gasUsed(),
+		Err:        vmerr,
+		ReturnData: ret,
+	}, nil
 }
 
 func (st *StateTransition) refundGas() {
@@ -240,6 +300,9 @@ func (st *StateTransition) refundGas() {
 	}
 	st.gas += refund
 
+	if tracer, ok := st.evm.Tracer().(vm.RefundTracer); ok {
+		tracer.CaptureGasRefund(refund, st.gas)
+	}
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
 	st.state.AddBalance(st.msg.From(), remaining)
@@ -0,0 +1,130 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AccessTuple is an account accessed during execution, along with the keys of
+// its storage slots that were read or written.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// AccessListTracer is a Tracer collecting every account and storage slot touched
+// during execution: the sender and recipient of the message, the targets of all
+// calls and contract creations, the accounts whose balance or code was queried
+// and the storage slots loaded or stored.
+//
+// Accesses are recorded regardless of whether the call frame making them was
+// reverted later on.
+type AccessListTracer struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+// NewAccessListTracer creates a new access list tracer.
+func NewAccessListTracer() *AccessListTracer {
+	return &AccessListTracer{
+		accounts: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// addAccount records an access to the given account.
+func (t *AccessListTracer) addAccount(addr common.Address) {
+	if _, ok := t.accounts[addr]; !ok {
+		t.accounts[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// addSlot records an access to a storage slot of the given account.
+func (t *AccessListTracer) addSlot(addr common.Address, slot common.Hash) {
+	t.addAccount(addr)
+	t.accounts[addr][slot] = struct{}{}
+}
+
+// CaptureStart implements the Tracer interface to record the sender and the
+// recipient of the message.
+func (t *AccessListTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.addAccount(from)
+	t.addAccount(to)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to record the state accessed by
+// a single step of VM execution.
+func (t *AccessListTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if stack.len() < 1 {
+		return nil
+	}
+	switch op {
+	case SLOAD, SSTORE:
+		t.addSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+	case BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH, SELFDESTRUCT:
+		t.addAccount(common.BigToAddress(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *AccessListTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// CaptureEnter implements the FrameTracer interface to record the targets of
+// nested calls and contract creations.
+func (t *AccessListTracer) CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	t.addAccount(to)
+	return nil
+}
+
+// CaptureExit implements the FrameTracer interface.
+func (t *AccessListTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// AccessList returns the accessed accounts and storage slots, sorted by address
+// and storage key respectively.
+func (t *AccessListTracer) AccessList() []AccessTuple {
+	list := make([]AccessTuple, 0, len(t.accounts))
+	for addr, slots := range t.accounts {
+		tuple := AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		sort.Slice(tuple.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(tuple.StorageKeys[i][:], tuple.StorageKeys[j][:]) < 0
+		})
+		list = append(list, tuple)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list
+}
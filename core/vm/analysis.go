@@ -16,6 +16,34 @@
 
 package vm
 
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// analysisCacheSize is the number of JUMPDEST analyses to keep around, shared by
+// all the EVM instances of the process. Only the analyses of code up to the size
+// limit of contracts are cached, so the cache takes up at most about 3MB.
+const analysisCacheSize = 1024
+
+// analysisCache holds the JUMPDEST analyses by code hash, so that hot contracts
+// don't need to be reanalysed in every transaction calling them.
+var analysisCache, _ = lru.New(analysisCacheSize)
+
+// cachedCodeBitmap returns the JUMPDEST analysis of the code with the given hash,
+// retrieving it from the shared cache if available.
+func cachedCodeBitmap(hash common.Hash, code []byte) bitvec {
+	if cached, ok := analysisCache.Get(hash); ok {
+		return cached.(bitvec)
+	}
+	bits := codeBitmap(code)
+	if len(code) <= params.MaxCodeSize {
+		analysisCache.Add(hash, bits)
+	}
+	return bits
+}
+
 // bitvec is a bit vector which maps bytes in a program.
 // An unset bit means the byte is an opcode, a set bit means
 // it's data (i.e. argument of PUSHxx).
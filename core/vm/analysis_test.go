@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestJumpDestAnalysis(t *testing.T) {
@@ -55,6 +56,25 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 }
 
+func TestCachedCodeBitmap(t *testing.T) {
+	code := []byte{byte(PUSH1), 0x01, byte(JUMPDEST)}
+	hash := crypto.Keccak256Hash(code)
+
+	bits := cachedCodeBitmap(hash, code)
+	if !bits.codeSegment(2) || bits.codeSegment(1) {
+		t.Fatalf("invalid analysis: %x", bits)
+	}
+	if cached, ok := analysisCache.Get(hash); !ok || &cached.(bitvec)[0] != &bits[0] {
+		t.Fatalf("analysis not cached")
+	}
+	// Code exceeding the contract size limit must not be cached
+	large := make([]byte, params.MaxCodeSize+1)
+	cachedCodeBitmap(crypto.Keccak256Hash(large), large)
+	if analysisCache.Contains(crypto.Keccak256Hash(large)) {
+		t.Fatalf("oversized code analysis cached")
+	}
+}
+
 func BenchmarkJumpdestAnalysis_1200k(bench *testing.B) {
 	// 1.4 ms
 	code := make([]byte, 1200000)
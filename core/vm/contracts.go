@@ -20,7 +20,9 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -75,6 +77,90 @@ var PrecompiledContractsIstanbul = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{9}): &blake2F{},
 }
 
+var (
+	customPrecompiles     = make(map[string]PrecompiledContract) // Custom precompiled contracts by name
+	customPrecompilesLock sync.RWMutex
+)
+
+// RegisterPrecompile makes a precompiled contract implementation available under
+// the given name, so chain configurations can activate it at an address. It must
+// be registered before setting up any chain using it.
+func RegisterPrecompile(name string, p PrecompiledContract) error {
+	if name == "" {
+		return errors.New("empty precompile name")
+	}
+	customPrecompilesLock.Lock()
+	defer customPrecompilesLock.Unlock()
+
+	if _, ok := customPrecompiles[name]; ok {
+		return fmt.Errorf("precompile %q already registered", name)
+	}
+	customPrecompiles[name] = p
+	return nil
+}
+
+// CheckPrecompiles verifies that the custom precompiled contracts activated by a
+// chain configuration are registered and don't replace standard ones.
+func CheckPrecompiles(config *params.ChainConfig) error {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+
+	for addr, p := range config.Precompiles {
+		if p == nil {
+			return fmt.Errorf("precompile %x: missing configuration", addr)
+		}
+		if _, ok := PrecompiledContractsIstanbul[addr]; ok {
+			return fmt.Errorf("precompile %x: address of a standard precompile", addr)
+		}
+		if _, ok := customPrecompiles[p.Name]; !ok {
+			return fmt.Errorf("precompile %x: unknown implementation %q", addr, p.Name)
+		}
+	}
+	return nil
+}
+
+// activePrecompiles returns the precompiled contracts active at the given block,
+// the standard ones of the chain rules extended with the custom ones activated by
+// the chain configuration.
+func activePrecompiles(config *params.ChainConfig, rules params.Rules, number *big.Int) map[common.Address]PrecompiledContract {
+	precompiles := PrecompiledContractsHomestead
+	if rules.IsByzantium {
+		precompiles = PrecompiledContractsByzantium
+	}
+	if rules.IsIstanbul {
+		precompiles = PrecompiledContractsIstanbul
+	}
+	if len(config.Precompiles) == 0 {
+		return precompiles
+	}
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+
+	var active map[common.Address]PrecompiledContract
+	for addr := range config.Precompiles {
+		if !config.IsPrecompileActive(addr, number) {
+			continue
+		}
+		p, ok := customPrecompiles[config.Precompiles[addr].Name]
+		if !ok {
+			// Chains are checked upon setup, only reachable on programming errors
+			panic(fmt.Sprintf("precompile %x: unknown implementation %q", addr, config.Precompiles[addr].Name))
+		}
+		// Copy the standard set upon the first activation, it's shared
+		if active == nil {
+			active = make(map[common.Address]PrecompiledContract, len(precompiles)+len(config.Precompiles))
+			for standard, contract := range precompiles {
+				active[standard] = contract
+			}
+		}
+		active[addr] = p
+	}
+	if active == nil {
+		return precompiles
+	}
+	return active
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -17,12 +17,16 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -574,3 +578,64 @@ func TestPrecompileBlake2FMalformedInput(t *testing.T) {
 		testPrecompiledFailure("09", test, t)
 	}
 }
+
+// echoPrecompile is a custom precompiled contract returning its input.
+type echoPrecompile struct{}
+
+func (echoPrecompile) RequiredGas(input []byte) uint64  { return 100 + uint64(len(input)) }
+func (echoPrecompile) Run(input []byte) ([]byte, error) { return input, nil }
+
+// Tests that custom precompiled contracts are activated at their configured block
+// and are checked upon chain setup.
+func TestCustomPrecompile(t *testing.T) {
+	if err := RegisterPrecompile("test-echo", echoPrecompile{}); err != nil {
+		t.Fatalf("failed to register precompile: %v", err)
+	}
+	if err := RegisterPrecompile("test-echo", echoPrecompile{}); err == nil {
+		t.Fatalf("duplicate precompile registered")
+	}
+	addr := common.BytesToAddress([]byte{0x01, 0x00})
+
+	config := *params.TestChainConfig
+	config.Precompiles = map[common.Address]*params.PrecompileConfig{
+		addr: {Name: "test-echo", Block: big.NewInt(5)},
+	}
+	if err := CheckPrecompiles(&config); err != nil {
+		t.Fatalf("valid configuration rejected: %v", err)
+	}
+	for number, active := range map[int64]bool{4: false, 5: true} {
+		evm := NewEVM(Context{BlockNumber: big.NewInt(number)}, nil, &config, Config{})
+		if _, ok := evm.Precompile(addr); ok != active {
+			t.Errorf("block %d: activation mismatch: have %v, want %v", number, ok, active)
+		}
+		if _, ok := evm.Precompile(common.BytesToAddress([]byte{1})); !ok {
+			t.Errorf("block %d: standard precompile missing", number)
+		}
+	}
+	// Calls are charged the same way as for the standard precompiles
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	vmctx := Context{
+		BlockNumber: big.NewInt(5),
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	evm := NewEVM(vmctx, statedb, &config, Config{})
+	ret, gas, err := evm.Call(AccountRef(common.Address{}), addr, []byte{0xca, 0xfe}, 1000, new(big.Int))
+	if err != nil || !bytes.Equal(ret, []byte{0xca, 0xfe}) || gas != 1000-102 {
+		t.Errorf("call result mismatch: have %x, %d gas left, %v, want cafe, %d gas left", ret, gas, err, 1000-102)
+	}
+	if _, _, err := evm.Call(AccountRef(common.Address{}), addr, []byte{0xca, 0xfe}, 101, new(big.Int)); err != ErrOutOfGas {
+		t.Errorf("call error mismatch: have %v, want %v", err, ErrOutOfGas)
+	}
+	// Unknown implementations and shadowing standard precompiles are rejected
+	config.Precompiles[addr] = &params.PrecompileConfig{Name: "test-unknown", Block: big.NewInt(5)}
+	if err := CheckPrecompiles(&config); err == nil {
+		t.Errorf("unknown implementation accepted")
+	}
+	config.Precompiles = map[common.Address]*params.PrecompileConfig{
+		common.BytesToAddress([]byte{1}): {Name: "test-echo", Block: big.NewInt(5)},
+	}
+	if err := CheckPrecompiles(&config); err == nil {
+		t.Errorf("standard precompile replacement accepted")
+	}
+}
@@ -104,6 +104,24 @@ type Tracer interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error
 }
 
+// FrameTracer is an optional extension of Tracer, notified whenever a call frame
+// is entered or exited below the outermost one, which is reported through
+// CaptureStart and CaptureEnd. Calls failing before their execution starts (e.g.
+// due to the depth limit or insufficient balance) are reported too.
+//
+// To receive the balance, nonce, storage, log and refund counter changes made
+// during execution, a tracer may also implement state.Tracer.
+type FrameTracer interface {
+	CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error
+	CaptureExit(output []byte, gasUsed uint64, err error) error
+}
+
+// RefundTracer is an optional extension of Tracer, notified of the gas refunded
+// to the sender once the execution of a transaction finished.
+type RefundTracer interface {
+	CaptureGasRefund(refund uint64, gasLeft uint64) error
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
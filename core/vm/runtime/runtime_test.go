@@ -17,7 +17,9 @@
 package runtime
 
 import (
+	"fmt"
 	"math/big"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -117,6 +120,160 @@ func TestCall(t *testing.T) {
 	}
 }
 
+// frameTracer is a tracer recording the call frames entered and exited.
+type frameTracer struct {
+	*vm.StructLogger
+	events []string
+	gas    []uint64
+}
+
+func (t *frameTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	t.events = append(t.events, fmt.Sprintf("enter %v %x->%x", typ, from[19:], to))
+	t.gas = append(t.gas, gas)
+	return nil
+}
+
+func (t *frameTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	t.events = append(t.events, fmt.Sprintf("exit %v", err))
+	t.gas = append(t.gas, gasUsed)
+	return nil
+}
+
+// Tests that the nested call frames are reported to tracers interested in them.
+func TestFrameTracer(t *testing.T) {
+	state, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	var (
+		caller = common.HexToAddress("0x0a")
+		callee = common.HexToAddress("0x0b")
+	)
+	// Statically call a contract modifying the state, then create an empty contract
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0x0b, byte(vm.PUSH2), 0xff, 0xff, byte(vm.STATICCALL), byte(vm.POP),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CREATE), byte(vm.POP),
+		byte(vm.STOP),
+	})
+	state.SetCode(callee, []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE),
+	})
+	tracer := &frameTracer{StructLogger: vm.NewStructLogger(nil)}
+	_, _, err := Call(caller, nil, &Config{
+		ChainConfig: params.AllEthashProtocolChanges,
+		State:       state,
+		EVMConfig:   vm.Config{Debug: true, Tracer: tracer},
+	})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	want := []string{
+		fmt.Sprintf("enter STATICCALL 0a->%x", callee),
+		"exit evm: write protection",
+		fmt.Sprintf("enter CREATE 0a->%x", crypto.CreateAddress(caller, 0)),
+		"exit <nil>",
+	}
+	if strings.Join(tracer.events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("frame mismatch:\nhave %q\nwant %q", tracer.events, want)
+	}
+	if tracer.gas[0] != 0xffff || tracer.gas[1] != 0xffff {
+		t.Errorf("failed call gas mismatch: have %d given, %d used, want %d", tracer.gas[0], tracer.gas[1], 0xffff)
+	}
+	if tracer.gas[3] != 0 {
+		t.Errorf("empty create gas used mismatch: have %d, want 0", tracer.gas[3])
+	}
+}
+
+// Tests that the access list tracer records every account and storage slot
+// touched, including those of reverted call frames.
+func TestAccessListTracer(t *testing.T) {
+	state, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	var (
+		origin = common.HexToAddress("0x01")
+		caller = common.HexToAddress("0x0a")
+		callee = common.HexToAddress("0x0b")
+		other  = common.HexToAddress("0x0c")
+	)
+	// Load and store a slot, query a balance, then call a contract which reverts
+	// after loading a slot of its own
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 5, byte(vm.PUSH1), 2, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x0c, byte(vm.BALANCE), byte(vm.POP),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0x0b, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.STOP),
+	})
+	state.SetCode(callee, []byte{
+		byte(vm.PUSH1), 3, byte(vm.SLOAD),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT),
+	})
+	tracer := vm.NewAccessListTracer()
+	_, _, err := Call(caller, nil, &Config{
+		ChainConfig: params.AllEthashProtocolChanges,
+		Origin:      origin,
+		State:       state,
+		EVMConfig:   vm.Config{Debug: true, Tracer: tracer},
+	})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	want := []vm.AccessTuple{
+		{Address: origin, StorageKeys: []common.Hash{}},
+		{Address: caller, StorageKeys: []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")}},
+		{Address: callee, StorageKeys: []common.Hash{common.HexToHash("0x03")}},
+		{Address: other, StorageKeys: []common.Hash{}},
+	}
+	if have := tracer.AccessList(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("access list mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
+
+// Benchmarks a tight loop, dominated by the interpreter overhead.
+func BenchmarkEVM_Loop(b *testing.B) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	address := common.HexToAddress("0x0a")
+
+	// Count down from 2^16 in a loop
+	statedb.SetCode(address, []byte{
+		byte(vm.PUSH3), 0x01, 0x00, 0x00, byte(vm.JUMPDEST), byte(vm.PUSH1), 1, byte(vm.SWAP1), byte(vm.SUB),
+		byte(vm.DUP1), byte(vm.PUSH1), 4, byte(vm.JUMPI), byte(vm.STOP),
+	})
+	cfg := &Config{ChainConfig: params.AllEthashProtocolChanges, State: statedb}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Call(address, nil, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmarks calling a contract of close to the maximum code size, jumping once.
+// Its JUMPDEST analysis dominates unless it's reused across calls.
+func BenchmarkEVM_JumpdestAnalysis(b *testing.B) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	address := common.HexToAddress("0x0a")
+
+	// Jump over 700 PUSH32 instructions straight to the end of the code
+	const pushes = 700
+	dest := 4 + pushes*33
+	code := []byte{byte(vm.PUSH2), byte(dest >> 8), byte(dest), byte(vm.JUMP)}
+	for i := 0; i < pushes; i++ {
+		code = append(code, byte(vm.PUSH32))
+		code = append(code, make([]byte, 32)...)
+	}
+	code = append(code, byte(vm.JUMPDEST), byte(vm.STOP))
+	statedb.SetCode(address, code)
+
+	cfg := &Config{ChainConfig: params.AllEthashProtocolChanges, State: statedb}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Call(address, nil, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
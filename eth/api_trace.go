@@ -0,0 +1,623 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxTraceFilterBlocks is the maximum number of blocks trace_filter replays to
+// serve a single request.
+const maxTraceFilterBlocks = 100
+
+// PrivateTraceAPI is the collection of Parity compatible tracing APIs exposed
+// over the private trace endpoint.
+type PrivateTraceAPI struct {
+	eth   *Ethereum
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the Parity compatible
+// private trace methods of the Ethereum service.
+func NewPrivateTraceAPI(eth *Ethereum) *PrivateTraceAPI {
+	return &PrivateTraceAPI{eth: eth, debug: NewPrivateDebugAPI(eth)}
+}
+
+// traceTypes selects the traces produced when replaying transactions.
+type traceTypes struct {
+	trace     bool
+	vmTrace   bool
+	stateDiff bool
+}
+
+// parseTraceTypes converts a list of Parity trace types into a selection.
+func parseTraceTypes(names []string) (traceTypes, error) {
+	var want traceTypes
+	for _, name := range names {
+		switch name {
+		case "trace":
+			want.trace = true
+		case "vmTrace":
+			want.vmTrace = true
+		case "stateDiff":
+			want.stateDiff = true
+		default:
+			return want, fmt.Errorf("unknown trace type %q", name)
+		}
+	}
+	return want, nil
+}
+
+// traceAction is the action part of a flat trace. The fields set depend on
+// the type of the trace.
+type traceAction struct {
+	CallType      string          `json:"callType,omitempty"`
+	From          *common.Address `json:"from,omitempty"`
+	To            *common.Address `json:"to,omitempty"`
+	Gas           *hexutil.Uint64 `json:"gas,omitempty"`
+	Input         *hexutil.Bytes  `json:"input,omitempty"`
+	Init          *hexutil.Bytes  `json:"init,omitempty"`
+	Value         *hexutil.Big    `json:"value,omitempty"`
+	Address       *common.Address `json:"address,omitempty"`
+	RefundAddress *common.Address `json:"refundAddress,omitempty"`
+	Balance       *hexutil.Big    `json:"balance,omitempty"`
+}
+
+// traceResult is the result part of a flat trace.
+type traceResult struct {
+	Address *common.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes  `json:"code,omitempty"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+}
+
+// flatTrace is a single call in the Parity trace format. The position of the
+// call in the call tree is given by its trace address.
+type flatTrace struct {
+	Action      *traceAction `json:"action"`
+	BlockHash   *common.Hash `json:"blockHash,omitempty"`
+	BlockNumber *uint64      `json:"blockNumber,omitempty"`
+	Error       string       `json:"error,omitempty"`
+
+	// Result is a nil interface for failed calls, omitting the field, and a nil
+	// *traceResult for self destructs, which is encoded as null.
+	Result interface{} `json:"result,omitempty"`
+
+	Subtraces           int          `json:"subtraces"`
+	TraceAddress        []int        `json:"traceAddress"`
+	TransactionHash     *common.Hash `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64      `json:"transactionPosition,omitempty"`
+	Type                string       `json:"type"`
+
+	from, to common.Address // Addresses matched by trace_filter
+}
+
+// traceResults is the result of replaying a transaction.
+type traceResults struct {
+	Output          hexutil.Bytes `json:"output"`
+	StateDiff       stateDiff     `json:"stateDiff"`
+	Trace           []*flatTrace  `json:"trace"`
+	VMTrace         *vmTrace      `json:"vmTrace"`
+	TransactionHash *common.Hash  `json:"transactionHash,omitempty"`
+}
+
+// txTrace is the outcome of tracing a single transaction.
+type txTrace struct {
+	tx        *types.Transaction
+	frame     *tracers.CallFrame
+	output    []byte
+	vmTrace   *vmTrace
+	stateDiff stateDiff
+}
+
+// results converts the transaction trace into the format of the replaying methods.
+func (t *txTrace) results(want traceTypes) *traceResults {
+	res := &traceResults{
+		Output:    t.output,
+		Trace:     []*flatTrace{},
+		StateDiff: t.stateDiff,
+		VMTrace:   t.vmTrace,
+	}
+	if want.trace {
+		res.Trace = flattenTrace(t.frame, nil, res.Trace)
+	}
+	if t.tx != nil {
+		hash := t.tx.Hash()
+		res.TransactionHash = &hash
+	}
+	return res
+}
+
+// localize converts the transaction trace into flat traces of the given block.
+func (t *txTrace) localize(block *types.Block, index int) []*flatTrace {
+	var (
+		traces = flattenTrace(t.frame, nil, nil)
+		hash   = block.Hash()
+		number = block.NumberU64()
+		txhash = t.tx.Hash()
+		txpos  = uint64(index)
+	)
+	for _, trace := range traces {
+		trace.BlockHash, trace.BlockNumber = &hash, &number
+		trace.TransactionHash, trace.TransactionPosition = &txhash, &txpos
+	}
+	return traces
+}
+
+// flattenTrace appends a call and all its subcalls to the list of flat traces,
+// in the order of execution.
+func flattenTrace(frame *tracers.CallFrame, address []int, traces []*flatTrace) []*flatTrace {
+	if frame == nil {
+		return traces
+	}
+	trace := &flatTrace{
+		Action:       new(traceAction),
+		Subtraces:    len(frame.Calls),
+		TraceAddress: append([]int{}, address...),
+		from:         frame.From,
+		to:           frame.To,
+	}
+	var (
+		from, to = frame.From, frame.To
+		gas      = hexutil.Uint64(frame.Gas)
+		input    = hexutil.Bytes(frame.Input)
+		output   = hexutil.Bytes(frame.Output)
+		value    = (*hexutil.Big)(frame.Value)
+	)
+	if value == nil {
+		value = new(hexutil.Big)
+	}
+	result := &traceResult{GasUsed: hexutil.Uint64(frame.GasUsed)}
+
+	switch frame.Type {
+	case vm.CREATE, vm.CREATE2:
+		trace.Type = "create"
+		trace.Action.From, trace.Action.Gas, trace.Action.Init, trace.Action.Value = &from, &gas, &input, value
+		result.Address, result.Code = &to, &output
+		trace.Result = result
+
+	case vm.SELFDESTRUCT:
+		trace.Type = "suicide"
+		trace.Action.Address, trace.Action.RefundAddress, trace.Action.Balance = &from, &to, value
+		trace.Result = (*traceResult)(nil)
+
+	default:
+		trace.Type = "call"
+		trace.Action.CallType = strings.ToLower(frame.Type.String())
+		trace.Action.From, trace.Action.To, trace.Action.Gas, trace.Action.Input, trace.Action.Value = &from, &to, &gas, &input, value
+		result.Output = &output
+		trace.Result = result
+	}
+	if frame.Error != nil {
+		trace.Error = parityError(frame.Error)
+		trace.Result = nil
+	}
+	traces = append(traces, trace)
+	for i, call := range frame.Calls {
+		traces = flattenTrace(call, append(address, i), traces)
+	}
+	return traces
+}
+
+// parityError converts an EVM error into the message used by Parity.
+func parityError(err error) string {
+	msg := err.Error()
+	switch {
+	case err == vm.ErrOutOfGas, err == vm.ErrCodeStoreOutOfGas:
+		return "Out of gas"
+	case msg == "evm: execution reverted":
+		return "Reverted"
+	case msg == "evm: invalid jump destination":
+		return "Bad jump destination"
+	case msg == "evm: write protection":
+		return "Mutable Call In Static Context"
+	case strings.HasPrefix(msg, "invalid opcode"):
+		return "Bad instruction"
+	case strings.HasPrefix(msg, "stack underflow"):
+		return "Stack underflow"
+	case strings.HasPrefix(msg, "stack limit reached"):
+		return "Out of stack"
+	}
+	return msg
+}
+
+// Block returns the traces of all the transactions within a block.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*flatTrace, error) {
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := api.traceBlock(ctx, block, traceTypes{trace: true})
+	if err != nil {
+		return nil, err
+	}
+	traces := []*flatTrace{}
+	for i, tx := range txs {
+		traces = append(traces, tx.localize(block, i)...)
+	}
+	return traces, nil
+}
+
+// Transaction returns the traces of a single transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*flatTrace, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	msg, vmctx, statedb, err := api.debug.computeTxEnv(blockHash, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.traceTx(ctx, msg, vmctx, statedb, block.Number(), traceTypes{trace: true})
+	if err != nil {
+		return nil, err
+	}
+	res.tx = tx
+	return res.localize(block, int(index)), nil
+}
+
+// ReplayBlockTransactions replays all the transactions within a block and
+// returns the requested traces of each.
+func (api *PrivateTraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, names []string) ([]*traceResults, error) {
+	want, err := parseTraceTypes(names)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := api.traceBlock(ctx, block, want)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*traceResults, len(txs))
+	for i, tx := range txs {
+		results[i] = tx.results(want)
+	}
+	return results, nil
+}
+
+// Call executes a message call on top of the state of the given block, which
+// defaults to the latest one, and returns the requested traces.
+func (api *PrivateTraceAPI) Call(ctx context.Context, args ethapi.CallArgs, names []string, number *rpc.BlockNumber) (*traceResults, error) {
+	want, err := parseTraceTypes(names)
+	if err != nil {
+		return nil, err
+	}
+	blockNr := rpc.LatestBlockNumber
+	if number != nil {
+		blockNr = *number
+	}
+	statedb, header, err := api.eth.APIBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	// Assemble the message with Parity's defaults, capping the gas allowance
+	var (
+		from     common.Address
+		gas      = header.GasLimit
+		gasPrice = new(big.Int)
+		value    = new(big.Int)
+		data     []byte
+	)
+	if args.From != nil {
+		from = *args.From
+	}
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	if gasCap := api.eth.APIBackend.RPCGasCap(); gasCap != nil && gasCap.Uint64() < gas {
+		gas = gasCap.Uint64()
+	}
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	if args.Data != nil {
+		data = *args.Data
+	}
+	msg := types.NewMessage(from, args.To, 0, value, gas, gasPrice, data, false)
+	vmctx := core.NewEVMContext(msg, header, api.eth.blockchain, nil)
+
+	res, err := api.traceTx(ctx, msg, vmctx, statedb, header.Number, want)
+	if err != nil {
+		return nil, err
+	}
+	return res.results(want), nil
+}
+
+// TraceFilterArgs are the criteria of trace_filter. A trace matches if its
+// sender is one of FromAddress and its recipient is one of ToAddress, empty
+// lists matching any address.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// Filter returns the traces of the given block range which match the filter. As
+// the blocks are replayed to trace them, the range is capped at maxTraceFilterBlocks.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*flatTrace, error) {
+	var (
+		start = rpc.LatestBlockNumber
+		end   = rpc.LatestBlockNumber
+	)
+	if args.FromBlock != nil {
+		start = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		end = *args.ToBlock
+	}
+	from, err := api.blockByNumber(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(end)
+	if err != nil {
+		return nil, err
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, fmt.Errorf("invalid block range #%d-#%d", from.NumberU64(), to.NumberU64())
+	}
+	if blocks := to.NumberU64() - from.NumberU64() + 1; blocks > maxTraceFilterBlocks {
+		return nil, fmt.Errorf("block range #%d-#%d too large: %d blocks, max %d", from.NumberU64(), to.NumberU64(), blocks, maxTraceFilterBlocks)
+	}
+	var (
+		fromAddrs = addressSet(args.FromAddress)
+		toAddrs   = addressSet(args.ToAddress)
+		skip      uint64
+		traces    = []*flatTrace{}
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	for number := from.NumberU64(); number <= to.NumberU64(); number++ {
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		txs, err := api.traceBlock(ctx, block, traceTypes{trace: true})
+		if err != nil {
+			return nil, err
+		}
+		for i, tx := range txs {
+			for _, trace := range tx.localize(block, i) {
+				if (fromAddrs != nil && !fromAddrs[trace.from]) || (toAddrs != nil && !toAddrs[trace.to]) {
+					continue
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				traces = append(traces, trace)
+				if args.Count != nil && uint64(len(traces)) >= *args.Count {
+					return traces, nil
+				}
+			}
+		}
+	}
+	return traces, nil
+}
+
+// addressSet converts a list of addresses into a set, nil if the list is empty.
+func addressSet(addrs []common.Address) map[common.Address]bool {
+	if len(addrs) == 0 {
+		return nil
+	}
+	set := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = true
+	}
+	return set
+}
+
+// blockByNumber retrieves a block from the chain, or the pending one.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
+	var block *types.Block
+
+	switch number {
+	case rpc.PendingBlockNumber:
+		block = api.eth.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// traceBlock replays all the transactions within a block on top of the state of
+// its parent, tracing each of them.
+func (api *PrivateTraceAPI) traceBlock(ctx context.Context, block *types.Block, want traceTypes) ([]*txTrace, error) {
+	if block.NumberU64() == 0 {
+		return nil, nil
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := api.debug.computeStateDB(parent, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer  = types.MakeSigner(api.eth.blockchain.Config(), block.Number())
+		results = make([]*txTrace, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+
+		res, err := api.traceTx(ctx, msg, vmctx, statedb, block.Number(), want)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x: %v", tx.Hash(), err)
+		}
+		res.tx = tx
+		results[i] = res
+	}
+	return results, nil
+}
+
+// traceTx executes the given message on top of the provided state, producing the
+// requested traces. The state is finalized afterwards, so that any following
+// transactions can be traced on top of it.
+func (api *PrivateTraceAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, number *big.Int, want traceTypes) (*txTrace, error) {
+	var (
+		frames = tracers.NewCallFrameTracer()
+		tracer = multiTracer{frames}
+		vmt    *vmTracer
+		diff   *stateDiffTracer
+		pre    *state.StateDB
+	)
+	if want.vmTrace {
+		vmt = newVMTracer()
+		tracer = append(tracer, vmt)
+	}
+	if want.stateDiff {
+		pre = statedb.Copy()
+		diff = newStateDiffTracer(vmctx.Coinbase)
+		tracer = append(tracer, diff)
+	}
+	config := api.eth.blockchain.Config()
+	vmenv := vm.NewEVM(vmctx, statedb, config, vm.Config{Debug: true, Tracer: tracer})
+
+	// Abort the execution if the request is cancelled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		vmenv.Cancel()
+	}()
+	ret, _, _, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	if vmenv.Cancelled() {
+		return nil, errors.New("execution aborted")
+	}
+	// Finalize the state so any modifications are visible to the next transaction.
+	// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+	statedb.Finalise(config.IsEIP158(number))
+
+	res := &txTrace{frame: frames.Frame(), output: ret}
+	if vmt != nil {
+		res.vmTrace = vmt.trace()
+	}
+	if diff != nil {
+		res.stateDiff = diff.diff(pre, statedb)
+	}
+	return res, nil
+}
+
+// multiTracer forwards the tracing events to multiple tracers.
+type multiTracer []vm.Tracer
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (mt multiTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	for _, t := range mt {
+		if err := t.CaptureStart(from, to, create, input, gas, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (mt multiTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	for _, t := range mt {
+		if err := t.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (mt multiTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	for _, t := range mt {
+		if err := t.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (mt multiTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	for _, tracer := range mt {
+		if err := tracer.CaptureEnd(output, gasUsed, t, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureEnter implements the FrameTracer interface, forwarding the event to the
+// tracers interested in call frames.
+func (mt multiTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	for _, t := range mt {
+		if ft, ok := t.(vm.FrameTracer); ok {
+			if err := ft.CaptureEnter(typ, from, to, input, gas, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CaptureExit implements the FrameTracer interface, forwarding the event to the
+// tracers interested in call frames.
+func (mt multiTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	for _, t := range mt {
+		if ft, ok := t.(vm.FrameTracer); ok {
+			if err := ft.CaptureExit(output, gasUsed, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
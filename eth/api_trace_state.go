@@ -0,0 +1,368 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// vmTrace is the Parity trace of all the operations executed by a call.
+type vmTrace struct {
+	Code hexutil.Bytes  `json:"code"`
+	Ops  []*vmOperation `json:"ops"`
+}
+
+// vmOperation is a single operation of a vmTrace. Sub is the trace of the call
+// made by the operation, if any.
+type vmOperation struct {
+	Cost uint64      `json:"cost"`
+	Ex   *vmExecuted `json:"ex"`
+	PC   uint64      `json:"pc"`
+	Sub  *vmTrace    `json:"sub"`
+}
+
+// vmExecuted contains the effects of an operation, nil if it failed. Used is
+// the gas remaining after the operation.
+type vmExecuted struct {
+	Mem   *vmMemoryDiff  `json:"mem"`
+	Push  []*hexutil.Big `json:"push"`
+	Store *vmStorageDiff `json:"store"`
+	Used  uint64         `json:"used"`
+}
+
+// vmMemoryDiff is the memory area written by an operation.
+type vmMemoryDiff struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+// vmStorageDiff is the storage slot written by an operation.
+type vmStorageDiff struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// vmFrame is the vmTrace of a call being executed, along with the operation
+// awaiting its effects.
+type vmFrame struct {
+	trace *vmTrace
+
+	last    *vmOperation   // Last operation executed by the call
+	lastGas uint64         // Gas remaining after paying for the last operation
+	pushes  int            // Number of stack items pushed by the last operation
+	memOff  *big.Int       // Offset of the memory area written by the last operation
+	memSize *big.Int       // Size of the memory area written by the last operation
+	store   *vmStorageDiff // Storage slot written by the last operation
+}
+
+// vmTracer assembles the Parity vmTrace of a transaction. The effects of an
+// operation are collected when the next operation of the same call executes.
+type vmTracer struct {
+	root  *vmTrace
+	stack []*vmFrame
+}
+
+func newVMTracer() *vmTracer {
+	return new(vmTracer)
+}
+
+// trace returns the assembled vmTrace.
+func (t *vmTracer) trace() *vmTrace {
+	if t.root == nil {
+		return &vmTrace{Code: hexutil.Bytes{}, Ops: []*vmOperation{}}
+	}
+	return t.root
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	frame := t.advance(depth, gas, memory, stack, contract)
+	if err != nil {
+		return nil
+	}
+	frame.last = &vmOperation{PC: pc, Cost: cost}
+	frame.trace.Ops = append(frame.trace.Ops, frame.last)
+	frame.lastGas = gas - cost
+	frame.pushes = vmPushes(op)
+	frame.memOff, frame.memSize, frame.store = nil, nil, nil
+
+	switch op {
+	case vm.MSTORE:
+		frame.memOff, frame.memSize = stackBack(stack, 0), big.NewInt(32)
+	case vm.MSTORE8:
+		frame.memOff, frame.memSize = stackBack(stack, 0), big.NewInt(1)
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		frame.memOff, frame.memSize = stackBack(stack, 0), stackBack(stack, 2)
+	case vm.EXTCODECOPY:
+		frame.memOff, frame.memSize = stackBack(stack, 1), stackBack(stack, 3)
+	case vm.CALL, vm.CALLCODE:
+		frame.memOff, frame.memSize = stackBack(stack, 5), stackBack(stack, 6)
+	case vm.DELEGATECALL, vm.STATICCALL:
+		frame.memOff, frame.memSize = stackBack(stack, 4), stackBack(stack, 5)
+	case vm.SSTORE:
+		frame.store = &vmStorageDiff{Key: (*hexutil.Big)(stackBack(stack, 0)), Val: (*hexutil.Big)(stackBack(stack, 1))}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *vmTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// The failed operation has no effects, leave them empty
+	if depth <= len(t.stack) {
+		t.stack[depth-1].last = nil
+	}
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	for len(t.stack) > 0 {
+		t.pop()
+	}
+	return nil
+}
+
+// advance updates the call stack to the given depth of execution and completes
+// the last operation of the call executing there, returning its frame.
+func (t *vmTracer) advance(depth int, gas uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract) *vmFrame {
+	for len(t.stack) > depth {
+		t.pop()
+	}
+	if len(t.stack) < depth {
+		// A new call started, link it to the operation of its caller
+		sub := &vmTrace{Code: common.CopyBytes(contract.Code), Ops: []*vmOperation{}}
+		if len(t.stack) == 0 {
+			t.root = sub
+		} else if parent := t.stack[len(t.stack)-1]; parent.last != nil {
+			parent.last.Sub = sub
+		}
+		t.stack = append(t.stack, &vmFrame{trace: sub})
+		return t.stack[len(t.stack)-1]
+	}
+	frame := t.stack[len(t.stack)-1]
+	if op := frame.last; op != nil {
+		op.Ex = &vmExecuted{Push: []*hexutil.Big{}, Store: frame.store, Used: gas}
+		if data := stack.Data(); len(data) >= frame.pushes {
+			for _, item := range data[len(data)-frame.pushes:] {
+				op.Ex.Push = append(op.Ex.Push, (*hexutil.Big)(new(big.Int).Set(item)))
+			}
+		}
+		if frame.memSize != nil && frame.memSize.Sign() > 0 {
+			end := new(big.Int).Add(frame.memOff, frame.memSize)
+			if end.IsInt64() && end.Int64() <= int64(memory.Len()) {
+				op.Ex.Mem = &vmMemoryDiff{
+					Data: memory.Get(frame.memOff.Int64(), frame.memSize.Int64()),
+					Off:  frame.memOff.Uint64(),
+				}
+			}
+		}
+		frame.last = nil
+	}
+	return frame
+}
+
+// pop finalizes the call on top of the stack. Its last operation returned from
+// the call, so it has no visible effects apart from its gas usage.
+func (t *vmTracer) pop() {
+	frame := t.stack[len(t.stack)-1]
+	if frame.last != nil {
+		frame.last.Ex = &vmExecuted{Push: []*hexutil.Big{}, Used: frame.lastGas}
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// vmPushes returns the number of stack items an operation pushes, as reported
+// by Parity. Duplications and swaps report all the items they touched.
+func vmPushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST,
+		vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY,
+		vm.RETURN, vm.REVERT, vm.SELFDESTRUCT:
+		return 0
+	}
+	return 1
+}
+
+// stackBack returns a copy of the nth-from-the-top element of the stack.
+func stackBack(stack *vm.Stack, n int) *big.Int {
+	data := stack.Data()
+	if len(data) <= n {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(data[len(data)-n-1])
+}
+
+// stateDiff is the Parity state diff of a transaction, keyed by account.
+type stateDiff map[common.Address]*accountDiff
+
+// accountDiff contains the changes of a single account. Each field is either
+// "=" if unchanged, or an object with the "+" (created), "-" (deleted) or "*"
+// (modified) key.
+type accountDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Code    interface{}                 `json:"code"`
+	Nonce   interface{}                 `json:"nonce"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// stateDiffTracer collects the accounts and storage slots possibly modified by
+// a transaction.
+type stateDiffTracer struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+func newStateDiffTracer(coinbase common.Address) *stateDiffTracer {
+	t := &stateDiffTracer{accounts: make(map[common.Address]map[common.Hash]struct{})}
+	t.touch(coinbase)
+	return t
+}
+
+// touch marks an account as possibly modified.
+func (t *stateDiffTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.accounts[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.accounts[addr] = slots
+	}
+	return slots
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *stateDiffTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.touch(from)
+	t.touch(to)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *stateDiffTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.touch(common.BigToAddress(stackBack(stack, 1)))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.touch(crypto.CreateAddress(from, env.StateDB.GetNonce(from)))
+
+	case vm.CREATE2:
+		off, size := stackBack(stack, 1), stackBack(stack, 2)
+		if end := new(big.Int).Add(off, size); end.IsInt64() && end.Int64() <= int64(memory.Len()) {
+			code := memory.Get(off.Int64(), size.Int64())
+			salt := common.BigToHash(stackBack(stack, 3))
+			t.touch(crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(code)))
+		}
+
+	case vm.SELFDESTRUCT:
+		t.touch(contract.Address())
+		t.touch(common.BigToAddress(stackBack(stack, 0)))
+
+	case vm.SSTORE:
+		t.touch(contract.Address())[common.BigToHash(stackBack(stack, 0))] = struct{}{}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *stateDiffTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *stateDiffTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// diff compares the touched accounts between the states before and after the
+// transaction. Unchanged accounts are omitted.
+func (t *stateDiffTracer) diff(pre, post *state.StateDB) stateDiff {
+	diff := make(stateDiff)
+	for addr, slots := range t.accounts {
+		var (
+			born = !pre.Exist(addr) && post.Exist(addr)
+			died = pre.Exist(addr) && !post.Exist(addr)
+		)
+		if !pre.Exist(addr) && !post.Exist(addr) {
+			continue
+		}
+		account := &accountDiff{
+			Balance: diffValue((*hexutil.Big)(pre.GetBalance(addr)), (*hexutil.Big)(post.GetBalance(addr)), born, died,
+				pre.GetBalance(addr).Cmp(post.GetBalance(addr)) == 0),
+			Code: diffValue(hexutil.Bytes(pre.GetCode(addr)), hexutil.Bytes(post.GetCode(addr)), born, died,
+				bytes.Equal(pre.GetCode(addr), post.GetCode(addr))),
+			Nonce: diffValue(hexutil.Uint64(pre.GetNonce(addr)), hexutil.Uint64(post.GetNonce(addr)), born, died,
+				pre.GetNonce(addr) == post.GetNonce(addr)),
+			Storage: make(map[common.Hash]interface{}),
+		}
+		for key := range slots {
+			from, to := pre.GetState(addr, key), post.GetState(addr, key)
+			switch {
+			case born && to == (common.Hash{}), died && from == (common.Hash{}):
+				continue
+			case !born && !died && from == to:
+				continue
+			}
+			account.Storage[key] = diffValue(from, to, born, died, false)
+		}
+		if !born && !died && len(account.Storage) == 0 &&
+			account.Balance == "=" && account.Code == "=" && account.Nonce == "=" {
+			continue
+		}
+		diff[addr] = account
+	}
+	return diff
+}
+
+// diffValue returns the Parity diff of a single value.
+func diffValue(from, to interface{}, born, died, equal bool) interface{} {
+	switch {
+	case born:
+		return map[string]interface{}{"+": to}
+	case died:
+		return map[string]interface{}{"-": from}
+	case equal:
+		return "="
+	}
+	return map[string]interface{}{"*": map[string]interface{}{"from": from, "to": to}}
+}
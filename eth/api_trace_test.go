@@ -0,0 +1,255 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that call trees are flattened into the Parity trace format.
+func TestFlattenTrace(t *testing.T) {
+	var (
+		alice   = common.HexToAddress("0x01")
+		bob     = common.HexToAddress("0x02")
+		carol   = common.HexToAddress("0x03")
+		created = common.HexToAddress("0x04")
+	)
+	root := &tracers.CallFrame{
+		Type: vm.CALL, From: alice, To: bob, Input: []byte{0x01}, Output: []byte{0x02},
+		Gas: 100000, GasUsed: 50000, Value: big.NewInt(1),
+		Calls: []*tracers.CallFrame{
+			{
+				Type: vm.STATICCALL, From: bob, To: carol, Gas: 1000, GasUsed: 1000,
+				Error: vm.ErrOutOfGas,
+			},
+			{
+				Type: vm.CREATE, From: bob, To: created, Input: []byte{0x03}, Output: []byte{0x04},
+				Gas: 20000, GasUsed: 10000, Value: big.NewInt(0),
+				Calls: []*tracers.CallFrame{
+					{Type: vm.SELFDESTRUCT, From: created, To: alice, Value: big.NewInt(2)},
+				},
+			},
+		},
+	}
+	traces := flattenTrace(root, nil, nil)
+
+	var have []map[string]interface{}
+	blob, err := json.Marshal(traces)
+	if err != nil {
+		t.Fatalf("failed to encode traces: %v", err)
+	}
+	if err := json.Unmarshal(blob, &have); err != nil {
+		t.Fatalf("failed to decode traces: %v", err)
+	}
+	want := []map[string]interface{}{
+		{
+			"action": map[string]interface{}{
+				"callType": "call",
+				"from":     "0x0000000000000000000000000000000000000001",
+				"to":       "0x0000000000000000000000000000000000000002",
+				"gas":      "0x186a0",
+				"input":    "0x01",
+				"value":    "0x1",
+			},
+			"result": map[string]interface{}{
+				"gasUsed": "0xc350",
+				"output":  "0x02",
+			},
+			"subtraces":    2.0,
+			"traceAddress": []interface{}{},
+			"type":         "call",
+		},
+		{
+			"action": map[string]interface{}{
+				"callType": "staticcall",
+				"from":     "0x0000000000000000000000000000000000000002",
+				"to":       "0x0000000000000000000000000000000000000003",
+				"gas":      "0x3e8",
+				"input":    "0x",
+				"value":    "0x0",
+			},
+			"error":        "Out of gas",
+			"subtraces":    0.0,
+			"traceAddress": []interface{}{0.0},
+			"type":         "call",
+		},
+		{
+			"action": map[string]interface{}{
+				"from":  "0x0000000000000000000000000000000000000002",
+				"gas":   "0x4e20",
+				"init":  "0x03",
+				"value": "0x0",
+			},
+			"result": map[string]interface{}{
+				"address": "0x0000000000000000000000000000000000000004",
+				"code":    "0x04",
+				"gasUsed": "0x2710",
+			},
+			"subtraces":    1.0,
+			"traceAddress": []interface{}{1.0},
+			"type":         "create",
+		},
+		{
+			"action": map[string]interface{}{
+				"address":       "0x0000000000000000000000000000000000000004",
+				"refundAddress": "0x0000000000000000000000000000000000000001",
+				"balance":       "0x2",
+			},
+			"result":       nil,
+			"subtraces":    0.0,
+			"traceAddress": []interface{}{1.0, 0.0},
+			"type":         "suicide",
+		},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("trace mismatch:\nhave %s\nwant %v", blob, want)
+	}
+}
+
+// Tests that unsent calls are traced on top of the requested block, with the
+// state and block context overrides applied.
+func TestTraceCall(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000000000000)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 4, func(i int, block *core.BlockGen) {})
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	api := NewPrivateDebugAPI(&Ethereum{chainDb: db, blockchain: blockchain, config: &Config{}})
+
+	// Return the sum of the first storage slot and the block number
+	var (
+		contract = common.HexToAddress("0xc0de")
+		code     = hexutil.Bytes{
+			byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.NUMBER), byte(vm.ADD),
+			byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+		}
+		slot = map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(5))}
+	)
+	tests := []struct {
+		block  rpc.BlockNumber
+		config *TraceCallConfig
+		want   uint64
+		fail   bool
+	}{
+		// No overrides, the call goes to an empty account
+		{rpc.LatestBlockNumber, nil, 0, false},
+		// Code overridden, executing on top of various blocks
+		{rpc.LatestBlockNumber, &TraceCallConfig{StateOverrides: &ethapi.StateOverride{contract: {Code: &code}}}, 4, false},
+		{2, &TraceCallConfig{StateOverrides: &ethapi.StateOverride{contract: {Code: &code}}}, 2, false},
+		// Storage and block number overridden too
+		{rpc.LatestBlockNumber, &TraceCallConfig{
+			StateOverrides: &ethapi.StateOverride{contract: {Code: &code, StateDiff: &slot}},
+			BlockOverrides: &ethapi.BlockOverrides{Number: (*hexutil.Big)(big.NewInt(100))},
+		}, 105, false},
+		// Invalid overrides and missing blocks
+		{rpc.LatestBlockNumber, &TraceCallConfig{StateOverrides: &ethapi.StateOverride{contract: {State: &slot, StateDiff: &slot}}}, 0, true},
+		{10, nil, 0, true},
+	}
+	for i, tt := range tests {
+		res, err := api.TraceCall(context.Background(), ethapi.CallArgs{From: &testBank, To: &contract}, tt.block, tt.config)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: expected failure", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		result := res.(*ethapi.ExecutionResult)
+		if tt.want == 0 {
+			if result.ReturnValue != "" || len(result.StructLogs) != 0 {
+				t.Errorf("test %d: unexpected execution: %+v", i, result)
+			}
+			continue
+		}
+		if have := new(big.Int).SetBytes(common.FromHex(result.ReturnValue)); have.Uint64() != tt.want {
+			t.Errorf("test %d: return value mismatch: have %v, want %d", i, have, tt.want)
+		}
+		if len(result.StructLogs) != 9 {
+			t.Errorf("test %d: step count mismatch: have %d, want %d", i, len(result.StructLogs), 9)
+		}
+	}
+	// Check that configured tracers are used
+	config := &TraceCallConfig{StateOverrides: &ethapi.StateOverride{contract: {Code: &code}}}
+	config.Tracer = new(string)
+	*config.Tracer = "callTracer"
+
+	res, err := api.TraceCall(context.Background(), ethapi.CallArgs{From: &testBank, To: &contract}, rpc.LatestBlockNumber, config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	var frame struct {
+		To     common.Address `json:"to"`
+		Output hexutil.Bytes  `json:"output"`
+	}
+	if err := json.Unmarshal(res.(json.RawMessage), &frame); err != nil {
+		t.Fatalf("failed to decode call tracer result: %v", err)
+	}
+	if frame.To != contract || new(big.Int).SetBytes(frame.Output).Uint64() != 4 {
+		t.Fatalf("call tracer result mismatch: have %x %x", frame.To, frame.Output)
+	}
+}
+
+// Tests that trace_filter refuses to replay block ranges above the cap.
+func TestTraceFilterRangeCap(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, maxTraceFilterBlocks+1, func(i int, block *core.BlockGen) {})
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	api := NewPrivateTraceAPI(&Ethereum{chainDb: db, blockchain: blockchain, config: &Config{}})
+
+	first, last := rpc.BlockNumber(1), rpc.BlockNumber(maxTraceFilterBlocks)
+	if traces, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &first, ToBlock: &last}); err != nil || len(traces) != 0 {
+		t.Fatalf("capped range: have %d traces, err %v; want 0, nil", len(traces), err)
+	}
+	genesisNum := rpc.BlockNumber(0)
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &genesisNum}); err == nil {
+		t.Fatalf("oversized range accepted")
+	}
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -69,6 +70,14 @@ type StdTraceConfig struct {
 	TxHash common.Hash
 }
 
+// TraceCallConfig holds extra parameters to the call trace functions, overriding
+// the state and the block context the call is executed in.
+type TraceCallConfig struct {
+	TraceConfig
+	StateOverrides *ethapi.StateOverride
+	BlockOverrides *ethapi.BlockOverrides
+}
+
 // txTraceResult is the result of a single transaction trace.
 type txTraceResult struct {
 	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
@@ -639,10 +648,20 @@ func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*
 	if err == nil {
 		return statedb, nil
 	}
-	// Otherwise try to reexec blocks until we find a state or reach our limit
+	return api.regenerateStateDB(block, reexec)
+}
+
+// regenerateStateDB reexecutes blocks until the state of the given one is
+// recreated, starting from the closest ancestor with available state. The
+// returned state is backed by a private database, not shared with the chain.
+func (api *PrivateDebugAPI) regenerateStateDB(block *types.Block, reexec uint64) (*state.StateDB, error) {
 	origin := block.NumberU64()
 	database := state.NewDatabaseWithCache(api.eth.ChainDb(), 16)
 
+	statedb, err := state.New(block.Root(), database)
+	if err == nil {
+		return statedb, nil
+	}
 	for i := uint64(0); i < reexec; i++ {
 		block = api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
 		if block == nil {
@@ -719,11 +738,59 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall returns the trace of executing the given call on top of the state of
+// the given block, without it being broadcast or included in the chain. Same as
+// with eth_call, the state and the block context can be overridden.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNr rpc.BlockNumber, config *TraceCallConfig) (interface{}, error) {
+	if config == nil {
+		config = new(TraceCallConfig)
+	}
+	// Retrieve the block and state to execute the call on top of
+	var (
+		block   *types.Block
+		statedb *state.StateDB
+		err     error
+	)
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		if block, statedb = api.eth.miner.Pending(); block == nil || statedb == nil {
+			return nil, errors.New("pending state not available")
+		}
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	if statedb == nil {
+		reexec := defaultTraceReexec
+		if config.Reexec != nil {
+			reexec = *config.Reexec
+		}
+		if statedb, err = api.computeStateDB(block, reexec); err != nil {
+			return nil, err
+		}
+	}
+	// Fund the sender the same way as eth_call does, then apply the overrides
+	msg := args.ToMessage(api.eth.config.RPCGasCap)
+	statedb.SetBalance(msg.From(), math.MaxBig256)
+
+	if err := config.StateOverrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+	config.BlockOverrides.Apply(&vmctx)
+
+	return api.traceTx(ctx, msg, vmctx, statedb, &config.TraceConfig)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the native or JavaScript tracer
 	var (
 		tracer vm.Tracer
 		err    error
@@ -737,15 +804,18 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the native or JavaScript tracer to execute with
+		t, err := tracers.NewTracer(*config.Tracer)
+		if err != nil {
 			return nil, err
 		}
+		tracer = t
+
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			t.Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -772,7 +842,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.ResultTracer:
 		return tracer.GetResult()
 
 	default:
@@ -821,3 +891,123 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 	}
 	return nil, vm.Context{}, nil, fmt.Errorf("transaction index %d out of range for block %#x", txIndex, blockHash)
 }
+
+// internalTxResult is an internal transaction returned by the trace index.
+type internalTxResult struct {
+	BlockNumber         hexutil.Uint64 `json:"blockNumber"`
+	BlockHash           common.Hash    `json:"blockHash"`
+	TransactionHash     common.Hash    `json:"transactionHash"`
+	TransactionPosition hexutil.Uint64 `json:"transactionPosition"`
+	TraceAddress        []uint64       `json:"traceAddress"`
+	Type                string         `json:"type"`
+	From                common.Address `json:"from"`
+	To                  common.Address `json:"to"`
+	Value               *hexutil.Big   `json:"value"`
+	Gas                 hexutil.Uint64 `json:"gas"`
+	GasUsed             hexutil.Uint64 `json:"gasUsed"`
+	Error               string         `json:"error,omitempty"`
+}
+
+// GetInternalTransactions returns the calls, contract creations and self destructs
+// made by contracts which the given address participated in, within a block range.
+// Blocks covered by the trace index are looked up, the rest is traced on demand.
+func (api *PrivateDebugAPI) GetInternalTransactions(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber) ([]*internalTxResult, error) {
+	if api.eth.traceIndexer == nil {
+		return nil, errors.New("trace index not enabled")
+	}
+	// Resolve the block range, pending blocks are not indexed
+	head := api.eth.blockchain.CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 || uint64(number) > head {
+			return head
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to, from)
+	}
+	sections, _, _ := api.eth.traceIndexer.Sections()
+	indexed := sections * traceIndexSectionSize
+
+	// Look up the indexed part of the range
+	results := []*internalTxResult{}
+	if from < indexed {
+		last := to
+		if last >= indexed {
+			last = indexed - 1
+		}
+		for _, entry := range rawdb.ReadInternalTxs(api.eth.ChainDb(), address, from, last) {
+			// Skip any entries of blocks which have been reorged out
+			if rawdb.ReadCanonicalHash(api.eth.ChainDb(), entry.Number) != entry.Hash {
+				continue
+			}
+			var txs []*internalTx
+			if err := rlp.DecodeBytes(entry.Blob, &txs); err != nil {
+				return nil, fmt.Errorf("invalid trace index entry of block #%d: %v", entry.Number, err)
+			}
+			results = append(results, newInternalTxResults(entry.Number, entry.Hash, txs)...)
+		}
+	}
+	// Trace the blocks past the index, which is only feasible while it's in sync.
+	// The genesis block has no transactions to trace.
+	start := from
+	if start < indexed {
+		start = indexed
+	}
+	if start == 0 {
+		start = 1
+	}
+	if start > to {
+		return results, nil
+	}
+	if to-start >= 2*traceIndexSectionSize+traceIndexConfirms {
+		return nil, fmt.Errorf("trace index not synced yet, indexed %d blocks", indexed)
+	}
+	parent := api.eth.blockchain.GetBlockByNumber(start - 1)
+	if parent == nil {
+		return nil, fmt.Errorf("block #%d not found", start-1)
+	}
+	statedb, err := api.computeStateDB(parent, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	for number := start; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		txs, err := traceInternalTxs(api.eth.blockchain, block, statedb)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, newInternalTxResults(number, block.Hash(), txs[address])...)
+	}
+	return results, nil
+}
+
+// newInternalTxResults converts the indexed internal transactions of a block into
+// their RPC representation.
+func newInternalTxResults(number uint64, hash common.Hash, txs []*internalTx) []*internalTxResult {
+	results := make([]*internalTxResult, 0, len(txs))
+	for _, tx := range txs {
+		results = append(results, &internalTxResult{
+			BlockNumber:         hexutil.Uint64(number),
+			BlockHash:           hash,
+			TransactionHash:     tx.TxHash,
+			TransactionPosition: hexutil.Uint64(tx.TxIndex),
+			TraceAddress:        tx.TraceAddress,
+			Type:                vm.OpCode(tx.Type).String(),
+			From:                tx.From,
+			To:                  tx.To,
+			Value:               (*hexutil.Big)(tx.Value),
+			Gas:                 hexutil.Uint64(tx.Gas),
+			GasUsed:             hexutil.Uint64(tx.GasUsed),
+			Error:               tx.Error,
+		})
+	}
+	return results
+}
@@ -139,6 +139,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables indexing the internal transactions of the chain by address
+	TraceIndex bool
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
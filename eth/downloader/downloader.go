@@ -69,6 +69,9 @@ var (
 	fsHeaderForceVerify    = 24              // Number of headers to verify before and after the pivot to accept it
 	fsHeaderContCheck      = 3 * time.Second // Time interval to check for header continuations during state download
 	fsMinFullBlocks        = 64              // Number of blocks to retrieve fully even in fast sync
+
+	skeletonWitnesses = 3 // Number of additional peers to cross-check skeleton batches against
+	skeletonQuorum    = 2 // Number of agreeing witnesses needed to overrule the skeleton of the origin
 )
 
 var (
@@ -334,7 +337,7 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 			// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
 			log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", id)
 		} else {
-			d.dropPeer(id)
+			d.dropPeer(id, err)
 		}
 	default:
 		log.Warn("Synchronisation failed, retrying", "err", err)
@@ -511,9 +514,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		d.syncInitHook(origin, height)
 	}
 	fetchers := []func() error{
-		func() error { return d.fetchHeaders(p, origin+1, pivot) }, // Headers are always retrieved
-		func() error { return d.fetchBodies(origin + 1) },          // Bodies are retrieved during normal and fast sync
-		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
+		func() error { return d.fetchHeaders(p, origin+1, pivot, td) }, // Headers are always retrieved
+		func() error { return d.fetchBodies(origin + 1) },              // Bodies are retrieved during normal and fast sync
+		func() error { return d.fetchReceipts(origin + 1) },            // Receipts are retrieved during fast sync
 		func() error { return d.processHeaders(origin+1, pivot, td) },
 	}
 	if d.mode == FastSync {
@@ -908,9 +911,14 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 // other peers are only accepted if they map cleanly to the skeleton. If no one
 // can fill in the skeleton - not even the origin peer - it's assumed invalid and
 // the origin is dropped.
-func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64) error {
+//
+// Every skeleton batch is also requested from a few witness peers advertising
+// at least the same total difficulty. If a quorum of witnesses contradicts the
+// origin, or the origin stops responding, it is dropped and one of the other
+// peers takes over as origin without restarting the sync.
+func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64, td *big.Int) error {
 	p.log.Debug("Directing header downloads", "origin", from)
-	defer p.log.Debug("Header download terminated")
+	defer func() { p.log.Debug("Header download terminated") }()
 
 	// Create a timeout timer, and the associated header fetcher
 	skeleton := true            // Skeleton assembly phase or finishing up
@@ -919,7 +927,12 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64)
 	<-timeout.C                 // timeout channel should be initially empty
 	defer timeout.Stop()
 
-	var ttl time.Duration
+	var (
+		ttl       time.Duration
+		witnesses map[string]bool            // witnesses with outstanding skeleton requests
+		replies   map[string][]*types.Header // skeleton batches delivered by witnesses
+		master    dataPack                   // skeleton batch of the origin, held back for witnesses
+	)
 	getHeaders := func(from uint64) {
 		request = time.Now()
 
@@ -929,6 +942,13 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64)
 		if skeleton {
 			p.log.Trace("Fetching skeleton headers", "count", MaxHeaderFetch, "from", from)
 			go p.peer.RequestHeadersByNumber(from+uint64(MaxHeaderFetch)-1, MaxSkeletonSize, MaxHeaderFetch-1, false)
+
+			witnesses, replies, master = make(map[string]bool), make(map[string][]*types.Header), nil
+			for _, w := range d.syncCandidates(p, td, skeletonWitnesses) {
+				w.log.Trace("Fetching skeleton headers for cross-check", "count", MaxHeaderFetch, "from", from)
+				go w.peer.RequestHeadersByNumber(from+uint64(MaxHeaderFetch)-1, MaxSkeletonSize, MaxHeaderFetch-1, false)
+				witnesses[w.id] = true
+			}
 		} else {
 			p.log.Trace("Fetching full headers", "count", MaxHeaderFetch, "from", from)
 			go p.peer.RequestHeadersByNumber(from, MaxHeaderFetch, 0, false)
@@ -939,123 +959,79 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64)
 	getHeaders(from)
 
 	for {
+		var packet dataPack
 		select {
 		case <-d.cancelCh:
 			return errCanceled
 
-		case packet := <-d.headerCh:
+		case packet = <-d.headerCh:
+			// Collect the skeleton batches of the witnesses
+			if skeleton && witnesses[packet.PeerId()] {
+				delete(witnesses, packet.PeerId())
+				replies[packet.PeerId()] = packet.(*headerPack).headers
+				if master == nil || len(witnesses) > 0 {
+					continue
+				}
+				packet, master = master, nil
+			}
 			// Make sure the active peer is giving us the skeleton headers
 			if packet.PeerId() != p.id {
 				log.Debug("Received skeleton from incorrect peer", "peer", packet.PeerId())
-				break
+				continue
 			}
-			headerReqTimer.UpdateSince(request)
-			timeout.Stop()
-
-			// If the skeleton's finished, pull any remaining head headers directly from the origin
-			if packet.Items() == 0 && skeleton {
-				skeleton = false
-				getHeaders(from)
+			if skeleton && (master != nil || !isSkeletonBatch(packet.(*headerPack).headers, from)) {
+				p.log.Debug("Received stale headers during skeleton assembly", "count", packet.Items())
 				continue
 			}
-			// If no more headers are inbound, notify the content fetchers and return
-			if packet.Items() == 0 {
-				// Don't abort header fetches while the pivot is downloading
-				if atomic.LoadInt32(&d.committed) == 0 && pivot <= from {
-					p.log.Debug("No headers, waiting for pivot commit")
+			// Hold back the skeleton until the witnesses answered, but don't wait for
+			// them longer than a round trip.
+			if skeleton && len(witnesses) > 0 {
+				master = packet
+				if !timeout.Stop() {
 					select {
-					case <-time.After(fsHeaderContCheck):
-						getHeaders(from)
-						continue
-					case <-d.cancelCh:
-						return errCanceled
-					}
-				}
-				// Pivot done (or not in fast sync) and no more headers, terminate the process
-				p.log.Debug("No more headers available")
-				select {
-				case d.headerProcCh <- nil:
-					return nil
-				case <-d.cancelCh:
-					return errCanceled
-				}
-			}
-			headers := packet.(*headerPack).headers
-
-			// If we received a skeleton batch, resolve internals concurrently
-			if skeleton {
-				filled, proced, err := d.fillHeaderSkeleton(from, headers)
-				if err != nil {
-					p.log.Debug("Skeleton chain invalid", "err", err)
-					return errInvalidChain
-				}
-				headers = filled[proced:]
-				from += uint64(proced)
-			} else {
-				// If we're closing in on the chain head, but haven't yet reached it, delay
-				// the last few headers so mini reorgs on the head don't cause invalid hash
-				// chain errors.
-				if n := len(headers); n > 0 {
-					// Retrieve the current head we're at
-					var head uint64
-					if d.mode == LightSync {
-						head = d.lightchain.CurrentHeader().Number.Uint64()
-					} else {
-						head = d.blockchain.CurrentFastBlock().NumberU64()
-						if full := d.blockchain.CurrentBlock().NumberU64(); head < full {
-							head = full
-						}
-					}
-					// If the head is below the common ancestor, we're actually deduplicating
-					// already existing chain segments, so use the ancestor as the fake head.
-					// Otherwise we might end up delaying header deliveries pointlessly.
-					if head < ancestor {
-						head = ancestor
-					}
-					// If the head is way older than this batch, delay the last few headers
-					if head+uint64(reorgProtThreshold) < headers[n-1].Number.Uint64() {
-						delay := reorgProtHeaderDelay
-						if delay > n {
-							delay = n
-						}
-						headers = headers[:n-delay]
+					case <-timeout.C:
+					default:
 					}
 				}
+				timeout.Reset(d.requestRTT())
+				continue
 			}
-			// Insert all the new headers and fetch the next batch
-			if len(headers) > 0 {
-				p.log.Trace("Scheduling new headers", "count", len(headers), "from", from)
-				select {
-				case d.headerProcCh <- headers:
-				case <-d.cancelCh:
-					return errCanceled
-				}
-				from += uint64(len(headers))
-				getHeaders(from)
-			} else {
-				// No headers delivered, or all of them being delayed, sleep a bit and retry
-				p.log.Trace("All headers delayed, waiting")
+			headerReqTimer.UpdateSince(request)
+			if !timeout.Stop() {
+				// The grace period for the witnesses might have just expired
 				select {
-				case <-time.After(fsHeaderContCheck):
-					getHeaders(from)
-					continue
-				case <-d.cancelCh:
-					return errCanceled
+				case <-timeout.C:
+				default:
 				}
 			}
 
 		case <-timeout.C:
+			// If the origin answered, give up on the remaining witnesses
+			if master != nil {
+				p.log.Trace("Skeleton witnesses timed out", "missing", len(witnesses))
+				packet, master, witnesses = master, nil, nil
+				headerReqTimer.UpdateSince(request)
+				break
+			}
 			if d.dropPeer == nil {
 				// The dropPeer method is nil when `--copydb` is used for a local copy.
 				// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
 				p.log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", p.id)
-				break
+				continue
 			}
 			// Header retrieval timed out, consider the peer bad and drop
 			p.log.Debug("Header request timed out", "elapsed", ttl)
 			headerTimeoutMeter.Mark(1)
-			d.dropPeer(p.id)
+			d.dropPeer(p.id, errTimeout)
 
+			// Hand the header retrieval over to another peer if possible
+			if next := d.syncCandidates(p, td, 1); len(next) > 0 {
+				p = next[0]
+				d.setMasterPeer(p.id)
+				p.log.Debug("Switching header origin", "from", from)
+				getHeaders(from)
+				continue
+			}
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh} {
 				select {
@@ -1069,7 +1045,185 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64)
 			}
 			return errBadPeer
 		}
+		// If the skeleton's finished, pull any remaining head headers directly from the origin
+		if packet.Items() == 0 && skeleton {
+			skeleton = false
+			getHeaders(from)
+			continue
+		}
+		// If no more headers are inbound, notify the content fetchers and return
+		if packet.Items() == 0 {
+			// Don't abort header fetches while the pivot is downloading
+			if atomic.LoadInt32(&d.committed) == 0 && pivot <= from {
+				p.log.Debug("No headers, waiting for pivot commit")
+				select {
+				case <-time.After(fsHeaderContCheck):
+					getHeaders(from)
+					continue
+				case <-d.cancelCh:
+					return errCanceled
+				}
+			}
+			// Pivot done (or not in fast sync) and no more headers, terminate the process
+			p.log.Debug("No more headers available")
+			select {
+			case d.headerProcCh <- nil:
+				return nil
+			case <-d.cancelCh:
+				return errCanceled
+			}
+		}
+		headers := packet.(*headerPack).headers
+
+		// If we received a skeleton batch, resolve internals concurrently
+		var switched bool
+		if skeleton {
+			// Cut the skeleton where a quorum of witnesses disagrees and switch over
+			// to a peer on the majority chain.
+			valid, rivals := crossCheckSkeleton(headers, replies)
+			if len(rivals) > 0 {
+				if next := d.peers.Peer(rivals[0]); next != nil {
+					p.log.Warn("Skeleton contradicted by other peers", "number", headers[valid].Number, "witnesses", len(rivals))
+					if d.dropPeer != nil {
+						d.dropPeer(p.id, errInvalidChain)
+					}
+					headers, p, switched = headers[:valid], next, true
+					d.setMasterPeer(p.id)
+					p.log.Debug("Switching header origin", "from", from)
+				}
+			}
+			if len(headers) > 0 {
+				filled, proced, err := d.fillHeaderSkeleton(from, headers)
+				if err != nil {
+					p.log.Debug("Skeleton chain invalid", "err", err)
+					return errInvalidChain
+				}
+				headers = filled[proced:]
+				from += uint64(proced)
+			}
+		} else {
+			// If we're closing in on the chain head, but haven't yet reached it, delay
+			// the last few headers so mini reorgs on the head don't cause invalid hash
+			// chain errors.
+			if n := len(headers); n > 0 {
+				// Retrieve the current head we're at
+				var head uint64
+				if d.mode == LightSync {
+					head = d.lightchain.CurrentHeader().Number.Uint64()
+				} else {
+					head = d.blockchain.CurrentFastBlock().NumberU64()
+					if full := d.blockchain.CurrentBlock().NumberU64(); head < full {
+						head = full
+					}
+				}
+				// If the head is below the common ancestor, we're actually deduplicating
+				// already existing chain segments, so use the ancestor as the fake head.
+				// Otherwise we might end up delaying header deliveries pointlessly.
+				if head < ancestor {
+					head = ancestor
+				}
+				// If the head is way older than this batch, delay the last few headers
+				if head+uint64(reorgProtThreshold) < headers[n-1].Number.Uint64() {
+					delay := reorgProtHeaderDelay
+					if delay > n {
+						delay = n
+					}
+					headers = headers[:n-delay]
+				}
+			}
+		}
+		// Insert all the new headers and fetch the next batch
+		if len(headers) > 0 {
+			p.log.Trace("Scheduling new headers", "count", len(headers), "from", from)
+			select {
+			case d.headerProcCh <- headers:
+			case <-d.cancelCh:
+				return errCanceled
+			}
+			from += uint64(len(headers))
+			getHeaders(from)
+		} else if switched {
+			// The new origin takes over right where the contradicted skeleton started
+			getHeaders(from)
+		} else {
+			// No headers delivered, or all of them being delayed, sleep a bit and retry
+			p.log.Trace("All headers delayed, waiting")
+			select {
+			case <-time.After(fsHeaderContCheck):
+				getHeaders(from)
+				continue
+			case <-d.cancelCh:
+				return errCanceled
+			}
+		}
+	}
+}
+
+// setMasterPeer marks the peer which took over as the sync origin, so that
+// stalls of its data deliveries abort the sync the same way as the original one.
+func (d *Downloader) setMasterPeer(id string) {
+	d.cancelLock.Lock()
+	d.cancelPeer = id
+	d.cancelLock.Unlock()
+}
+
+// isSkeletonBatch reports whether the headers may be the answer to a skeleton
+// request starting at the given block number.
+func isSkeletonBatch(headers []*types.Header, from uint64) bool {
+	return len(headers) == 0 || headers[0].Number.Uint64() == from+uint64(MaxHeaderFetch)-1
+}
+
+// syncCandidates returns up to n peers other than the origin, which advertise at
+// least the total difficulty of the sync target. These peers are used to cross-check
+// the skeleton of the origin and may take its place.
+func (d *Downloader) syncCandidates(origin *peerConnection, td *big.Int, n int) []*peerConnection {
+	var peers []*peerConnection
+	for _, p := range d.peers.AllPeers() {
+		if len(peers) >= n {
+			break
+		}
+		if p.id == origin.id || p.version < 62 {
+			continue
+		}
+		if _, ptd := p.peer.Head(); ptd == nil || ptd.Cmp(td) < 0 {
+			continue
+		}
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// crossCheckSkeleton compares a skeleton batch of the origin with the batches
+// retrieved from witnesses. It returns the number of leading skeleton headers
+// which are not contradicted, along with the witnesses contradicting the next
+// one. A header is contradicted if no witness agrees with it, but at least
+// skeletonQuorum of them agree on a different header at the same height.
+func crossCheckSkeleton(skeleton []*types.Header, replies map[string][]*types.Header) (int, []string) {
+	for i, header := range skeleton {
+		var (
+			agreed bool
+			rivals = make(map[common.Hash][]string)
+		)
+		for id, reply := range replies {
+			if i >= len(reply) || reply[i].Number.Cmp(header.Number) != 0 {
+				continue
+			}
+			if hash := reply[i].Hash(); hash == header.Hash() {
+				agreed = true
+			} else {
+				rivals[hash] = append(rivals[hash], id)
+			}
+		}
+		if agreed {
+			continue
+		}
+		for _, ids := range rivals {
+			if len(ids) >= skeletonQuorum {
+				return i, ids
+			}
+		}
 	}
+	return len(skeleton), nil
 }
 
 // fillHeaderSkeleton concurrently retrieves headers from all our available peers
@@ -1275,7 +1429,7 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 							// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
 							peer.log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", pid)
 						} else {
-							d.dropPeer(pid)
+							d.dropPeer(pid, errStallingPeer)
 
 							// If this peer was the master peer, abort sync immediately
 							d.cancelLock.RLock()
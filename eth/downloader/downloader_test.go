@@ -243,8 +243,15 @@ func (dl *downloadTester) InsertHeaderChain(headers []*types.Header, checkFreq i
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
-	// Do a quick check, as the blockchain.InsertHeaderChain doesn't insert anything in case of errors
-	if _, ok := dl.ownHeaders[headers[0].ParentHash]; !ok {
+	// Do a quick check, as the blockchain.InsertHeaderChain doesn't insert anything in case of errors.
+	// The parent might have been migrated into the ancient store already.
+	ptd := func(hash common.Hash) *big.Int {
+		if td, ok := dl.ownChainTd[hash]; ok {
+			return td
+		}
+		return dl.ancientChainTd[hash]
+	}
+	if ptd(headers[0].ParentHash) == nil {
 		return 0, errors.New("unknown parent")
 	}
 	for i := 1; i < len(headers); i++ {
@@ -254,15 +261,15 @@ func (dl *downloadTester) InsertHeaderChain(headers []*types.Header, checkFreq i
 	}
 	// Do a full insert if pre-checks passed
 	for i, header := range headers {
-		if _, ok := dl.ownHeaders[header.Hash()]; ok {
+		if ptd(header.Hash()) != nil {
 			continue
 		}
-		if _, ok := dl.ownHeaders[header.ParentHash]; !ok {
+		if ptd(header.ParentHash) == nil {
 			return i, errors.New("unknown parent")
 		}
 		dl.ownHashes = append(dl.ownHashes, header.Hash())
 		dl.ownHeaders[header.Hash()] = header
-		dl.ownChainTd[header.Hash()] = new(big.Int).Add(dl.ownChainTd[header.ParentHash], header.Difficulty)
+		dl.ownChainTd[header.Hash()] = new(big.Int).Add(ptd(header.ParentHash), header.Difficulty)
 	}
 	return len(headers), nil
 }
@@ -354,7 +361,7 @@ func (dl *downloadTester) newPeer(id string, version int, chain *testChain) erro
 }
 
 // dropPeer simulates a hard peer removal from the connection pool.
-func (dl *downloadTester) dropPeer(id string) {
+func (dl *downloadTester) dropPeer(id string, reason error) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
@@ -362,6 +369,18 @@ func (dl *downloadTester) dropPeer(id string) {
 	dl.downloader.UnregisterPeer(id)
 }
 
+// newStallingPeer registers a new download peer, which stops responding to
+// requests once the stalled flag is set, except to header requests if headers
+// is true.
+func (dl *downloadTester) newStallingPeer(id string, version int, chain *testChain, stalled *int32, headers bool) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	peer := &downloadTesterPeer{dl: dl, id: id, chain: chain}
+	dl.peers[id] = peer
+	return dl.downloader.RegisterPeer(id, version, &stallingTesterPeer{peer, stalled, headers})
+}
+
 type downloadTesterPeer struct {
 	dl            *downloadTester
 	id            string
@@ -440,6 +459,42 @@ func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// stallingTesterPeer is a download tester peer which stops responding to some
+// of the requests once the stalled flag is set.
+type stallingTesterPeer struct {
+	*downloadTesterPeer
+	stalled *int32 // Flag marking whether the peer stopped responding
+	headers bool   // Whether to keep responding to header requests when stalled
+}
+
+func (dlp *stallingTesterPeer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse bool) error {
+	if atomic.LoadInt32(dlp.stalled) == 1 && !dlp.headers {
+		return nil
+	}
+	return dlp.downloadTesterPeer.RequestHeadersByHash(origin, amount, skip, reverse)
+}
+
+func (dlp *stallingTesterPeer) RequestHeadersByNumber(origin uint64, amount int, skip int, reverse bool) error {
+	if atomic.LoadInt32(dlp.stalled) == 1 && !dlp.headers {
+		return nil
+	}
+	return dlp.downloadTesterPeer.RequestHeadersByNumber(origin, amount, skip, reverse)
+}
+
+func (dlp *stallingTesterPeer) RequestBodies(hashes []common.Hash) error {
+	if atomic.LoadInt32(dlp.stalled) == 1 {
+		return nil
+	}
+	return dlp.downloadTesterPeer.RequestBodies(hashes)
+}
+
+func (dlp *stallingTesterPeer) RequestReceipts(hashes []common.Hash) error {
+	if atomic.LoadInt32(dlp.stalled) == 1 {
+		return nil
+	}
+	return dlp.downloadTesterPeer.RequestReceipts(hashes)
+}
+
 // assertOwnChain checks if the local chain contains the correct number of items
 // of the various chain components.
 func assertOwnChain(t *testing.T, tester *downloadTester, length int) {
@@ -720,6 +775,95 @@ func testBoundedHeavyForkedSync(t *testing.T, protocol int, mode SyncMode) {
 	}
 }
 
+// Tests that if the skeleton of the origin peer is contradicted by a quorum of
+// other peers, the origin is dropped and the sync continues with one of them.
+func TestSkeletonCrossCheck63Full(t *testing.T)  { testSkeletonCrossCheck(t, 63, FullSync) }
+func TestSkeletonCrossCheck63Fast(t *testing.T)  { testSkeletonCrossCheck(t, 63, FastSync) }
+func TestSkeletonCrossCheck64Full(t *testing.T)  { testSkeletonCrossCheck(t, 64, FullSync) }
+func TestSkeletonCrossCheck64Fast(t *testing.T)  { testSkeletonCrossCheck(t, 64, FastSync) }
+func TestSkeletonCrossCheck64Light(t *testing.T) { testSkeletonCrossCheck(t, 64, LightSync) }
+
+func testSkeletonCrossCheck(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chainA := testChainForkLightA
+	chainB := testChainForkLightB
+	tester.newPeer("liar", protocol, chainB)
+	tester.newPeer("honest-1", protocol, chainA)
+	tester.newPeer("honest-2", protocol, chainA)
+
+	// Synchronise with the liar and make sure the honest chain was retrieved
+	if err := tester.sync("liar", chainA.td(chainA.headBlock().Hash()), mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chainA.len())
+	if _, ok := tester.peers["liar"]; ok {
+		t.Errorf("contradicted origin not dropped")
+	}
+}
+
+// Tests that if the origin peer times out and its replacement stalls delivering
+// data, the stall is detected as that of the master peer and the sync aborted.
+func TestOriginSwitchStall63Full(t *testing.T) { testOriginSwitchStall(t, 63, FullSync) }
+func TestOriginSwitchStall63Fast(t *testing.T) { testOriginSwitchStall(t, 63, FastSync) }
+func TestOriginSwitchStall64Full(t *testing.T) { testOriginSwitchStall(t, 64, FullSync) }
+func TestOriginSwitchStall64Fast(t *testing.T) { testOriginSwitchStall(t, 64, FastSync) }
+
+func testOriginSwitchStall(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// Create an origin going silent once the sync starts and a replacement which
+	// keeps serving headers, but never any block data.
+	var stalled int32
+	tester.downloader.syncInitHook = func(uint64, uint64) { atomic.StoreInt32(&stalled, 1) }
+
+	chain := testChainBase.shorten(MaxHeaderFetch * 4)
+	tester.newStallingPeer("origin", protocol, chain, &stalled, false)
+	tester.newStallingPeer("staller", protocol, chain, &stalled, true)
+
+	// Pin the QoS values low so the test doesn't wait for the default timeouts.
+	// The peer round trip times are lowered too, so that even if the QoS tuner
+	// runs in between, it can't raise the timeouts by much.
+	for _, id := range []string{"origin", "staller"} {
+		p := tester.downloader.peers.Peer(id)
+		p.lock.Lock()
+		p.rtt = rttMinEstimate
+		p.lock.Unlock()
+	}
+	pin := func() {
+		atomic.StoreUint64(&tester.downloader.rttEstimate, uint64(100*time.Millisecond))
+		atomic.StoreUint64(&tester.downloader.rttConfidence, 1000000)
+	}
+	pin()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			pin()
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	if err := tester.sync("origin", nil, mode); err != errTimeout {
+		t.Fatalf("synchronisation error mismatch: have %v, want %v", err, errTimeout)
+	}
+	for _, id := range []string{"origin", "staller"} {
+		if _, ok := tester.peers[id]; ok {
+			t.Errorf("peer %s not dropped", id)
+		}
+	}
+}
+
 // Tests that an inactive downloader will not accept incoming block headers and
 // bodies.
 func TestInactiveDownloader62(t *testing.T) {
@@ -20,10 +20,25 @@ import (
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer detected as malicious. The
+// reason is the synchronisation error which caused the drop.
+type peerDropFn func(id string, reason error)
+
+// DropViolation classifies the reason of a peer drop as the misbehaviour it
+// represents at the networking layer. It returns false if the reason does not
+// indicate misbehaviour, e.g. if the peer is merely behind.
+func DropViolation(reason error) (p2p.Violation, bool) {
+	switch reason {
+	case errTimeout, errStallingPeer:
+		return p2p.ViolationTimeout, true
+	case errBadPeer, errEmptyHeaderSet, errInvalidAncestor, errInvalidChain:
+		return p2p.ViolationBadBlock, true
+	}
+	return 0, false
+}
 
 // dataPack is a data message returned by a peer for some query.
 type dataPack interface {
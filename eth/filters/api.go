@@ -28,9 +28,11 @@ import (
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -104,7 +106,7 @@ func (api *PublicFilterAPI) timeoutLoop() {
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newpendingtransactionfilter
 func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	var (
-		pendingTxs   = make(chan []common.Hash)
+		pendingTxs   = make(chan []*types.Transaction)
 		pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
 	)
 
@@ -115,10 +117,12 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	go func() {
 		for {
 			select {
-			case ph := <-pendingTxs:
+			case txs := <-pendingTxs:
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
-					f.hashes = append(f.hashes, ph...)
+					for _, tx := range txs {
+						f.hashes = append(f.hashes, tx.Hash())
+					}
 				}
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
@@ -133,27 +137,60 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	return pendingTxSub.ID
 }
 
+// PendingTxFilter narrows down the pending transactions delivered by the
+// newPendingTransactions subscription and selects their representation.
+type PendingTxFilter struct {
+	FullTx bool             `json:"fullTx"` // Deliver full transaction objects instead of hashes
+	From   []common.Address `json:"from"`   // Only deliver transactions sent by these accounts
+	To     []common.Address `json:"to"`     // Only deliver transactions sent to these accounts
+}
+
+// matches reports whether the transaction passes the sender and recipient
+// restrictions of the filter.
+func (f *PendingTxFilter) matches(tx *types.Transaction) bool {
+	if len(f.To) > 0 && (tx.To() == nil || !includes(f.To, *tx.To())) {
+		return false
+	}
+	if len(f.From) > 0 && !includes(f.From, txSender(tx)) {
+		return false
+	}
+	return true
+}
+
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+//
+// By default only the transaction hashes are delivered. The optional filter can request
+// the full transaction objects instead and restrict the stream to the given senders and
+// recipients.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, filter *PendingTxFilter) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
+	if filter == nil {
+		filter = new(PendingTxFilter)
+	}
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan []common.Hash, 128)
-		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
+		pendingTxs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribePendingTxs(pendingTxs)
 
 		for {
 			select {
-			case hashes := <-txHashes:
-				// To keep the original behaviour, send a single tx hash in one notification.
-				// TODO(rjl493456442) Send a batch of tx hashes in one notification
-				for _, h := range hashes {
-					notifier.Notify(rpcSub.ID, h)
+			case txs := <-pendingTxs:
+				// To keep the original behaviour, send a single tx in one notification.
+				// TODO(rjl493456442) Send a batch of txs in one notification
+				for _, tx := range txs {
+					if !filter.matches(tx) {
+						continue
+					}
+					if filter.FullTx {
+						notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx))
+					} else {
+						notifier.Notify(rpcSub.ID, tx.Hash())
+					}
 				}
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
@@ -168,6 +205,60 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// TransactionStatus creates a subscription that tracks the transaction with the
+// given hash. A notification is sent whenever the transaction enters the pool,
+// is included in the canonical chain, is reorged out of it again or is replaced
+// by a different transaction of the same sender and nonce.
+//
+// The current status, if the transaction is known, is sent right away.
+func (api *PublicFilterAPI) TransactionStatus(ctx context.Context, hash common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	// Subscribe to the events before checking the initial status, so no change
+	// can slip through in between
+	var (
+		txs      = make(chan core.NewTxsEvent, txChanSize)
+		chain    = make(chan core.ChainEvent, chainEvChanSize)
+		txSub    = api.backend.SubscribeNewTxsEvent(txs)
+		chainSub = api.backend.SubscribeChainEvent(chain)
+		tracker  = newTxStatusTracker(api.backend, hash)
+	)
+	notify := func(events []*TxStatusEvent) {
+		for _, ev := range events {
+			notifier.Notify(rpcSub.ID, ev)
+		}
+	}
+	notify(tracker.check(nil))
+
+	go func() {
+		defer txSub.Unsubscribe()
+		defer chainSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-txs:
+				notify(tracker.pooled(ev.Txs))
+			case ev := <-chain:
+				notify(tracker.check(ev.Block))
+			case <-txSub.Err():
+				return
+			case <-chainSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
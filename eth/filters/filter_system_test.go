@@ -18,6 +18,8 @@ package filters
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -31,9 +33,12 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -83,6 +88,23 @@ func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*type
 	return rawdb.ReadHeader(b.db, hash, *number), nil
 }
 
+func (b *testBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	header, _ := b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, nil
+	}
+	return rawdb.ReadBlock(b.db, header.Hash(), header.Number.Uint64()), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, _ := b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(b.db))
+	return statedb, header, err
+}
+
 func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	if number := rawdb.ReadHeaderNumber(b.db, hash); number != nil {
 		return rawdb.ReadReceipts(b.db, hash, *number, params.TestChainConfig), nil
@@ -104,6 +126,15 @@ func (b *testBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types
 	return logs, nil
 }
 
+func (b *testBackend) GetTransaction(ctx context.Context, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.db, hash)
+	return tx, blockHash, blockNumber, index, nil
+}
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return nil
+}
+
 func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.txFeed.Subscribe(ch)
 }
@@ -273,6 +304,105 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTxSubscription tests that pending transaction subscriptions deliver
+// hashes or full transactions, restricted to the requested senders and recipients.
+func TestPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = rawdb.NewMemoryDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		api        = NewPublicFilterAPI(backend, false)
+
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		to1     = common.HexToAddress("0x01")
+		to2     = common.HexToAddress("0x02")
+	)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, new(big.Int), 21000, new(big.Int), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	transactions := []*types.Transaction{sign(key1, 0, to1), sign(key2, 0, to1), sign(key1, 1, to2), sign(key2, 1, to2)}
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	server.RegisterName("eth", api)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var (
+		hashes    = make(chan common.Hash)
+		fullTxs   = make(chan *ethapi.RPCTransaction)
+		all       = make(chan common.Hash)
+		ctx       = context.Background()
+		subs      []*rpc.ClientSubscription
+		subscribe = func(ch interface{}, filter *PendingTxFilter) {
+			sub, err := client.EthSubscribe(ctx, ch, "newPendingTransactions", filter)
+			if err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+			subs = append(subs, sub)
+		}
+	)
+	subscribe(all, nil)
+	subscribe(hashes, &PendingTxFilter{To: []common.Address{to2}})
+	subscribe(fullTxs, &PendingTxFilter{FullTx: true, From: []common.Address{addr1}})
+	defer func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}()
+
+	time.Sleep(1 * time.Second)
+	txFeed.Send(core.NewTxsEvent{Txs: transactions})
+
+	timeout := time.After(5 * time.Second)
+	for _, tx := range transactions {
+		select {
+		case hash := <-all:
+			if hash != tx.Hash() {
+				t.Errorf("unfiltered hash mismatch: have %x, want %x", hash, tx.Hash())
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for unfiltered transactions")
+		}
+	}
+	for _, tx := range []*types.Transaction{transactions[2], transactions[3]} {
+		select {
+		case hash := <-hashes:
+			if hash != tx.Hash() {
+				t.Errorf("recipient filtered hash mismatch: have %x, want %x", hash, tx.Hash())
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for recipient filtered transactions")
+		}
+	}
+	for _, tx := range []*types.Transaction{transactions[0], transactions[2]} {
+		select {
+		case rpcTx := <-fullTxs:
+			if rpcTx.Hash != tx.Hash() || rpcTx.From != addr1 || uint64(rpcTx.Nonce) != tx.Nonce() || rpcTx.BlockHash != nil {
+				t.Errorf("sender filtered transaction mismatch: have %+v, want %x", rpcTx, tx.Hash())
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for sender filtered transactions")
+		}
+	}
+	select {
+	case hash := <-hashes:
+		t.Errorf("unexpected recipient filtered transaction %x", hash)
+	case rpcTx := <-fullTxs:
+		t.Errorf("unexpected sender filtered transaction %x", rpcTx.Hash)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
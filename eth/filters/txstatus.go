@@ -0,0 +1,194 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxReplacementLookback is the maximum number of canonical blocks searched for
+// the transaction superseding a tracked one.
+const maxReplacementLookback = 128
+
+// TxStatus is the stage of a transaction's lifecycle reported by the
+// transactionStatus subscription.
+type TxStatus string
+
+const (
+	TxStatusPending  TxStatus = "pending"  // Transaction entered the transaction pool
+	TxStatusIncluded TxStatus = "included" // Transaction was included in the canonical chain
+	TxStatusReorged  TxStatus = "reorged"  // Transaction was removed from the canonical chain
+	TxStatusReplaced TxStatus = "replaced" // Another transaction with the same sender and nonce superseded it
+)
+
+// TxStatusEvent is a notification of the transactionStatus subscription.
+type TxStatusEvent struct {
+	Hash        common.Hash     `json:"hash"`
+	Status      TxStatus        `json:"status"`
+	BlockHash   *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	ReplacedBy  *common.Hash    `json:"replacedBy,omitempty"`
+}
+
+// txStatusTracker follows a single transaction through the transaction pool and
+// the canonical chain, producing an event whenever its status changes.
+//
+// Replacements can only be detected once the transaction itself was seen, since
+// its sender and nonce are needed to recognise them.
+type txStatusTracker struct {
+	backend Backend
+	hash    common.Hash
+
+	tx     *types.Transaction // Tracked transaction, nil until first seen
+	sender common.Address     // Sender of the tracked transaction, if seen
+
+	status    TxStatus    // Last reported status, empty if none yet
+	blockHash common.Hash // Block including the transaction if status is included
+}
+
+// newTxStatusTracker creates a tracker for the transaction with the given hash.
+func newTxStatusTracker(backend Backend, hash common.Hash) *txStatusTracker {
+	return &txStatusTracker{backend: backend, hash: hash}
+}
+
+// check re-evaluates the status of the transaction against the canonical chain
+// after the given block was imported. A nil block requests the initial status,
+// which also considers the transaction pool.
+func (t *txStatusTracker) check(block *types.Block) []*TxStatusEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Report inclusion if the transaction is found in a canonical block
+	tx, blockHash, number, _, err := t.backend.GetTransaction(ctx, t.hash)
+	if err == nil && tx != nil {
+		t.learn(tx)
+		if header, _ := t.backend.HeaderByNumber(ctx, rpc.BlockNumber(number)); header != nil && header.Hash() == blockHash {
+			if t.status == TxStatusIncluded && t.blockHash == blockHash {
+				return nil
+			}
+			t.status, t.blockHash = TxStatusIncluded, blockHash
+			return []*TxStatusEvent{{Hash: t.hash, Status: TxStatusIncluded, BlockHash: &blockHash, BlockNumber: (*hexutil.Uint64)(&number)}}
+		}
+	}
+	// Not (or no longer) part of the canonical chain
+	var events []*TxStatusEvent
+	if t.status == TxStatusIncluded {
+		t.status, t.blockHash = TxStatusReorged, common.Hash{}
+		events = append(events, &TxStatusEvent{Hash: t.hash, Status: TxStatusReorged})
+	}
+	if block == nil {
+		if tx := t.backend.GetPoolTransaction(t.hash); tx != nil {
+			t.learn(tx)
+			t.status = TxStatusPending
+			events = append(events, &TxStatusEvent{Hash: t.hash, Status: TxStatusPending})
+		}
+		return events
+	}
+	// A transaction whose nonce was consumed by the chain was superseded, even if
+	// the replacement landed in a block not announced by a chain event (reorgs
+	// only announce the new head)
+	if t.tx != nil && t.status != TxStatusReplaced {
+		if statedb, header, _ := t.backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber); statedb != nil && statedb.GetNonce(t.sender) > t.tx.Nonce() {
+			events = append(events, t.replaced(t.findReplacement(ctx, header.Number.Uint64())))
+		}
+	}
+	return events
+}
+
+// pooled processes a batch of transactions entering the transaction pool.
+func (t *txStatusTracker) pooled(txs []*types.Transaction) []*TxStatusEvent {
+	var events []*TxStatusEvent
+	for _, tx := range txs {
+		if tx.Hash() == t.hash {
+			t.learn(tx)
+			if t.status == "" || t.status == TxStatusReorged {
+				t.status = TxStatusPending
+				events = append(events, &TxStatusEvent{Hash: t.hash, Status: TxStatusPending})
+			}
+			continue
+		}
+		if t.status != TxStatusIncluded && t.status != TxStatusReplaced && t.replacedBy(tx) {
+			events = append(events, t.replaced(tx.Hash()))
+		}
+	}
+	return events
+}
+
+// learn records the sender and nonce of the tracked transaction.
+func (t *txStatusTracker) learn(tx *types.Transaction) {
+	if t.tx == nil {
+		t.tx, t.sender = tx, txSender(tx)
+	}
+}
+
+// replacedBy reports whether the given transaction supersedes the tracked one.
+func (t *txStatusTracker) replacedBy(tx *types.Transaction) bool {
+	if t.tx == nil || tx.Nonce() != t.tx.Nonce() || tx.Hash() == t.hash {
+		return false
+	}
+	return txSender(tx) == t.sender
+}
+
+// findReplacement searches the canonical chain backwards from the given block
+// for the transaction of the same sender and nonce as the tracked one, looking at
+// no more than maxReplacementLookback blocks. It returns the zero hash if none
+// is found.
+func (t *txStatusTracker) findReplacement(ctx context.Context, head uint64) common.Hash {
+	for number := head; number+maxReplacementLookback > head; number-- {
+		block, _ := t.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if block == nil {
+			break
+		}
+		for _, tx := range block.Transactions() {
+			if t.replacedBy(tx) {
+				return tx.Hash()
+			}
+		}
+		if number == 0 {
+			break
+		}
+	}
+	return common.Hash{}
+}
+
+// replaced marks the tracked transaction as superseded by the one with the given
+// hash, which is not reported if zero.
+func (t *txStatusTracker) replaced(by common.Hash) *TxStatusEvent {
+	t.status = TxStatusReplaced
+	event := &TxStatusEvent{Hash: t.hash, Status: TxStatusReplaced}
+	if by != (common.Hash{}) {
+		event.ReplacedBy = &by
+	}
+	return event
+}
+
+// txSender returns the sender of a transaction, or the zero address if the
+// signature is invalid.
+func txSender(tx *types.Transaction) common.Address {
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+	return from
+}
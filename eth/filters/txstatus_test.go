@@ -0,0 +1,166 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the transaction status subscription reports transactions entering
+// the pool, getting included, reorged out and replaced.
+func TestTransactionStatus(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = rawdb.NewMemoryDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		api        = NewPublicFilterAPI(backend, false)
+
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+	)
+	sign := func(nonce uint64, price int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, new(big.Int), params.TxGas, big.NewInt(price), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	var (
+		genesis = gspec.MustCommit(db)
+		tx1     = sign(0, 1)
+		tx2     = sign(0, 2) // replaces tx1 in the reorged chain
+		tx3     = sign(1, 1)
+		tx4     = sign(1, 2) // replaces tx3 in the pool
+		tx5     = sign(1, 3)
+		tx6     = sign(1, 4) // replaces tx5 in a reorged in block below the head
+
+		chain, _  = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) { gen.AddTx(tx1) })
+		fork, _   = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) { gen.AddTx(tx2) })
+		chain2, _ = core.GenerateChain(params.TestChainConfig, fork[0], ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) { gen.AddTx(tx5) })
+		fork2, _  = core.GenerateChain(params.TestChainConfig, fork[0], ethash.NewFaker(), db, 2, func(i int, gen *core.BlockGen) {
+			if i == 0 {
+				gen.AddTx(tx6)
+			}
+		})
+	)
+	// setCanonical makes the given block canonical, dropping the transaction
+	// lookups of the block it replaces
+	setCanonical := func(block, old *types.Block) {
+		if old != nil {
+			for _, tx := range old.Transactions() {
+				rawdb.DeleteTxLookupEntry(db, tx.Hash())
+			}
+		}
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteTxLookupEntries(db, block)
+	}
+	// setHead additionally makes the block the head and announces it
+	setHead := func(block, old *types.Block) {
+		setCanonical(block, old)
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		chainFeed.Send(core.ChainEvent{Block: block, Hash: block.Hash()})
+	}
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	server.RegisterName("eth", api)
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	subscribe := func(hash common.Hash) (chan *TxStatusEvent, *rpc.ClientSubscription) {
+		ch := make(chan *TxStatusEvent)
+		sub, err := client.EthSubscribe(context.Background(), ch, "transactionStatus", hash)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		return ch, sub
+	}
+	expect := func(ch chan *TxStatusEvent, status TxStatus, block *types.Block, replacement *types.Transaction) {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			if ev.Status != status {
+				t.Fatalf("status mismatch: have %s, want %s", ev.Status, status)
+			}
+			if block != nil && (ev.BlockHash == nil || *ev.BlockHash != block.Hash() || ev.BlockNumber == nil || uint64(*ev.BlockNumber) != block.NumberU64()) {
+				t.Errorf("inclusion mismatch: have %v #%v, want %x #%d", ev.BlockHash, ev.BlockNumber, block.Hash(), block.NumberU64())
+			}
+			if replacement != nil && (ev.ReplacedBy == nil || *ev.ReplacedBy != replacement.Hash()) {
+				t.Errorf("replacement mismatch: have %v, want %x", ev.ReplacedBy, replacement.Hash())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s status", status)
+		}
+	}
+	// Follow tx1 from the pool into a block and out of the chain again
+	ch1, sub1 := subscribe(tx1.Hash())
+	defer sub1.Unsubscribe()
+
+	txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx1}})
+	expect(ch1, TxStatusPending, nil, nil)
+	setHead(chain[0], nil)
+	expect(ch1, TxStatusIncluded, chain[0], nil)
+	setHead(fork[0], chain[0])
+	expect(ch1, TxStatusReorged, nil, nil)
+	expect(ch1, TxStatusReplaced, nil, tx2)
+
+	// Subscribing to an already included transaction reports it right away
+	ch2, sub2 := subscribe(tx2.Hash())
+	defer sub2.Unsubscribe()
+	expect(ch2, TxStatusIncluded, fork[0], nil)
+
+	// Follow tx3 being replaced in the pool
+	ch3, sub3 := subscribe(tx3.Hash())
+	defer sub3.Unsubscribe()
+
+	txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx3}})
+	expect(ch3, TxStatusPending, nil, nil)
+	txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx4}})
+	expect(ch3, TxStatusReplaced, nil, tx4)
+
+	// Follow tx5 being reorged out by a chain replacing it below its new head,
+	// which only announces the new head
+	ch5, sub5 := subscribe(tx5.Hash())
+	defer sub5.Unsubscribe()
+
+	txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx5}})
+	expect(ch5, TxStatusPending, nil, nil)
+	setHead(chain2[0], nil)
+	expect(ch5, TxStatusIncluded, chain2[0], nil)
+	setCanonical(fork2[0], chain2[0])
+	setHead(fork2[1], nil)
+	expect(ch5, TxStatusReorged, nil, nil)
+	expect(ch5, TxStatusReplaced, nil, tx6)
+}
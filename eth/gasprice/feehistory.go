@@ -0,0 +1,201 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxFeeHistory is the maximum number of blocks that can be retrieved for a
+	// fee history request.
+	maxFeeHistory = 1024
+
+	// maxBlockFetchers is the maximum number of blocks processed concurrently
+	// while serving a fee history request or suggesting a gas price.
+	maxBlockFetchers = 4
+)
+
+var (
+	errBlockNotFound     = errors.New("block not found")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// blockFees is the fee history data of a single block.
+type blockFees struct {
+	reward       []*big.Int // Gas prices at the requested percentiles, nil if none requested
+	gasUsedRatio float64    // Ratio of the gas used to the gas limit of the block
+	err          error      // Any error encountered while processing the block
+}
+
+// txGasAndPrice is the gas used and the gas price of a single transaction.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+type txsByGasPrice []txGasAndPrice
+
+func (t txsByGasPrice) Len() int           { return len(t) }
+func (t txsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t txsByGasPrice) Less(i, j int) bool { return t[i].price.Cmp(t[j].price) < 0 }
+
+// FeeHistory returns the fee history of the given number of blocks up to (and
+// including) lastBlock: the number of the oldest block of the range, the gas used
+// ratio of each block and, if percentiles are requested, the gas prices paid at
+// each of them, weighted by the gas used by the transactions of the block.
+//
+// The pending block is not tracked, requests for it are served up to the latest
+// block instead. The number of blocks is capped at maxFeeHistory and may also be
+// less if the chain is shorter.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	if blocks < 1 {
+		return new(big.Int), nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		log.Warn("Sanitizing fee history length", "requested", blocks, "truncated", maxFeeHistory)
+		blocks = maxFeeHistory
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, nil, nil, fmt.Errorf("invalid reward percentile: %f", p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return nil, nil, nil, fmt.Errorf("invalid reward percentile: #%d:%f > #%d:%f", i-1, percentiles[i-1], i, p)
+		}
+	}
+	// Resolve the last block of the range and the number of available blocks
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return nil, nil, nil, errRequestBeyondHead
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	// Process the blocks with a few concurrent fetchers, each picking the next
+	// unprocessed block, and gather the results in order
+	var (
+		next    int64
+		quit    = make(chan struct{})
+		results = make([]chan blockFees, blocks)
+	)
+	defer close(quit)
+
+	for i := range results {
+		results[i] = make(chan blockFees, 1)
+	}
+	for i := 0; i < maxBlockFetchers && i < blocks; i++ {
+		go func() {
+			for {
+				n := int(atomic.AddInt64(&next, 1) - 1)
+				if n >= blocks {
+					return
+				}
+				select {
+				case <-quit:
+					return
+				default:
+				}
+				results[n] <- gpo.processBlock(ctx, oldest+uint64(n), percentiles)
+			}
+		}()
+	}
+	var (
+		reward       [][]*big.Int
+		gasUsedRatio = make([]float64, blocks)
+	)
+	if len(percentiles) > 0 {
+		reward = make([][]*big.Int, blocks)
+	}
+	for i, ch := range results {
+		fees := <-ch
+		if fees.err != nil {
+			return nil, nil, nil, fees.err
+		}
+		gasUsedRatio[i] = fees.gasUsedRatio
+		if reward != nil {
+			reward[i] = fees.reward
+		}
+	}
+	return new(big.Int).SetUint64(oldest), reward, gasUsedRatio, nil
+}
+
+// processBlock calculates the fee history data of the block with the given number.
+func (gpo *Oracle) processBlock(ctx context.Context, number uint64, percentiles []float64) blockFees {
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		if err == nil {
+			err = errBlockNotFound
+		}
+		return blockFees{err: err}
+	}
+	fees := blockFees{gasUsedRatio: float64(block.GasUsed()) / float64(block.GasLimit())}
+	if len(percentiles) == 0 {
+		return fees
+	}
+	fees.reward = make([]*big.Int, len(percentiles))
+	if len(block.Transactions()) == 0 {
+		for i := range fees.reward {
+			fees.reward[i] = new(big.Int)
+		}
+		return fees
+	}
+	// Weigh the transaction prices by the gas they actually used
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return blockFees{err: err}
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return blockFees{err: fmt.Errorf("receipts of block #%d unavailable", number)}
+	}
+	txs := make([]txGasAndPrice, len(receipts))
+	for i, tx := range block.Transactions() {
+		txs[i] = txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+	}
+	sort.Sort(txsByGasPrice(txs))
+
+	var (
+		index   int
+		gasUsed = txs[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * p / 100)
+		for gasUsed < threshold && index < len(txs)-1 {
+			index++
+			gasUsed += txs[index].gasUsed
+		}
+		fees.reward[i] = txs[index].price
+	}
+	return fees
+}
@@ -29,6 +29,10 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// maxSuggestBlocksFactor is the maximum multiple of the configured number of
+// blocks a custom gas price suggestion may sample.
+const maxSuggestBlocksFactor = 2
+
 var maxPrice = big.NewInt(500 * params.GWei)
 
 type Config struct {
@@ -46,8 +50,8 @@ type Oracle struct {
 	cacheLock sync.RWMutex
 	fetchLock sync.Mutex
 
-	checkBlocks, maxEmpty, maxBlocks int
-	percentile                       int
+	checkBlocks int
+	percentile  int
 }
 
 // NewOracle returns a new oracle.
@@ -67,8 +71,6 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 		backend:     backend,
 		lastPrice:   params.Default,
 		checkBlocks: blocks,
-		maxEmpty:    blocks / 2,
-		maxBlocks:   blocks * 5,
 		percentile:  percent,
 	}
 }
@@ -97,53 +99,129 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	if headHash == lastHead {
 		return lastPrice, nil
 	}
+	price, err := gpo.suggestPrice(ctx, head, gpo.checkBlocks, gpo.percentile, lastPrice)
+	if err != nil {
+		return lastPrice, err
+	}
+	gpo.cacheLock.Lock()
+	gpo.lastHead = headHash
+	gpo.lastPrice = price
+	gpo.cacheLock.Unlock()
+	return price, nil
+}
 
-	blockNum := head.Number.Uint64()
-	ch := make(chan getBlockPricesResult, gpo.checkBlocks)
-	sent := 0
-	exp := 0
-	var blockPrices []*big.Int
-	for sent < gpo.checkBlocks && blockNum > 0 {
-		go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(blockNum))), blockNum, ch)
-		sent++
-		exp++
-		blockNum--
-	}
-	maxEmpty := gpo.maxEmpty
-	for exp > 0 {
-		res := <-ch
+// SuggestPriceWithOptions returns the recommended gas price computed over the
+// given number of recent blocks at the given percentile, instead of the ones
+// configured for the oracle. A non-positive number of blocks or a negative
+// percentile selects the configured value. The number of blocks is capped at
+// maxSuggestBlocksFactor times the configured one. Custom suggestions are not
+// cached.
+func (gpo *Oracle) SuggestPriceWithOptions(ctx context.Context, blocks int, percentile int) (*big.Int, error) {
+	if blocks < 1 {
+		blocks = gpo.checkBlocks
+	}
+	if limit := gpo.checkBlocks * maxSuggestBlocksFactor; blocks > limit {
+		blocks = limit
+	}
+	if percentile < 0 {
+		percentile = gpo.percentile
+	}
+	if percentile > 100 {
+		percentile = 100
+	}
+	if blocks == gpo.checkBlocks && percentile == gpo.percentile {
+		return gpo.SuggestPrice(ctx)
+	}
+	gpo.cacheLock.RLock()
+	lastPrice := gpo.lastPrice
+	gpo.cacheLock.RUnlock()
+
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return lastPrice, err
+	}
+	price, err := gpo.suggestPrice(ctx, head, blocks, percentile, lastPrice)
+	if err != nil {
+		return lastPrice, err
+	}
+	return price, nil
+}
+
+// suggestPrice calculates the gas price at the given percentile of the lowest
+// transaction prices of the given number of blocks before (and including) head.
+// Up to half of the blocks may be empty or only contain transactions of their
+// miners, in which case further blocks are sampled. If no price is found at all,
+// the fallback is returned.
+func (gpo *Oracle) suggestPrice(ctx context.Context, head *types.Header, checkBlocks int, percentile int, fallback *big.Int) (*big.Int, error) {
+	// Process the blocks with a few concurrent fetchers, each picking the next
+	// block number handed out. At most maxBlockFetchers blocks are in flight, so
+	// the fetchers never block on delivering their results.
+	var (
+		quit    = make(chan struct{})
+		blocks  = make(chan uint64)
+		results = make(chan getBlockPricesResult, maxBlockFetchers)
+	)
+	defer close(quit)
+
+	for i := 0; i < maxBlockFetchers; i++ {
+		go func() {
+			for {
+				select {
+				case number := <-blocks:
+					gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), new(big.Int).SetUint64(number)), number, results)
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+	var (
+		maxEmpty  = checkBlocks / 2
+		maxBlocks = checkBlocks * 5
+
+		blockNum    = head.Number.Uint64()
+		needed      = checkBlocks
+		sent        int
+		pending     int
+		blockPrices []*big.Int
+	)
+	for {
+		// Keep the fetchers busy while there are blocks left to sample
+		for pending < maxBlockFetchers && sent < needed && blockNum > 0 {
+			blocks <- blockNum
+			sent++
+			pending++
+			blockNum--
+		}
+		if pending == 0 {
+			break
+		}
+		res := <-results
+		pending--
 		if res.err != nil {
-			return lastPrice, res.err
+			return nil, res.err
 		}
-		exp--
 		if res.price != nil {
 			blockPrices = append(blockPrices, res.price)
 			continue
 		}
+		// Empty block, sample a further one if the allowance is used up
 		if maxEmpty > 0 {
 			maxEmpty--
 			continue
 		}
-		if blockNum > 0 && sent < gpo.maxBlocks {
-			go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(blockNum))), blockNum, ch)
-			sent++
-			exp++
-			blockNum--
+		if needed < maxBlocks {
+			needed++
 		}
 	}
-	price := lastPrice
+	price := fallback
 	if len(blockPrices) > 0 {
 		sort.Sort(bigIntArray(blockPrices))
-		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
+		price = blockPrices[(len(blockPrices)-1)*percentile/100]
 	}
 	if price.Cmp(maxPrice) > 0 {
 		price = new(big.Int).Set(maxPrice)
 	}
-
-	gpo.cacheLock.Lock()
-	gpo.lastHead = headHash
-	gpo.lastPrice = price
-	gpo.cacheLock.Unlock()
 	return price, nil
 }
 
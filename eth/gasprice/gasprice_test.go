@@ -0,0 +1,262 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend is a chain backed oracle backend, implementing only the methods
+// used by the oracle.
+type testBackend struct {
+	ethapi.Backend
+	chain *core.BlockChain
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number < 0 {
+		return b.chain.CurrentBlock().Header(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number < 0 {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return b.chain.Config()
+}
+
+// newTestBackend creates a chain of 32 blocks, each block n containing two value
+// transfers paying n and 2n gwei gas price respectively.
+func newTestBackend(t *testing.T) *testBackend {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	db := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 32, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		for j := 1; j <= 2; j++ {
+			price := new(big.Int).Mul(big.NewInt(int64(j*(i+1))), big.NewInt(params.GWei))
+			tx, err := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{2}, big.NewInt(1), params.TxGas, price, nil), signer, key)
+			if err != nil {
+				t.Fatalf("failed to create tx: %v", err)
+			}
+			b.AddTx(tx)
+		}
+	})
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	chain, err := core.NewBlockChain(diskdb, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return &testBackend{chain: chain}
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.GWei))
+}
+
+// Tests that gas prices are suggested over the requested block window and at
+// the requested percentile.
+func TestSuggestPrice(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.chain.Stop()
+
+	oracle := NewOracle(backend, Config{Blocks: 5, Percentile: 60, Default: big.NewInt(1)})
+	tests := []struct {
+		blocks     int
+		percentile int
+		want       *big.Int
+	}{
+		{0, -1, gwei(30)},   // configured window of blocks 32..28
+		{10, 0, gwei(23)},   // cheapest of blocks 32..23
+		{10, 100, gwei(32)}, // priciest of blocks 32..23
+		{10, -1, gwei(28)},  // configured percentile of blocks 32..23
+		{0, 0, gwei(28)},    // configured window at custom percentile
+		{30, 0, gwei(23)},   // window capped at twice the configured one
+	}
+	for i, tt := range tests {
+		price, err := oracle.SuggestPriceWithOptions(context.Background(), tt.blocks, tt.percentile)
+		if err != nil {
+			t.Fatalf("test %d: failed to suggest price: %v", i, err)
+		}
+		if price.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: price mismatch: have %v, want %v", i, price, tt.want)
+		}
+	}
+	price, err := oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if price.Cmp(gwei(30)) != 0 {
+		t.Errorf("default price mismatch: have %v, want %v", price, gwei(30))
+	}
+}
+
+// countingBackend is a test backend tracking the number of blocks retrieved and
+// the peak number of concurrent block retrievals.
+type countingBackend struct {
+	*testBackend
+	fetched int32
+	active  int32
+	peak    int32
+}
+
+func (b *countingBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	atomic.AddInt32(&b.fetched, 1)
+	active := atomic.AddInt32(&b.active, 1)
+	defer atomic.AddInt32(&b.active, -1)
+
+	for peak := atomic.LoadInt32(&b.peak); active > peak; peak = atomic.LoadInt32(&b.peak) {
+		if atomic.CompareAndSwapInt32(&b.peak, peak, active) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return b.testBackend.BlockByNumber(ctx, number)
+}
+
+// Tests that gas price suggestions retrieve the blocks with a bounded number of
+// concurrent fetchers, sampling no more blocks than capped.
+func TestSuggestPriceConcurrency(t *testing.T) {
+	backend := &countingBackend{testBackend: newTestBackend(t)}
+	defer backend.chain.Stop()
+
+	oracle := NewOracle(backend, Config{Blocks: 10, Percentile: 60, Default: big.NewInt(1)})
+	price, err := oracle.SuggestPriceWithOptions(context.Background(), 1000, 0)
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if price.Cmp(gwei(13)) != 0 {
+		t.Errorf("price mismatch: have %v, want %v", price, gwei(13))
+	}
+	if fetched := atomic.LoadInt32(&backend.fetched); fetched != 20 {
+		t.Errorf("fetched block count mismatch: have %d, want %d", fetched, 20)
+	}
+	if peak := atomic.LoadInt32(&backend.peak); peak > maxBlockFetchers {
+		t.Errorf("concurrent fetchers exceeded: have %d, want at most %d", peak, maxBlockFetchers)
+	}
+}
+
+// Tests that the fee history reports the gas usage and the gas used weighted
+// reward percentiles of the requested block range.
+func TestFeeHistory(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.chain.Stop()
+
+	oracle := NewOracle(backend, Config{Blocks: 2, Percentile: 60, Default: big.NewInt(1)})
+	gasUsedRatio := float64(2*params.TxGas) / float64(backend.chain.CurrentBlock().GasLimit())
+
+	tests := []struct {
+		blocks      int
+		last        rpc.BlockNumber
+		percentiles []float64
+		oldest      uint64
+		count       int
+		fail        bool
+	}{
+		{4, rpc.LatestBlockNumber, []float64{0, 50, 100}, 29, 4, false},
+		{4, rpc.PendingBlockNumber, nil, 29, 4, false},
+		{4, 10, []float64{25, 75}, 7, 4, false},
+		{100, 5, []float64{50}, 0, 6, false},
+		{2000, rpc.LatestBlockNumber, nil, 0, 33, false},
+		{0, rpc.LatestBlockNumber, nil, 0, 0, false},
+		{4, 33, nil, 0, 0, true},
+		{4, rpc.LatestBlockNumber, []float64{50, 10}, 0, 0, true},
+		{4, rpc.LatestBlockNumber, []float64{101}, 0, 0, true},
+	}
+	for i, tt := range tests {
+		oldest, reward, ratios, err := oracle.FeeHistory(context.Background(), tt.blocks, tt.last, tt.percentiles)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: expected failure", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve fee history: %v", i, err)
+		}
+		if oldest.Uint64() != tt.oldest {
+			t.Errorf("test %d: oldest block mismatch: have %d, want %d", i, oldest, tt.oldest)
+		}
+		if len(ratios) != tt.count {
+			t.Fatalf("test %d: gas used ratio count mismatch: have %d, want %d", i, len(ratios), tt.count)
+		}
+		if (reward != nil) != (len(tt.percentiles) > 0) {
+			t.Fatalf("test %d: reward presence mismatch: have %v, want %v", i, reward != nil, len(tt.percentiles) > 0)
+		}
+		for j := range ratios {
+			number := tt.oldest + uint64(j)
+			if number > 0 && ratios[j] != gasUsedRatio {
+				t.Errorf("test %d, block %d: gas used ratio mismatch: have %f, want %f", i, number, ratios[j], gasUsedRatio)
+			}
+			if reward == nil {
+				continue
+			}
+			for k, p := range tt.percentiles {
+				want := new(big.Int)
+				switch {
+				case number == 0:
+				case p <= 50:
+					want = gwei(int64(number))
+				default:
+					want = gwei(int64(2 * number))
+				}
+				if reward[j][k].Cmp(want) != 0 {
+					t.Errorf("test %d, block %d: reward at percentile %v mismatch: have %v, want %v", i, number, p, reward[j][k], want)
+				}
+			}
+		}
+	}
+}
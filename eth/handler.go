@@ -58,8 +58,18 @@ var (
 	syncChallengeTimeout = 15 * time.Second // Time allowance for a node to reply to the sync progress challenge
 )
 
+// protocolError is an eth protocol violation committed by a remote peer.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
@@ -149,7 +159,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 	if atomic.LoadUint32(&manager.fastSync) == 1 {
 		stateBloom = trie.NewSyncBloom(uint64(cacheLimit), chaindb)
 	}
-	manager.downloader = downloader.New(manager.checkpointNumber, chaindb, stateBloom, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(manager.checkpointNumber, chaindb, stateBloom, manager.eventMux, blockchain, nil, manager.dropSyncPeer)
 
 	// Construct the fetcher (short sync)
 	validator := func(header *types.Header) error {
@@ -184,7 +194,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		}
 		return n, err
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.dropFetchPeer)
 
 	return manager, nil
 }
@@ -241,6 +251,29 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// dropSyncPeer removes a peer on behalf of the downloader, penalizing it at the
+// networking layer if the drop was caused by misbehaviour.
+func (pm *ProtocolManager) dropSyncPeer(id string, reason error) {
+	if violation, ok := downloader.DropViolation(reason); ok {
+		pm.penalizePeer(id, violation, reason.Error())
+	}
+	pm.removePeer(id)
+}
+
+// dropFetchPeer removes a peer on behalf of the fetcher, which only drops peers
+// delivering invalid blocks.
+func (pm *ProtocolManager) dropFetchPeer(id string) {
+	pm.penalizePeer(id, p2p.ViolationBadBlock, "invalid propagated block")
+	pm.removePeer(id)
+}
+
+// penalizePeer records a violation of a peer at the networking layer.
+func (pm *ProtocolManager) penalizePeer(id string, violation p2p.Violation, reason string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.Peer.Penalize(violation, reason)
+	}
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -355,6 +388,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Ethereum message handling failed", "err", err)
+			if perr, ok := err.(*protocolError); ok {
+				p.Peer.Penalize(p2p.ViolationProtocol, perr.msg)
+			}
 			return err
 		}
 	}
@@ -730,7 +766,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		for _, err := range pm.txpool.AddRemotes(txs) {
+			// Transactions which can never become valid are penalized, all other
+			// errors (e.g. nonce too low, underpriced) can occur in honest operation.
+			if err == core.ErrInvalidSender || err == core.ErrNegativeValue || err == core.ErrOversizedData {
+				p.Peer.Penalize(p2p.ViolationInvalidTx, err.Error())
+				break
+			}
+		}
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
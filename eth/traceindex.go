@@ -0,0 +1,301 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// traceIndexSectionSize is the number of blocks in a section of the internal
+	// transaction index. Blocks past the last indexed section are traced on
+	// demand, so it's kept small.
+	traceIndexSectionSize = 256
+
+	// traceIndexConfirms is the number of confirmations a block needs before it
+	// is indexed, keeping shallow reorgs off the index.
+	traceIndexConfirms = 64
+
+	// traceIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	traceIndexThrottling = 100 * time.Millisecond
+
+	// traceIndexReexec is the number of blocks the indexer is willing to reexecute
+	// to regenerate the state it resumes indexing from.
+	traceIndexReexec = uint64(16384)
+)
+
+// internalTx is a call, contract creation or self destruct made by a contract,
+// as stored in the trace index.
+type internalTx struct {
+	TxHash       common.Hash
+	TxIndex      uint64
+	TraceAddress []uint64
+	Type         uint8
+	From         common.Address
+	To           common.Address
+	Value        *big.Int
+	Gas          uint64
+	GasUsed      uint64
+	Error        string
+}
+
+// TraceIndexer implements a core.ChainIndexer, indexing the internal transactions
+// of the canonical chain, as well as the positions of all transactions touching
+// an account, by the accounts participating in them.
+type TraceIndexer struct {
+	eth   *Ethereum
+	debug *PrivateDebugAPI
+	batch ethdb.Batch
+
+	statedb *state.StateDB // State after the last processed block
+	root    common.Hash    // Root referenced in the state database, released when moving on
+	head    common.Hash    // Hash of the last processed block
+}
+
+// NewTraceIndexer returns a chain indexer that generates the internal transaction
+// index of the canonical chain.
+func NewTraceIndexer(eth *Ethereum) *core.ChainIndexer {
+	backend := &TraceIndexer{
+		eth:   eth,
+		debug: NewPrivateDebugAPI(eth),
+	}
+	table := rawdb.NewTable(eth.chainDb, string(rawdb.TraceIndexPrefix))
+
+	return core.NewChainIndexer(eth.chainDb, table, backend, traceIndexSectionSize, traceIndexConfirms, traceIndexThrottling, "traceindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section. The state
+// of the previous one is reused if it continues from the same block.
+func (t *TraceIndexer) Reset(ctx context.Context, section uint64, prevHead common.Hash) error {
+	if prevHead != t.head {
+		t.release()
+	}
+	t.batch = t.eth.chainDb.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, executing the block on top of the
+// state of its parent and adding its internal transactions to the index.
+func (t *TraceIndexer) Process(ctx context.Context, header *types.Header) error {
+	number, hash := header.Number.Uint64(), header.Hash()
+	if number == 0 {
+		t.head = hash
+		return nil
+	}
+	block := t.eth.blockchain.GetBlock(hash, number)
+	if block == nil {
+		return fmt.Errorf("block #%d [%x…] not found", number, hash[:4])
+	}
+	if t.statedb == nil {
+		parent := t.eth.blockchain.GetBlock(block.ParentHash(), number-1)
+		if parent == nil {
+			return fmt.Errorf("parent %x of block #%d not found", block.ParentHash(), number)
+		}
+		statedb, err := t.debug.regenerateStateDB(parent, traceIndexReexec)
+		if err != nil {
+			return err
+		}
+		t.statedb = statedb
+	}
+	txs, err := traceInternalTxs(t.eth.blockchain, block, t.statedb)
+	if err != nil {
+		t.release()
+		return err
+	}
+	// Persist the state changes so the next block can continue from them
+	root, err := t.statedb.Commit(t.eth.blockchain.Config().IsEIP158(block.Number()))
+	if err != nil {
+		t.release()
+		return err
+	}
+	if root != block.Root() {
+		t.release()
+		return fmt.Errorf("state root mismatch in block #%d: have %x, want %x", number, root, block.Root())
+	}
+	if err := t.statedb.Reset(root); err != nil {
+		t.release()
+		return err
+	}
+	triedb := t.statedb.Database().TrieDB()
+	triedb.Reference(root, common.Hash{})
+	if t.root != (common.Hash{}) {
+		triedb.Dereference(t.root)
+	}
+	t.root, t.head = root, hash
+
+	// Drop the entries of the block previously indexed at this height, if it has
+	// been reorged out since, then index the new one
+	if prev := rawdb.ReadTracedBlock(t.eth.chainDb, number); prev != nil && prev.Hash != hash {
+		for _, addr := range prev.Accounts {
+			rawdb.DeleteInternalTxs(t.batch, addr, number, prev.Hash)
+			rawdb.DeleteAccountTxs(t.batch, addr, number, prev.Hash)
+		}
+	}
+	traced := &rawdb.TracedBlock{Hash: hash}
+	for addr, list := range txs {
+		blob, err := rlp.EncodeToBytes(list)
+		if err != nil {
+			return err
+		}
+		rawdb.WriteInternalTxs(t.batch, addr, number, hash, blob)
+	}
+	for addr, indexes := range accountTxIndexes(t.eth.blockchain.Config(), block, txs) {
+		rawdb.WriteAccountTxs(t.batch, addr, number, hash, indexes)
+
+		// Internal transactions are also account transactions of their participants
+		traced.Accounts = append(traced.Accounts, addr)
+	}
+	sort.Slice(traced.Accounts, func(i, j int) bool {
+		return bytes.Compare(traced.Accounts[i][:], traced.Accounts[j][:]) < 0
+	})
+	rawdb.WriteTracedBlock(t.batch, number, traced)
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, flushing the index of the section
+// into the database.
+func (t *TraceIndexer) Commit() error {
+	return t.batch.Write()
+}
+
+// release drops the tracked state, forcing it to be regenerated for the next
+// processed block.
+func (t *TraceIndexer) release() {
+	if t.statedb != nil && t.root != (common.Hash{}) {
+		t.statedb.Database().TrieDB().Dereference(t.root)
+	}
+	t.statedb, t.root, t.head = nil, common.Hash{}, common.Hash{}
+}
+
+// traceInternalTxs executes a block on top of the given state the same way the
+// state processor does, and returns its internal transactions grouped by the
+// participating accounts.
+func traceInternalTxs(chain *core.BlockChain, block *types.Block, statedb *state.StateDB) (map[common.Address][]*internalTx, error) {
+	var (
+		config  = chain.Config()
+		header  = block.Header()
+		gp      = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+		index   = make(map[common.Address][]*internalTx)
+	)
+	if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	for i, tx := range block.Transactions() {
+		tracer := tracers.NewCallFrameTracer()
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if _, _, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{Debug: true, Tracer: tracer}); err != nil {
+			return nil, fmt.Errorf("processing transaction %#x in block %d failed: %v", tx.Hash(), block.NumberU64(), err)
+		}
+		if root := tracer.Frame(); root != nil {
+			for j, call := range root.Calls {
+				collectInternalTxs(index, tx.Hash(), uint64(i), []uint64{uint64(j)}, call)
+			}
+		}
+	}
+	chain.Engine().Finalize(chain, header, statedb, block.Transactions(), block.Uncles())
+	return index, nil
+}
+
+// accountTxIndexes returns the positions of the transactions of a block grouped
+// by the accounts they touch: their senders, recipients or created contracts and
+// the participants of their internal transactions.
+func accountTxIndexes(config *params.ChainConfig, block *types.Block, internal map[common.Address][]*internalTx) map[common.Address][]uint64 {
+	var (
+		signer = types.MakeSigner(config, block.Number())
+		seen   = make(map[common.Address]map[uint64]struct{})
+	)
+	mark := func(addr common.Address, index uint64) {
+		if seen[addr] == nil {
+			seen[addr] = make(map[uint64]struct{})
+		}
+		seen[addr][index] = struct{}{}
+	}
+	for i, tx := range block.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		mark(from, uint64(i))
+		if to := tx.To(); to != nil {
+			mark(*to, uint64(i))
+		} else {
+			mark(crypto.CreateAddress(from, tx.Nonce()), uint64(i))
+		}
+	}
+	for addr, txs := range internal {
+		for _, tx := range txs {
+			mark(addr, tx.TxIndex)
+		}
+	}
+	index := make(map[common.Address][]uint64, len(seen))
+	for addr, set := range seen {
+		indexes := make([]uint64, 0, len(set))
+		for i := range set {
+			indexes = append(indexes, i)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+		index[addr] = indexes
+	}
+	return index
+}
+
+// collectInternalTxs adds a call and all its subcalls to the index of the
+// accounts participating in them.
+func collectInternalTxs(index map[common.Address][]*internalTx, hash common.Hash, txIndex uint64, address []uint64, frame *tracers.CallFrame) {
+	itx := &internalTx{
+		TxHash:       hash,
+		TxIndex:      txIndex,
+		TraceAddress: append([]uint64{}, address...),
+		Type:         uint8(frame.Type),
+		From:         frame.From,
+		To:           frame.To,
+		Value:        frame.Value,
+		Gas:          frame.Gas,
+		GasUsed:      frame.GasUsed,
+	}
+	if itx.Value == nil {
+		itx.Value = new(big.Int)
+	}
+	if frame.Error != nil {
+		itx.Error = frame.Error.Error()
+	}
+	index[frame.From] = append(index[frame.From], itx)
+	if frame.To != frame.From {
+		index[frame.To] = append(index[frame.To], itx)
+	}
+	for i, call := range frame.Calls {
+		collectInternalTxs(index, hash, txIndex, append(address, uint64(i)), call)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the internal transactions of the chain are indexed, and that blocks
+// past the index are traced on demand.
+func TestTraceIndex(t *testing.T) {
+	var (
+		forwarder = common.HexToAddress("0xf0")
+		recipient = common.HexToAddress("0xbb")
+		blocks    = traceIndexSectionSize + traceIndexConfirms + 10
+	)
+	// Deploy a contract forwarding any received value to the recipient
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.CALLVALUE), byte(vm.PUSH20),
+	}
+	code = append(code, recipient.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:  {Balance: big.NewInt(1000000000000000000)},
+				forwarder: {Code: code, Balance: new(big.Int)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), forwarder, big.NewInt(int64(i+1)), 100000, big.NewInt(1), nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{chainDb: db, blockchain: blockchain}
+	eth.traceIndexer = NewTraceIndexer(eth)
+	eth.traceIndexer.Start(blockchain)
+	defer eth.traceIndexer.Close()
+
+	// Wait for the first section to be indexed
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := eth.traceIndexer.Sections(); sections == 1 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("trace index section not processed")
+		}
+	}
+	if entries := rawdb.ReadInternalTxs(db, recipient, 0, traceIndexSectionSize-1); len(entries) != traceIndexSectionSize-1 {
+		t.Fatalf("indexed block count mismatch: have %d, want %d", len(entries), traceIndexSectionSize-1)
+	}
+	// Check that the transactions are indexed by the sender, the called contract
+	// and the recipient of the internal transaction
+	for _, addr := range []common.Address{testBank, forwarder, recipient} {
+		entries := rawdb.ReadAccountTxs(db, addr, 0, traceIndexSectionSize-1)
+		if len(entries) != traceIndexSectionSize-1 {
+			t.Fatalf("account %x: indexed block count mismatch: have %d, want %d", addr, len(entries), traceIndexSectionSize-1)
+		}
+		for _, entry := range entries {
+			if len(entry.Indexes) != 1 || entry.Indexes[0] != 0 {
+				t.Fatalf("account %x, block #%d: transaction positions mismatch: have %v, want [0]", addr, entry.Number, entry.Indexes)
+			}
+		}
+	}
+	// Retrieve the internal transactions spanning both the indexed and unindexed
+	// parts of the chain
+	api := NewPrivateDebugAPI(eth)
+	from := rpc.BlockNumber(traceIndexSectionSize - 5)
+
+	txs, err := api.GetInternalTransactions(context.Background(), recipient, from, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to retrieve internal transactions: %v", err)
+	}
+	if want := blocks - int(from) + 1; len(txs) != want {
+		t.Fatalf("internal transaction count mismatch: have %d, want %d", len(txs), want)
+	}
+	for i, tx := range txs {
+		number := uint64(from) + uint64(i)
+		block := blockchain.GetBlockByNumber(number)
+
+		if uint64(tx.BlockNumber) != number || tx.BlockHash != block.Hash() || tx.TransactionHash != block.Transactions()[0].Hash() {
+			t.Errorf("tx %d: position mismatch: have #%d [%x] %x", i, tx.BlockNumber, tx.BlockHash, tx.TransactionHash)
+		}
+		if tx.Type != "CALL" || tx.From != forwarder || tx.To != recipient || tx.Value.ToInt().Uint64() != number || tx.Error != "" {
+			t.Errorf("tx %d: call mismatch: have %s %x->%x %v %q", i, tx.Type, tx.From, tx.To, tx.Value, tx.Error)
+		}
+	}
+	// Check that unrelated accounts have no internal transactions
+	if txs, err := api.GetInternalTransactions(context.Background(), testBank, 0, rpc.LatestBlockNumber); err != nil || len(txs) != 0 {
+		t.Fatalf("unrelated account results mismatch: have %d, %v", len(txs), err)
+	}
+}
+
+// Tests that re-indexing a section after a reorg drops the index entries of the
+// blocks which have been reorged out.
+func TestTraceIndexReorg(t *testing.T) {
+	var (
+		forwarder = common.HexToAddress("0xf0")
+		recipient = common.HexToAddress("0xbb")
+		blocks    = traceIndexSectionSize + traceIndexConfirms + 10
+		fork      = 100
+	)
+	// Deploy a contract forwarding any received value to the recipient
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.CALLVALUE), byte(vm.PUSH20),
+	}
+	code = append(code, recipient.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:  {Balance: big.NewInt(1000000000000000000)},
+				forwarder: {Code: code, Balance: new(big.Int)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	// Generate two chains forwarding different values, the second one forking
+	// off the first within its first section and overtaking it
+	generate := func(offset int64) func(int, *core.BlockGen) {
+		return func(i int, block *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), forwarder, big.NewInt(offset+int64(i)), 100000, big.NewInt(1), nil), signer, testBankKey)
+			block.AddTx(tx)
+		}
+	}
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, generate(1))
+	forked, _ := core.GenerateChain(gspec.Config, chain[fork-1], ethash.NewFaker(), db, blocks-fork+10, generate(1000))
+
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{chainDb: db, blockchain: blockchain}
+	eth.traceIndexer = NewTraceIndexer(eth)
+	eth.traceIndexer.Start(blockchain)
+	defer eth.traceIndexer.Close()
+
+	waitSection := func(head common.Hash) {
+		t.Helper()
+		for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+			if sections, _, sectionHead := eth.traceIndexer.Sections(); sections == 1 && sectionHead == head {
+				return
+			}
+			if time.Since(start) > 10*time.Second {
+				t.Fatalf("trace index section not processed")
+			}
+		}
+	}
+	waitSection(chain[traceIndexSectionSize-2].Hash())
+
+	// Reorg the chain and wait for the section to be indexed again
+	if _, err := blockchain.InsertChain(forked); err != nil {
+		t.Fatalf("failed to insert forked chain: %v", err)
+	}
+	waitSection(forked[traceIndexSectionSize-fork-2].Hash())
+
+	// Check that only the entries of the canonical blocks remain
+	last := uint64(traceIndexSectionSize - 1)
+	canonical := func(number uint64, hash common.Hash) bool {
+		return rawdb.ReadCanonicalHash(db, number) == hash
+	}
+	entries := rawdb.ReadInternalTxs(db, recipient, 0, last)
+	if len(entries) != int(last) {
+		t.Fatalf("internal transaction entry count mismatch: have %d, want %d", len(entries), last)
+	}
+	for _, entry := range entries {
+		if !canonical(entry.Number, entry.Hash) {
+			t.Fatalf("stale internal transaction entry of block #%d [%x]", entry.Number, entry.Hash)
+		}
+	}
+	for _, addr := range []common.Address{testBank, forwarder, recipient} {
+		entries := rawdb.ReadAccountTxs(db, addr, 0, last)
+		if len(entries) != int(last) {
+			t.Fatalf("account %x: entry count mismatch: have %d, want %d", addr, len(entries), last)
+		}
+		for _, entry := range entries {
+			if !canonical(entry.Number, entry.Hash) {
+				t.Fatalf("account %x: stale entry of block #%d [%x]", addr, entry.Number, entry.Hash)
+			}
+		}
+	}
+	if traced := rawdb.ReadTracedBlock(db, uint64(fork+1)); traced == nil || traced.Hash != forked[0].Hash() || len(traced.Accounts) != 3 {
+		t.Fatalf("traced block mismatch: have %+v", traced)
+	}
+}
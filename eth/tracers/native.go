@@ -0,0 +1,129 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ResultTracer is a vm.Tracer which assembles a JSON result from the traced
+// execution. Both the JavaScript and the native tracers implement it.
+type ResultTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the trace, or any error that occurred.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+// native contains the built in tracers implemented in Go. They produce the same
+// output as their JavaScript counterparts, without the cost of the JSVM.
+var native = map[string]func() ResultTracer{
+	"callTracer":     func() ResultTracer { return newCallTracer() },
+	"prestateTracer": func() ResultTracer { return newPrestateTracer() },
+	"4byteTracer":    func() ResultTracer { return newFourByteTracer() },
+}
+
+// NewTracer creates a tracer from a built in tracer name or a JavaScript snippet.
+// Built in tracers with a native implementation take precedence over the ones
+// written in JavaScript.
+func NewTracer(code string) (ResultTracer, error) {
+	if ctor, ok := native[code]; ok {
+		return ctor(), nil
+	}
+	tracer, err := New(code)
+	if err != nil {
+		return nil, err
+	}
+	return tracer, nil
+}
+
+// nativeTracer contains the interruption and error handling shared by the
+// native tracers.
+type nativeTracer struct {
+	err error // Error, if one has occurred
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *nativeTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// running reports whether tracing should go on. An interruption is recorded as
+// the error of the trace.
+func (t *nativeTracer) running() bool {
+	if t.err != nil {
+		return false
+	}
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.err = t.reason
+		return false
+	}
+	return true
+}
+
+// peekStack returns the nth-from-the-top element of the stack.
+func peekStack(stack *vm.Stack, n int) *big.Int {
+	data := stack.Data()
+	if len(data) <= n {
+		log.Warn("Tracer accessed out of bound stack", "size", len(data), "index", n)
+		return new(big.Int)
+	}
+	return data[len(data)-n-1]
+}
+
+// sliceMemory returns a copy of the requested range of memory, or nil if the
+// range is out of bounds.
+func sliceMemory(memory *vm.Memory, offset, size *big.Int) []byte {
+	end := new(big.Int).Add(offset, size)
+	if !end.IsInt64() || end.Int64() > int64(memory.Len()) {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return nil
+	}
+	return memory.Get(offset.Int64(), size.Int64())
+}
+
+// isPrecompiled reports whether the address is a precompiled contract active in
+// the traced EVM.
+func isPrecompiled(env *vm.EVM, addr common.Address) bool {
+	_, ok := env.Precompile(addr)
+	return ok
+}
+
+// hexInt formats a number the way the JavaScript tracers do, which keeps the
+// sign of negative values after the hex prefix.
+func hexInt(n int64) string {
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// hexBig is the big integer version of hexInt.
+func hexBig(n *big.Int) string {
+	return "0x" + n.Text(16)
+}
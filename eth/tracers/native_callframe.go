@@ -0,0 +1,255 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errCallFailed is the error of calls which failed without an error surfacing
+// from the EVM, e.g. because of an insufficient balance or a failed precompile.
+var errCallFailed = errors.New("internal failure")
+
+// CallFrame is a single call, contract creation or self destruct made during
+// the execution of a transaction.
+type CallFrame struct {
+	Type    vm.OpCode      // CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE, CREATE2 or SELFDESTRUCT
+	From    common.Address // Caller, or the destructed contract
+	To      common.Address // Callee, created contract or beneficiary of the destructed contract
+	Input   []byte         // Call data or init code
+	Output  []byte         // Return data or deployed code
+	Gas     uint64         // Gas made available to the call
+	GasUsed uint64         // Gas used by the call
+	Value   *big.Int       // Transferred value, nil for static calls
+	Error   error          // Error the call failed with, nil on success
+	Calls   []*CallFrame   // Calls made by this one, in execution order
+}
+
+// callFrameState is the tracking information of a call being executed.
+type callFrameState struct {
+	frame     *CallFrame
+	entered   bool   // Whether the callee is executing code
+	remaining uint64 // Gas left to the caller, not counting the callee allowance
+}
+
+// CallFrameTracer is a native tracer which assembles the tree of all the calls
+// made by a transaction.
+type CallFrameTracer struct {
+	root  *CallFrame
+	stack []*callFrameState // Frames by call depth, the top one may not have started
+	depth int               // Depth of the innermost frame entered, as reported by the EVM
+}
+
+// NewCallFrameTracer creates a call frame tracer.
+func NewCallFrameTracer() *CallFrameTracer {
+	return new(CallFrameTracer)
+}
+
+// Frame returns the outermost call of the traced transaction, or nil if nothing
+// was traced.
+func (t *CallFrameTracer) Frame() *CallFrame {
+	return t.root
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *CallFrameTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.root = &CallFrame{
+		Type:  vm.CALL,
+		From:  from,
+		To:    to,
+		Input: common.CopyBytes(input),
+		Gas:   gas,
+		Value: new(big.Int).Set(value),
+	}
+	if create {
+		t.root.Type = vm.CREATE
+	}
+	t.stack = []*callFrameState{{frame: t.root, entered: true}}
+	t.depth = 1
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *CallFrameTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	t.advance(env, depth, gas, stack)
+	if err != nil {
+		t.fail(depth, err)
+		return nil
+	}
+	parent := t.stack[len(t.stack)-1].frame
+
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		frame := &CallFrame{
+			Type:  op,
+			From:  contract.Address(),
+			To:    common.BigToAddress(peekStack(stack, 1)),
+			Input: sliceMemory(memory, peekStack(stack, 2+off), peekStack(stack, 3+off)),
+			Gas:   env.CallGas(),
+		}
+		switch op {
+		case vm.CALL, vm.CALLCODE:
+			frame.Value = new(big.Int).Set(peekStack(stack, 2))
+			if frame.Value.Sign() != 0 {
+				frame.Gas += params.CallStipend
+			}
+		case vm.DELEGATECALL:
+			frame.Value = new(big.Int).Set(contract.Value())
+		}
+		t.push(parent, frame, gas-cost)
+
+	case vm.CREATE, vm.CREATE2:
+		frame := &CallFrame{
+			Type:  op,
+			From:  contract.Address(),
+			Input: sliceMemory(memory, peekStack(stack, 1), peekStack(stack, 2)),
+			Value: new(big.Int).Set(peekStack(stack, 0)),
+			Gas:   gas - cost,
+		}
+		if env.ChainConfig().IsEIP150(env.BlockNumber) {
+			frame.Gas -= frame.Gas / 64
+		}
+		t.push(parent, frame, gas-cost-frame.Gas)
+
+	case vm.SELFDESTRUCT:
+		parent.Calls = append(parent.Calls, &CallFrame{
+			Type:  op,
+			From:  contract.Address(),
+			To:    common.BigToAddress(peekStack(stack, 0)),
+			Value: new(big.Int).Set(env.StateDB.GetBalance(contract.Address())),
+		})
+
+	case vm.RETURN, vm.REVERT:
+		parent.Output = sliceMemory(memory, peekStack(stack, 0), peekStack(stack, 1))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *CallFrameTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	t.advance(env, depth, gas, stack)
+	t.fail(depth, err)
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *CallFrameTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	t.root.Output = common.CopyBytes(output)
+	t.root.GasUsed = gasUsed
+	if err != nil && t.root.Error == nil {
+		t.root.Error = err
+	}
+	t.stack = t.stack[:1]
+	return nil
+}
+
+// CaptureEnter implements the FrameTracer interface, tracking the depth of the
+// call frames entered by the EVM.
+func (t *CallFrameTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	t.depth++
+	return nil
+}
+
+// CaptureExit implements the FrameTracer interface. Calls which didn't execute
+// any code, i.e. precompiles, have their output recorded here.
+func (t *CallFrameTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	if t.depth <= len(t.stack) {
+		if state := t.stack[t.depth-1]; !state.entered && err == nil {
+			state.frame.Output = common.CopyBytes(output)
+		}
+	}
+	t.depth--
+	return nil
+}
+
+// push adds a new call to the parent frame, which will be entered if the callee
+// has code to execute.
+func (t *CallFrameTracer) push(parent *CallFrame, frame *CallFrame, remaining uint64) {
+	parent.Calls = append(parent.Calls, frame)
+	t.stack = append(t.stack, &callFrameState{frame: frame, remaining: remaining})
+}
+
+// advance updates the call stack to the given depth of execution. Calls which
+// returned are finalized from the state of their caller.
+func (t *CallFrameTracer) advance(env *vm.EVM, depth int, gas uint64, stack *vm.Stack) {
+	if top := t.stack[len(t.stack)-1]; !top.entered && depth == len(t.stack) {
+		// The callee started executing, its exact allowance is known
+		top.entered = true
+		top.frame.Gas = gas
+		return
+	}
+	for len(t.stack) > depth && len(t.stack) > 1 {
+		state := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+
+		frame := state.frame
+		if returned := gas - state.remaining; gas >= state.remaining && returned <= frame.Gas {
+			frame.GasUsed = frame.Gas - returned
+		}
+		ret := peekStack(stack, 0)
+		if ret.Sign() == 0 {
+			if frame.Error == nil {
+				frame.Error = errCallFailed
+				if frame.GasUsed == frame.Gas {
+					frame.Error = vm.ErrOutOfGas
+				}
+				// Creations failing after returning didn't deploy their code
+				if frame.Type == vm.CREATE || frame.Type == vm.CREATE2 {
+					frame.Output = nil
+				}
+			}
+			continue
+		}
+		if frame.Type == vm.CREATE || frame.Type == vm.CREATE2 {
+			frame.To = common.BigToAddress(ret)
+			frame.Output = env.StateDB.GetCode(frame.To)
+		}
+	}
+}
+
+// fail records the error of the call executing at the given depth.
+func (t *CallFrameTracer) fail(depth int, err error) {
+	if depth > len(t.stack) || depth < 1 {
+		return
+	}
+	if frame := t.stack[depth-1].frame; frame.Error == nil {
+		frame.Error = err
+	}
+}
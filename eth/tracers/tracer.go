@@ -290,6 +290,8 @@ type Tracer struct {
 	contractWrapper *contractWrapper // Wrapper around the contract object
 	dbWrapper       *dbWrapper       // Wrapper around the VM environment
 
+	env *vm.EVM // EVM being traced, providing the active precompiles
+
 	pcValue     *uint   // Swappable pc value wrapped by a log accessor
 	gasValue    *uint   // Swappable gas value wrapped by a log accessor
 	costValue   *uint   // Swappable cost value wrapped by a log accessor
@@ -390,7 +392,14 @@ func New(code string) (*Tracer, error) {
 		return 1
 	})
 	tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
-		_, ok := vm.PrecompiledContractsIstanbul[common.BytesToAddress(popSlice(ctx))]
+		addr := common.BytesToAddress(popSlice(ctx))
+
+		var ok bool
+		if tracer.env != nil {
+			_, ok = tracer.env.Precompile(addr)
+		} else {
+			_, ok = vm.PrecompiledContractsIstanbul[addr]
+		}
 		ctx.PushBoolean(ok)
 		return 1
 	})
@@ -549,6 +558,7 @@ func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 		jst.memoryWrapper.memory = memory
 		jst.contractWrapper.contract = contract
 		jst.dbWrapper.db = env.StateDB
+		jst.env = env
 
 		*jst.pcValue = uint(pc)
 		*jst.gasValue = uint(gas)
@@ -17,9 +17,11 @@
 package tracers
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
@@ -34,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -168,10 +171,19 @@ func TestPrestateTracerCreate2(t *testing.T) {
 		Code:    []byte{},
 		Balance: big.NewInt(500000000000000),
 	}
+	for _, newTracer := range []func(string) (ResultTracer, error){
+		func(code string) (ResultTracer, error) { return New(code) },
+		NewTracer,
+	} {
+		testPrestateTracerCreate2(t, tx, signer, context, alloc, newTracer)
+	}
+}
+
+func testPrestateTracerCreate2(t *testing.T, tx *types.Transaction, signer types.Signer, context vm.Context, alloc core.GenesisAlloc, newTracer func(string) (ResultTracer, error)) {
 	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc)
 
 	// Create the tracer, the EVM environment and run it
-	tracer, err := New("prestateTracer")
+	tracer, err := newTracer("prestateTracer")
 	if err != nil {
 		t.Fatalf("failed to create call tracer: %v", err)
 	}
@@ -202,6 +214,16 @@ func TestPrestateTracerCreate2(t *testing.T) {
 // Iterates over all the input-output datasets in the tracer test harness and
 // runs the JavaScript tracers against them.
 func TestCallTracer(t *testing.T) {
+	testCallTracer(t, func() (ResultTracer, error) { return New("callTracer") })
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// runs the native tracers against them.
+func TestCallTracerNative(t *testing.T) {
+	testCallTracer(t, func() (ResultTracer, error) { return NewTracer("callTracer") })
+}
+
+func testCallTracer(t *testing.T, newTracer func() (ResultTracer, error)) {
 	files, err := ioutil.ReadDir("testdata")
 	if err != nil {
 		t.Fatalf("failed to retrieve tracer test suite: %v", err)
@@ -223,44 +245,12 @@ func TestCallTracer(t *testing.T) {
 			if err := json.Unmarshal(blob, test); err != nil {
 				t.Fatalf("failed to parse testcase: %v", err)
 			}
-			// Configure a blockchain with the given prestate
-			tx := new(types.Transaction)
-			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
-				t.Fatalf("failed to parse testcase input: %v", err)
-			}
-			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
-			origin, _ := signer.Sender(tx)
-
-			context := vm.Context{
-				CanTransfer: core.CanTransfer,
-				Transfer:    core.Transfer,
-				Origin:      origin,
-				Coinbase:    test.Context.Miner,
-				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
-				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
-				Difficulty:  (*big.Int)(test.Context.Difficulty),
-				GasLimit:    uint64(test.Context.GasLimit),
-				GasPrice:    tx.GasPrice(),
-			}
-			statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
-
-			// Create the tracer, the EVM environment and run it
-			tracer, err := New("callTracer")
+			// Create the tracer and run it against the test case
+			tracer, err := newTracer()
 			if err != nil {
 				t.Fatalf("failed to create call tracer: %v", err)
 			}
-			evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
-
-			msg, err := tx.AsMessage(signer)
-			if err != nil {
-				t.Fatalf("failed to prepare transaction for tracing: %v", err)
-			}
-			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-			if _, _, _, err = st.TransitionDb(); err != nil {
-				t.Fatalf("failed to execute transaction: %v", err)
-			}
-			// Retrieve the trace result and compare against the etalon
-			res, err := tracer.GetResult()
+			res, err := runTracerTest(test, tracer)
 			if err != nil {
 				t.Fatalf("failed to retrieve trace result: %v", err)
 			}
@@ -275,3 +265,196 @@ func TestCallTracer(t *testing.T) {
 		})
 	}
 }
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the native tracers produce the same output as the JavaScript ones.
+func TestNativeTracersMatchJS(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer"} {
+		for _, file := range files {
+			if !strings.HasPrefix(file.Name(), "call_tracer_") {
+				continue
+			}
+			name, file := name, file // capture range variables
+			t.Run(name+"/"+camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+				t.Parallel()
+
+				blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+				if err != nil {
+					t.Fatalf("failed to read testcase: %v", err)
+				}
+				test := new(callTracerTest)
+				if err := json.Unmarshal(blob, test); err != nil {
+					t.Fatalf("failed to parse testcase: %v", err)
+				}
+				results := make([]interface{}, 2)
+				for i, newTracer := range []func(string) (ResultTracer, error){
+					func(code string) (ResultTracer, error) { return New(code) },
+					NewTracer,
+				} {
+					tracer, err := newTracer(name)
+					if err != nil {
+						t.Fatalf("failed to create tracer: %v", err)
+					}
+					res, err := runTracerTest(test, tracer)
+					if err != nil {
+						t.Fatalf("failed to retrieve trace result: %v", err)
+					}
+					if err := json.Unmarshal(res, &results[i]); err != nil {
+						t.Fatalf("failed to unmarshal trace result: %v", err)
+					}
+					// Execution time naturally differs between the runs
+					if res, ok := results[i].(map[string]interface{}); ok && name == "callTracer" {
+						delete(res, "time")
+					}
+				}
+				if !reflect.DeepEqual(results[0], results[1]) {
+					t.Fatalf("trace mismatch: \njs     %v\nnative %v", results[0], results[1])
+				}
+			})
+		}
+	}
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the call frame tracer assembles the same call tree.
+func TestCallFrameTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			tracer := NewCallFrameTracer()
+			if err := executeTracerTest(test, tracer); err != nil {
+				t.Fatalf("failed to execute testcase: %v", err)
+			}
+			compareCallFrame(t, "root", tracer.Frame(), test.Result)
+		})
+	}
+}
+
+// Tests that the call frame tracer reports the output of precompiles, which
+// don't execute any code the tracer could capture the return data of.
+func TestCallFrameTracerPrecompile(t *testing.T) {
+	// Call the identity precompile with 0xdeadbeef as input
+	code := common.FromHex("63deadbeef600052600060006004601c600060045af100")
+
+	tracer := NewCallFrameTracer()
+	if _, _, err := runtime.Execute(code, nil, &runtime.Config{EVMConfig: vm.Config{Debug: true, Tracer: tracer}}); err != nil {
+		t.Fatalf("failed to execute code: %v", err)
+	}
+	root := tracer.Frame()
+	if len(root.Calls) != 1 {
+		t.Fatalf("call count mismatch: have %d, want 1", len(root.Calls))
+	}
+	call := root.Calls[0]
+	if call.To != common.BytesToAddress([]byte{4}) || call.Error != nil {
+		t.Fatalf("call mismatch: have %x (err %v), want identity precompile", call.To, call.Error)
+	}
+	if want := common.FromHex("deadbeef"); !bytes.Equal(call.Output, want) {
+		t.Fatalf("output mismatch: have %x, want %x", call.Output, want)
+	}
+}
+
+// compareCallFrame checks a call frame against the output of the JavaScript call
+// tracer. Gas values are only compared if reported by the JavaScript tracer, as
+// it can't determine them for calls not executing any code.
+func compareCallFrame(t *testing.T, path string, have *CallFrame, want *callTrace) {
+	t.Helper()
+
+	if have.Type.String() != want.Type {
+		t.Fatalf("%s: type mismatch: have %v, want %v", path, have.Type, want.Type)
+	}
+	if have.From != want.From || have.To != want.To {
+		t.Fatalf("%s: address mismatch: have %x->%x, want %x->%x", path, have.From, have.To, want.From, want.To)
+	}
+	if !bytes.Equal(have.Input, want.Input) {
+		t.Fatalf("%s: input mismatch: have %x, want %x", path, have.Input, want.Input)
+	}
+	if (have.Error != nil) != (want.Error != "") {
+		t.Fatalf("%s: error mismatch: have %v, want %v", path, have.Error, want.Error)
+	}
+	if have.Error == nil && !bytes.Equal(have.Output, want.Output) {
+		t.Fatalf("%s: output mismatch: have %x, want %x", path, have.Output, want.Output)
+	}
+	if want.Gas != nil && have.Gas != uint64(*want.Gas) {
+		t.Fatalf("%s: gas mismatch: have %d, want %d", path, have.Gas, *want.Gas)
+	}
+	if want.GasUsed != nil && have.GasUsed != uint64(*want.GasUsed) {
+		t.Fatalf("%s: gas used mismatch: have %d, want %d", path, have.GasUsed, *want.GasUsed)
+	}
+	if want.Value != nil && (have.Value == nil || have.Value.Cmp(want.Value.ToInt()) != 0) {
+		t.Fatalf("%s: value mismatch: have %v, want %v", path, have.Value, want.Value)
+	}
+	if len(have.Calls) != len(want.Calls) {
+		t.Fatalf("%s: call count mismatch: have %d, want %d", path, len(have.Calls), len(want.Calls))
+	}
+	for i := range have.Calls {
+		compareCallFrame(t, fmt.Sprintf("%s/%d", path, i), have.Calls[i], &want.Calls[i])
+	}
+}
+
+// runTracerTest executes the transaction of a tracer test case on top of its
+// prestate and returns the result of the tracer.
+func runTracerTest(test *callTracerTest, tracer ResultTracer) (json.RawMessage, error) {
+	if err := executeTracerTest(test, tracer); err != nil {
+		return nil, err
+	}
+	return tracer.GetResult()
+}
+
+// executeTracerTest executes the transaction of a tracer test case on top of
+// its prestate with the given tracer attached.
+func executeTracerTest(test *callTracerTest, tracer vm.Tracer) error {
+	// Configure a blockchain with the given prestate
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		return fmt.Errorf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
+
+	// Create the EVM environment and run the tracer
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		return fmt.Errorf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		return fmt.Errorf("failed to execute transaction: %v", err)
+	}
+	return nil
+}
@@ -22,19 +22,25 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Verify that Client implements the ethereum interfaces.
@@ -166,6 +172,17 @@ var (
 	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
 	testBalance = big.NewInt(2e10)
+
+	// revertAddr holds a contract reverting with Error("foo") if called with
+	// value, otherwise incrementing a counter and returning its new value.
+	revertAddr = common.HexToAddress("0xc0")
+	revertCode = common.FromHex("3415605c577f08c379a000000000000000000000000000000000000000000000000000000000600052602060045260036024527f666f6f000000000000000000000000000000000000000000000000000000000060445260646000fd5b6000546001018060005560005260206000a060206000f3")
+
+	// logCode is the init code of a contract emitting a log with 0x2a as data,
+	// deploying no code. It's included in the first block of the test chain,
+	// followed by a transfer. Both are free to keep the balance of testAddr.
+	logCode      = common.FromHex("602a60005260206000a000")
+	transferAddr = common.HexToAddress("0xdead")
 )
 
 func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
@@ -196,14 +213,23 @@ func generateTestChain() (*core.Genesis, []*types.Block) {
 	db := rawdb.NewMemoryDatabase()
 	config := params.AllEthashProtocolChanges
 	genesis := &core.Genesis{
-		Config:    config,
-		Alloc:     core.GenesisAlloc{testAddr: {Balance: testBalance}},
+		Config: config,
+		Alloc: core.GenesisAlloc{
+			testAddr:   {Balance: testBalance},
+			revertAddr: {Code: revertCode, Balance: new(big.Int)},
+		},
 		ExtraData: []byte("test genesis"),
 		Timestamp: 9000,
 	}
+	signer := types.NewEIP155Signer(config.ChainID)
 	generate := func(i int, g *core.BlockGen) {
 		g.OffsetTime(5)
 		g.SetExtra([]byte("test"))
+
+		create, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), 100000, new(big.Int), logCode), signer, testKey)
+		g.AddTx(create)
+		transfer, _ := types.SignTx(types.NewTransaction(1, transferAddr, new(big.Int), params.TxGas, new(big.Int), nil), signer, testKey)
+		g.AddTx(transfer)
 	}
 	gblock := genesis.ToBlock(db)
 	engine := ethash.NewFaker()
@@ -302,6 +328,134 @@ func TestBalanceAt(t *testing.T) {
 	}
 }
 
+func TestBalanceAtHash(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+
+	tests := map[string]struct {
+		account common.Address
+		block   common.Hash
+		want    *big.Int
+		wantErr error
+	}{
+		"valid_account": {
+			account: testAddr,
+			block:   chain[1].Hash(),
+			want:    testBalance,
+		},
+		"non_existent_account": {
+			account: common.Address{1},
+			block:   chain[1].Hash(),
+			want:    big.NewInt(0),
+		},
+		"unknown_block": {
+			account: testAddr,
+			block:   common.Hash{1},
+			want:    big.NewInt(0),
+			wantErr: errors.New("header for hash not found"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ec := NewClient(client)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			got, err := ec.BalanceAtHash(ctx, tt.account, tt.block)
+			if tt.wantErr != nil && (err == nil || err.Error() != tt.wantErr.Error()) {
+				t.Fatalf("BalanceAtHash(%x, %x) error = %q, want %q", tt.account, tt.block, err, tt.wantErr)
+			}
+			if got.Cmp(tt.want) != 0 {
+				t.Fatalf("BalanceAtHash(%x, %x) = %v, want %v", tt.account, tt.block, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockReceipts(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+
+	tests := map[string]struct {
+		block   rpc.BlockNumberOrHash
+		wantErr error
+	}{
+		"by_number": {
+			block: rpc.BlockNumberOrHashWithNumber(1),
+		},
+		"by_hash": {
+			block: rpc.BlockNumberOrHashWithHash(chain[1].Hash(), true),
+		},
+		"unknown_number": {
+			block:   rpc.BlockNumberOrHashWithNumber(100),
+			wantErr: ethereum.NotFound,
+		},
+		"unknown_hash": {
+			block:   rpc.BlockNumberOrHashWithHash(common.Hash{1}, false),
+			wantErr: errors.New("block for hash not found"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ec := NewClient(client)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			receipts, err := ec.BlockReceipts(ctx, tt.block)
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Fatalf("BlockReceipts error = %q, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BlockReceipts error = %q", err)
+			}
+			txs := chain[1].Transactions()
+			if len(receipts) != len(txs) {
+				t.Fatalf("BlockReceipts returned %d receipts, want %d", len(receipts), len(txs))
+			}
+			var cumulative uint64
+			for i, receipt := range receipts {
+				if receipt.TxHash != txs[i].Hash() || receipt.BlockHash != chain[1].Hash() || receipt.TransactionIndex != uint(i) {
+					t.Fatalf("receipt %d: position mismatch: have tx %x block %x index %d", i, receipt.TxHash, receipt.BlockHash, receipt.TransactionIndex)
+				}
+				if receipt.Status != types.ReceiptStatusSuccessful {
+					t.Fatalf("receipt %d: status mismatch: have %d", i, receipt.Status)
+				}
+				cumulative += receipt.GasUsed
+				if receipt.CumulativeGasUsed != cumulative {
+					t.Fatalf("receipt %d: cumulative gas mismatch: have %d, want %d", i, receipt.CumulativeGasUsed, cumulative)
+				}
+			}
+			// The contract creation emitted a log, the transfer didn't
+			create, transfer := receipts[0], receipts[1]
+			if want := crypto.CreateAddress(testAddr, 0); create.ContractAddress != want {
+				t.Fatalf("contract address mismatch: have %x, want %x", create.ContractAddress, want)
+			}
+			if create.GasUsed <= params.TxGasContractCreation {
+				t.Fatalf("contract creation gas too low: %d", create.GasUsed)
+			}
+			if len(create.Logs) != 1 {
+				t.Fatalf("contract creation log count mismatch: have %d, want 1", len(create.Logs))
+			}
+			if log := create.Logs[0]; log.Address != create.ContractAddress || log.TxHash != txs[0].Hash() || log.BlockHash != chain[1].Hash() || new(big.Int).SetBytes(log.Data).Uint64() != 0x2a {
+				t.Fatalf("contract creation log mismatch: %+v", log)
+			}
+			if transfer.ContractAddress != (common.Address{}) || len(transfer.Logs) != 0 || transfer.GasUsed != params.TxGas {
+				t.Fatalf("transfer receipt mismatch: contract %x, logs %d, gas %d", transfer.ContractAddress, len(transfer.Logs), transfer.GasUsed)
+			}
+			if cumulative != chain[1].GasUsed() {
+				t.Fatalf("block gas mismatch: have %d, want %d", cumulative, chain[1].GasUsed())
+			}
+		})
+	}
+}
+
 func TestTransactionInBlockInterrupted(t *testing.T) {
 	backend, _ := newTestBackend(t)
 	client, _ := backend.Attach()
@@ -335,3 +489,151 @@ func TestChainID(t *testing.T) {
 		t.Fatalf("ChainID returned wrong number: %+v", id)
 	}
 }
+
+func TestCallContractRevert(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	// Successful calls return the result
+	res, err := ec.CallContract(context.Background(), ethereum.CallMsg{To: &revertAddr}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if new(big.Int).SetBytes(res).Uint64() != 1 {
+		t.Fatalf("call result mismatch: have %x, want 1", res)
+	}
+	// Reverted calls and estimations return the revert reason and data
+	want := "0x08c379a000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000003666f6f0000000000000000000000000000000000000000000000000000000000"
+
+	_, callErr := ec.CallContract(context.Background(), ethereum.CallMsg{From: testAddr, To: &revertAddr, Value: big.NewInt(1)}, nil)
+	_, estimateErr := ec.EstimateGas(context.Background(), ethereum.CallMsg{From: testAddr, To: &revertAddr, Value: big.NewInt(1)})
+
+	for name, err := range map[string]error{"call": callErr, "estimate": estimateErr} {
+		if err == nil {
+			t.Errorf("%s: expected revert error", name)
+			continue
+		}
+		if err.Error() != "execution reverted: foo" {
+			t.Errorf("%s: error message mismatch: have %q, want %q", name, err, "execution reverted: foo")
+		}
+		if e, ok := err.(rpc.Error); !ok || e.ErrorCode() != 3 {
+			t.Errorf("%s: error code mismatch: have %v", name, err)
+		}
+		if e, ok := err.(rpc.DataError); !ok || e.ErrorData() != want {
+			t.Errorf("%s: error data mismatch: have %v, want %v", name, err, want)
+		}
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	// The counter contract reads and writes its only storage slot
+	list, gas, errStr, err := ec.CreateAccessList(context.Background(), ethereum.CallMsg{From: testAddr, To: &revertAddr}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []vm.AccessTuple{
+		{Address: revertAddr, StorageKeys: []common.Hash{{}}},
+		{Address: testAddr, StorageKeys: []common.Hash{}},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("access list mismatch:\nhave %v\nwant %v", list, want)
+	}
+	if gas <= params.TxGas || errStr != "" {
+		t.Errorf("call outcome mismatch: have gas %d, error %q", gas, errStr)
+	}
+	// Reverted calls report their error along with the accesses made
+	list, _, errStr, err = ec.CreateAccessList(context.Background(), ethereum.CallMsg{From: testAddr, To: &revertAddr, Value: big.NewInt(1)}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errStr != "execution reverted: foo" {
+		t.Errorf("error mismatch: have %q, want %q", errStr, "execution reverted: foo")
+	}
+	if len(list) != 2 {
+		t.Errorf("access list length mismatch: have %d, want 2", len(list))
+	}
+}
+
+func TestCallBundle(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	nonce, err := ec.NonceAt(context.Background(), testAddr, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve nonce: %v", err)
+	}
+	signer := types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+	signTx := func(nonce uint64) string {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, revertAddr, new(big.Int), 100000, big.NewInt(1), nil), signer, testKey)
+		blob, _ := rlp.EncodeToBytes(tx)
+		return hexutil.Encode(blob)
+	}
+	// Message calls are made from a separate account funded by a state override,
+	// as they increment the nonce of their sender too
+	caller := common.HexToAddress("0xca11e7")
+	call := map[string]interface{}{"from": caller, "to": revertAddr}
+	items := []interface{}{
+		call,          // Increments the counter to 1
+		signTx(nonce), // Increments the counter to 2
+		map[string]interface{}{"from": caller, "to": revertAddr, "value": "0x1"}, // Reverts
+		signTx(nonce + 5), // Nonce gap, rejected
+		map[string]interface{}{"from": caller, "to": revertAddr, "gas": "0x3b9aca00"}, // Exceeds the block gas limit
+		call, // Increments the counter to 3
+	}
+	config := map[string]interface{}{
+		"stateOverrides": map[common.Address]interface{}{caller: map[string]interface{}{"balance": "0x1"}},
+		"tracer":         "callTracer",
+	}
+	var results []*ethapi.BundleResult
+	if err := client.Call(&results, "eth_callBundle", items, "latest", config); err != nil {
+		t.Fatalf("eth_callBundle failed: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(items))
+	}
+	// Successful items see the state changes of the previous ones
+	for i, want := range map[int]uint64{0: 1, 1: 2, 5: 3} {
+		res := results[i]
+		if res.Failed || res.Error != "" {
+			t.Errorf("item %d: unexpected failure: %q", i, res.Error)
+		}
+		if have := new(big.Int).SetBytes(res.ReturnValue).Uint64(); have != want {
+			t.Errorf("item %d: counter mismatch: have %d, want %d", i, have, want)
+		}
+		if len(res.Logs) != 1 {
+			t.Errorf("item %d: log count mismatch: have %d, want 1", i, len(res.Logs))
+		}
+		if res.GasUsed <= hexutil.Uint64(params.TxGas) {
+			t.Errorf("item %d: gas used too low: %d", i, res.GasUsed)
+		}
+		if !strings.Contains(string(res.Trace), `"type":"CALL"`) {
+			t.Errorf("item %d: call trace missing: %s", i, res.Trace)
+		}
+	}
+	if results[0].TxHash != nil || results[1].TxHash == nil {
+		t.Errorf("transaction hash mismatch: call %v, transaction %v", results[0].TxHash, results[1].TxHash)
+	}
+	// Reverting items report the reason, invalid items are rejected without any
+	// effect, including on the gas left in the block
+	if res := results[2]; !res.Failed || res.RevertReason != "foo" {
+		t.Errorf("reverting item: have failed %v, reason %q", res.Failed, res.RevertReason)
+	}
+	if res := results[3]; res.Error != core.ErrNonceTooHigh.Error() || res.GasUsed != 0 {
+		t.Errorf("nonce gapped item: have error %q, gas %d", res.Error, res.GasUsed)
+	}
+	if res := results[4]; res.Error != core.ErrGasLimitReached.Error() {
+		t.Errorf("gas limit exceeding item: have error %q, want %q", res.Error, core.ErrGasLimitReached)
+	}
+}
@@ -32,7 +32,9 @@ import (
 // This handler returns GraphiQL when requested.
 //
 // For more information, see https://github.com/graphql/graphiql.
-type GraphiQL struct{}
+type GraphiQL struct {
+	path string // URL path of the queried GraphQL API, "/graphql" if empty
+}
 
 func respond(w http.ResponseWriter, body []byte, code int) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -53,7 +55,11 @@ func (h GraphiQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Write(graphiql)
+	if h.path == "" {
+		w.Write(graphiql)
+		return
+	}
+	w.Write(bytes.Replace(graphiql, []byte(`fetch("/graphql"`), []byte(`fetch("`+h.path+`"`), 1))
 }
 
 var graphiql = []byte(`
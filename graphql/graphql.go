@@ -20,6 +20,8 @@ package graphql
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -28,28 +30,33 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+const (
+	defaultTxPageSize = 100  // Number of transactions returned per history page by default
+	maxTxPageSize     = 1000 // Maximum number of transactions returned per history page
+)
+
 var (
 	errOnlyOnMainChain = errors.New("this operation is only available for blocks on the canonical chain")
 	errBlockInvariant  = errors.New("block objects must be instantiated with at least one of num or hash")
+	errNoTxIndex       = errors.New("transaction history is not available without the transaction index")
 )
 
 // Account represents an Ethereum account at a particular block.
 type Account struct {
-	backend     ethapi.Backend
-	address     common.Address
-	blockNumber rpc.BlockNumber
+	backend       ethapi.Backend
+	address       common.Address
+	blockNrOrHash rpc.BlockNumberOrHash
 }
 
 // getState fetches the StateDB object for an account.
 func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
-	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	state, _, err := a.backend.StateAndHeaderByNumberOrHash(ctx, a.blockNrOrHash)
 	return state, err
 }
 
@@ -89,6 +96,137 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+func (a *Account) StorageSlots(ctx context.Context, args struct{ Slots []common.Hash }) ([]common.Hash, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]common.Hash, len(args.Slots))
+	for i, slot := range args.Slots {
+		values[i] = state.GetState(a.address, slot)
+	}
+	return values, nil
+}
+
+// accountTxIndexer is implemented by backends maintaining an index of the
+// transactions touching each account.
+type accountTxIndexer interface {
+	// AccountTxIndexed returns the number of blocks covered by the index, or
+	// false if the index is disabled.
+	AccountTxIndexed() (uint64, bool)
+}
+
+// TransactionPage is a page of the transaction history of an account.
+type TransactionPage struct {
+	transactions []*Transaction
+	cursor       *string
+}
+
+func (p *TransactionPage) Transactions(ctx context.Context) []*Transaction {
+	return p.transactions
+}
+
+func (p *TransactionPage) Cursor(ctx context.Context) *string {
+	return p.cursor
+}
+
+// Transactions returns a page of the transactions touching the account, either
+// directly or through internal transactions, within the indexed part of the
+// chain. Pages are continued after the position encoded in the cursor.
+func (a *Account) Transactions(ctx context.Context, args struct {
+	FromBlock *hexutil.Uint64
+	ToBlock   *hexutil.Uint64
+	First     *int32
+	After     *string
+}) (*TransactionPage, error) {
+	var (
+		indexed uint64
+		enabled bool
+	)
+	if indexer, ok := a.backend.(accountTxIndexer); ok {
+		indexed, enabled = indexer.AccountTxIndexed()
+	}
+	if !enabled {
+		return nil, errNoTxIndex
+	}
+	limit := defaultTxPageSize
+	if args.First != nil {
+		if *args.First < 1 || *args.First > maxTxPageSize {
+			return nil, fmt.Errorf("page size must be between 1 and %d", maxTxPageSize)
+		}
+		limit = int(*args.First)
+	}
+	// Resolve the block range, only the indexed part of the chain is searched
+	var from, to uint64
+	if args.FromBlock != nil {
+		from = uint64(*args.FromBlock)
+	}
+	if indexed == 0 {
+		return &TransactionPage{transactions: []*Transaction{}}, nil
+	}
+	to = indexed - 1
+	if args.ToBlock != nil && uint64(*args.ToBlock) < to {
+		to = uint64(*args.ToBlock)
+	}
+	var afterNumber, afterIndex uint64
+	if args.After != nil {
+		if _, err := fmt.Sscanf(*args.After, "%d:%d", &afterNumber, &afterIndex); err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", *args.After)
+		}
+		if afterNumber > from {
+			from = afterNumber
+		}
+	}
+	page := &TransactionPage{transactions: []*Transaction{}}
+	if from > to {
+		return page, nil
+	}
+	// Walk the index from the cursor block, stopping as soon as a transaction past
+	// the page is found, so deep pages don't load the entire block range
+	var (
+		db  = a.backend.ChainDb()
+		err error
+	)
+	rawdb.IterateAccountTxs(db, a.address, from, to, func(entry rawdb.AccountTxs) bool {
+		// Skip any entries of blocks which have been reorged out
+		if rawdb.ReadCanonicalHash(db, entry.Number) != entry.Hash {
+			return true
+		}
+		num := rpc.BlockNumber(entry.Number)
+		block := &Block{
+			backend:   a.backend,
+			num:       &num,
+			hash:      entry.Hash,
+			canonical: isCanonical,
+		}
+		for _, index := range entry.Indexes {
+			if args.After != nil && (entry.Number < afterNumber || (entry.Number == afterNumber && index <= afterIndex)) {
+				continue
+			}
+			if len(page.transactions) == limit {
+				last := page.transactions[limit-1]
+				cursor := fmt.Sprintf("%d:%d", uint64(*last.block.num), last.index)
+				page.cursor = &cursor
+				return false
+			}
+			var tx *Transaction
+			if tx, err = block.TransactionAt(ctx, struct{ Index int32 }{int32(index)}); err != nil {
+				return false
+			}
+			if tx == nil {
+				err = fmt.Errorf("transaction #%d of block #%d not found", index, entry.Number)
+				return false
+			}
+			page.transactions = append(page.transactions, tx)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -102,9 +240,9 @@ func (l *Log) Transaction(ctx context.Context) *Transaction {
 
 func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
 	return &Account{
-		backend:     l.backend,
-		address:     l.log.Address,
-		blockNumber: args.Number(),
+		backend:       l.backend,
+		address:       l.log.Address,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}
 }
 
@@ -203,9 +341,9 @@ func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, e
 		return nil, nil
 	}
 	return &Account{
-		backend:     t.backend,
-		address:     *to,
-		blockNumber: args.Number(),
+		backend:       t.backend,
+		address:       *to,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -221,9 +359,9 @@ func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account,
 	from, _ := types.Sender(signer, tx)
 
 	return &Account{
-		backend:     t.backend,
-		address:     from,
-		blockNumber: args.Number(),
+		backend:       t.backend,
+		address:       from,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -293,9 +431,9 @@ func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs)
 		return nil, err
 	}
 	return &Account{
-		backend:     t.backend,
-		address:     receipt.ContractAddress,
-		blockNumber: args.Number(),
+		backend:       t.backend,
+		address:       receipt.ContractAddress,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -315,6 +453,55 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	return &ret, nil
 }
 
+// Receipt represents the receipt of a transaction included in a block.
+type Receipt struct {
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.Status)
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		backend:       r.transaction.backend,
+		address:       r.receipt.ContractAddress,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
+	}
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     r.transaction.backend,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(r.receipt.Bloom.Bytes())
+}
+
 type BlockType int
 
 const (
@@ -621,9 +808,9 @@ func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, erro
 		return nil, err
 	}
 	return &Account{
-		backend:     b.backend,
-		address:     header.Coinbase,
-		blockNumber: args.Number(),
+		backend:       b.backend,
+		address:       header.Coinbase,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -673,6 +860,25 @@ func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (
 	}, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	txs, err := b.Transactions(ctx)
+	if err != nil || txs == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(*txs) {
+		return nil, errors.New("receipts of block unavailable")
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{transaction: (*txs)[i], receipt: receipt})
+	}
+	return &ret, nil
+}
+
 func (b *Block) OmmerAt(ctx context.Context, args struct{ Index int32 }) (*Block, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -768,9 +974,9 @@ func (b *Block) Account(ctx context.Context, args struct {
 		}
 	}
 	return &Account{
-		backend:     b.backend,
-		address:     args.Address,
-		blockNumber: *b.num,
+		backend:       b.backend,
+		address:       args.Address,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(*b.num),
 	}, nil
 }
 
@@ -817,16 +1023,19 @@ func (b *Block) Call(ctx context.Context, args struct {
 			return nil, err
 		}
 	}
-	result, gas, failed, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.num, nil, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
+	result, err := ethapi.DoCall(ctx, b.backend, args.Data, rpc.BlockNumberOrHashWithNumber(*b.num), nil, nil, 5*time.Second, b.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
 	status := hexutil.Uint64(1)
-	if failed {
+	if result.Failed() {
 		status = 0
 	}
 	return &CallResult{
-		data:    hexutil.Bytes(result),
-		gasUsed: hexutil.Uint64(gas),
+		data:    hexutil.Bytes(result.ReturnData),
+		gasUsed: hexutil.Uint64(result.UsedGas),
 		status:  status,
-	}, err
+	}, nil
 }
 
 func (b *Block) EstimateGas(ctx context.Context, args struct {
@@ -876,25 +1085,28 @@ func (p *Pending) Account(ctx context.Context, args struct {
 	Address common.Address
 }) *Account {
 	return &Account{
-		backend:     p.backend,
-		address:     args.Address,
-		blockNumber: rpc.PendingBlockNumber,
+		backend:       p.backend,
+		address:       args.Address,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber),
 	}
 }
 
 func (p *Pending) Call(ctx context.Context, args struct {
 	Data ethapi.CallArgs
 }) (*CallResult, error) {
-	result, gas, failed, err := ethapi.DoCall(ctx, p.backend, args.Data, rpc.PendingBlockNumber, nil, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	result, err := ethapi.DoCall(ctx, p.backend, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), nil, nil, 5*time.Second, p.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
 	status := hexutil.Uint64(1)
-	if failed {
+	if result.Failed() {
 		status = 0
 	}
 	return &CallResult{
-		data:    hexutil.Bytes(result),
-		gasUsed: hexutil.Uint64(gas),
+		data:    hexutil.Bytes(result.ReturnData),
+		gasUsed: hexutil.Uint64(result.UsedGas),
 		status:  status,
-	}, err
+	}, nil
 }
 
 func (p *Pending) EstimateGas(ctx context.Context, args struct {
@@ -973,6 +1185,53 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	return ret, nil
 }
 
+func (r *Resolver) Account(ctx context.Context, args struct {
+	Address   common.Address
+	Block     *hexutil.Uint64
+	BlockHash *common.Hash
+}) (*Account, error) {
+	blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	switch {
+	case args.Block != nil && args.BlockHash != nil:
+		return nil, errors.New("only one of block and blockHash may be specified")
+	case args.Block != nil:
+		blockNrOrHash = rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(*args.Block))
+	case args.BlockHash != nil:
+		blockNrOrHash = rpc.BlockNumberOrHashWithHash(*args.BlockHash, false)
+	}
+	return &Account{
+		backend:       r.backend,
+		address:       args.Address,
+		blockNrOrHash: blockNrOrHash,
+	}, nil
+}
+
+func (r *Resolver) Call(ctx context.Context, args struct {
+	Data  ethapi.CallArgs
+	Block *hexutil.Uint64
+}) (*CallResult, error) {
+	num := BlockNumberArgs{Block: args.Block}.Number()
+	block := &Block{
+		backend:   r.backend,
+		num:       &num,
+		canonical: isCanonical,
+	}
+	return block.Call(ctx, struct{ Data ethapi.CallArgs }{args.Data})
+}
+
+func (r *Resolver) EstimateGas(ctx context.Context, args struct {
+	Data  ethapi.CallArgs
+	Block *hexutil.Uint64
+}) (hexutil.Uint64, error) {
+	num := BlockNumberArgs{Block: args.Block}.Number()
+	block := &Block{
+		backend:   r.backend,
+		num:       &num,
+		canonical: isCanonical,
+	}
+	return block.EstimateGas(ctx, struct{ Data ethapi.CallArgs }{args.Data})
+}
+
 func (r *Resolver) Pending(ctx context.Context) *Pending {
 	return &Pending{r.backend}
 }
@@ -1044,9 +1303,79 @@ func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria
 	return runFilter(ctx, r.backend, filter)
 }
 
-func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
-	price, err := r.backend.SuggestPrice(ctx)
-	return hexutil.Big(*price), err
+func (r *Resolver) GasPrice(ctx context.Context, args struct {
+	Blocks     *int32
+	Percentile *int32
+}) (hexutil.Big, error) {
+	var (
+		price *big.Int
+		err   error
+	)
+	if args.Blocks == nil && args.Percentile == nil {
+		price, err = r.backend.SuggestPrice(ctx)
+	} else {
+		blocks, percentile := 0, -1 // use the oracle's configuration by default
+		if args.Blocks != nil {
+			blocks = int(*args.Blocks)
+		}
+		if args.Percentile != nil {
+			percentile = int(*args.Percentile)
+		}
+		price, err = r.backend.SuggestPriceWithOptions(ctx, blocks, percentile)
+	}
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*price), nil
+}
+
+// FeeHistory represents the fee history of a range of blocks.
+type FeeHistory struct {
+	oldest       *big.Int
+	reward       [][]*big.Int
+	gasUsedRatio []float64
+}
+
+func (f *FeeHistory) OldestBlock(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(f.oldest.Uint64())
+}
+
+func (f *FeeHistory) Reward(ctx context.Context) *[][]hexutil.Big {
+	if f.reward == nil {
+		return nil
+	}
+	reward := make([][]hexutil.Big, len(f.reward))
+	for i, prices := range f.reward {
+		reward[i] = make([]hexutil.Big, len(prices))
+		for j, price := range prices {
+			reward[i][j] = hexutil.Big(*price)
+		}
+	}
+	return &reward
+}
+
+func (f *FeeHistory) GasUsedRatio(ctx context.Context) []float64 {
+	return f.gasUsedRatio
+}
+
+func (r *Resolver) FeeHistory(ctx context.Context, args struct {
+	BlockCount        int32
+	LastBlock         *hexutil.Uint64
+	RewardPercentiles *[]float64
+}) (*FeeHistory, error) {
+	last := rpc.LatestBlockNumber
+	if args.LastBlock != nil {
+		last = rpc.BlockNumber(*args.LastBlock)
+	}
+	var percentiles []float64
+	if args.RewardPercentiles != nil {
+		percentiles = *args.RewardPercentiles
+	}
+	oldest, reward, gasUsedRatio, err := r.backend.FeeHistory(ctx, int(args.BlockCount), last, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeHistory{oldest: oldest, reward: reward, gasUsedRatio: gasUsedRatio}, nil
 }
 
 func (r *Resolver) ProtocolVersion(ctx context.Context) (int32, error) {
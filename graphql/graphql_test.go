@@ -17,12 +17,188 @@
 package graphql
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 )
 
 func TestBuildSchema(t *testing.T) {
 	// Make sure the schema can be parsed and matched up to the object model.
-	if _, err := newHandler(nil); err != nil {
+	if _, err := newHandler(nil, nil); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
+
+func TestPrefixHandler(t *testing.T) {
+	// Make sure the query browser queries the API under the configured prefix.
+	h, err := newPrefixHandler(nil, "/gql/", nil)
+	if err != nil {
+		t.Fatalf("Could not construct GraphQL handler: %v", err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/gql/ui", nil))
+	if !strings.Contains(w.Body.String(), `fetch("/gql"`) {
+		t.Errorf("GraphiQL not querying the prefixed API")
+	}
+}
+
+// historyBackend is a backend serving blocks from memory, with an account
+// transaction index covering all of them.
+type historyBackend struct {
+	ethapi.Backend
+	db     ethdb.Database
+	blocks map[common.Hash]*types.Block
+}
+
+func (b *historyBackend) ChainDb() ethdb.Database { return b.db }
+
+func (b *historyBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.blocks[hash], nil
+}
+
+func (b *historyBackend) AccountTxIndexed() (uint64, bool) {
+	return uint64(len(b.blocks)), true
+}
+
+// Tests that the transaction history of an account is paginated in chain order,
+// skipping the index entries of reorged blocks.
+func TestAccountTransactions(t *testing.T) {
+	var (
+		addr    = common.HexToAddress("0x01")
+		backend = &historyBackend{db: rawdb.NewMemoryDatabase(), blocks: make(map[common.Hash]*types.Block)}
+	)
+	for i := uint64(0); i < 3; i++ {
+		var txs []*types.Transaction
+		for j := uint64(0); j < 2; j++ {
+			txs = append(txs, types.NewTransaction(i*2+j, addr, big.NewInt(1), 21000, big.NewInt(1), nil))
+		}
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, txs, nil, nil)
+		backend.blocks[block.Hash()] = block
+		rawdb.WriteCanonicalHash(backend.db, block.Hash(), i)
+	}
+	hash := func(n uint64) common.Hash { return rawdb.ReadCanonicalHash(backend.db, n) }
+	rawdb.WriteAccountTxs(backend.db, addr, 1, hash(1), []uint64{0, 1})
+	rawdb.WriteAccountTxs(backend.db, addr, 2, common.Hash{0x01}, []uint64{0})
+	rawdb.WriteAccountTxs(backend.db, addr, 2, hash(2), []uint64{1})
+
+	account, err := (&Resolver{backend}).Account(context.Background(), struct {
+		Address   common.Address
+		Block     *hexutil.Uint64
+		BlockHash *common.Hash
+	}{Address: addr})
+	if err != nil {
+		t.Fatalf("failed to resolve account: %v", err)
+	}
+	var (
+		first  = int32(2)
+		cursor *string
+		have   []common.Hash
+	)
+	for pages := 0; ; pages++ {
+		if pages > 2 {
+			t.Fatalf("too many pages")
+		}
+		page, err := account.Transactions(context.Background(), struct {
+			FromBlock *hexutil.Uint64
+			ToBlock   *hexutil.Uint64
+			First     *int32
+			After     *string
+		}{First: &first, After: cursor})
+		if err != nil {
+			t.Fatalf("failed to retrieve transactions: %v", err)
+		}
+		for _, tx := range page.Transactions(context.Background()) {
+			have = append(have, tx.Hash(context.Background()))
+		}
+		if cursor = page.Cursor(context.Background()); cursor == nil {
+			break
+		}
+	}
+	want := []common.Hash{
+		backend.blocks[hash(1)].Transactions()[0].Hash(),
+		backend.blocks[hash(1)].Transactions()[1].Hash(),
+		backend.blocks[hash(2)].Transactions()[1].Hash(),
+	}
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("transaction %d: hash mismatch: have %x, want %x", i, have[i], want[i])
+		}
+	}
+}
+
+// receiptsBackend is a backend serving a single block along with its receipts.
+type receiptsBackend struct {
+	ethapi.Backend
+	block    *types.Block
+	receipts types.Receipts
+}
+
+func (b *receiptsBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if hash != b.block.Hash() {
+		return nil, nil
+	}
+	return b.block.Header(), nil
+}
+
+func (b *receiptsBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if hash != b.block.Hash() {
+		return nil, nil
+	}
+	return b.block, nil
+}
+
+func (b *receiptsBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if hash != b.block.Hash() {
+		return nil, nil
+	}
+	return b.receipts, nil
+}
+
+// Tests that the receipts of a block are returned in transaction order, along
+// with their transactions, created contracts and logs.
+func TestBlockReceipts(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0de")
+		txs      = []*types.Transaction{
+			types.NewContractCreation(0, new(big.Int), 100000, big.NewInt(1), []byte{0x00}),
+			types.NewTransaction(1, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil),
+		}
+		receipts = types.Receipts{
+			{Status: types.ReceiptStatusSuccessful, GasUsed: 60000, CumulativeGasUsed: 60000, ContractAddress: contract, Logs: []*types.Log{{Address: contract, Index: 0}, {Address: contract, Index: 1}}},
+			{Status: types.ReceiptStatusFailed, GasUsed: 21000, CumulativeGasUsed: 81000, Logs: []*types.Log{}},
+		}
+		block   = types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, nil, receipts)
+		backend = &receiptsBackend{block: block, receipts: receipts}
+	)
+	handler, err := newHandler(backend, nil)
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	query := fmt.Sprintf(`{ block(hash: "%s") { receipts { transaction { hash } status gasUsed cumulativeGasUsed createdContract { address } logs { index account { address } } } } }`, block.Hash().Hex())
+	body, _ := json.Marshal(map[string]string{"query": query})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+
+	want := fmt.Sprintf(`{"data":{"block":{"receipts":[`+
+		`{"transaction":{"hash":"%s"},"status":"0x1","gasUsed":"0xea60","cumulativeGasUsed":"0xea60","createdContract":{"address":"%s"},"logs":[{"index":0,"account":{"address":"%[2]s"}},{"index":1,"account":{"address":"%[2]s"}}]},`+
+		`{"transaction":{"hash":"%s"},"status":"0x0","gasUsed":"0x5208","cumulativeGasUsed":"0x13c68","createdContract":null,"logs":[]}`+
+		`]}}}`, txs[0].Hash().Hex(), strings.ToLower(contract.Hex()), txs[1].Hash().Hex())
+	if have := strings.TrimSpace(w.Body.String()); have != want {
+		t.Fatalf("receipts mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -52,6 +53,26 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # StorageSlots provides access to multiple storage slots of a contract
+        # account at once, returning their values in the order requested.
+        storageSlots(slots: [Bytes32!]!): [Bytes32!]!
+        # Transactions returns a page of the transactions sent from, sent to or
+        # creating this account, as well as those reaching it through internal
+        # transactions, in chain order. Only the blocks covered by the node's
+        # transaction index are searched, and the query fails if the index is
+        # not enabled. The page holds at most first transactions (default 100,
+        # at most 1000), following the position identified by after.
+        transactions(fromBlock: Long, toBlock: Long, first: Int, after: String): TransactionPage
+    }
+
+    # TransactionPage is a page of the transaction history of an account.
+    type TransactionPage {
+        # Transactions is the list of transactions in this page.
+        transactions: [Transaction!]!
+        # Cursor identifies the last transaction of this page, to be passed as
+        # the after argument to retrieve the next page. It is null if there are
+        # no more transactions in the requested block range.
+        cursor: String
     }
 
     # Log is an Ethereum event log.
@@ -117,6 +138,27 @@ const schema string = `
         logs: [Log!]
     }
 
+    # Receipt is the receipt of a transaction included in a block.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction - 1 for success or 0
+        # for failure.
+        status: Long!
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and
+        # including the transaction.
+        cumulativeGasUsed: Long!
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, null otherwise.
+        createdContract(block: Long): Account
+        # Logs is the list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is a bloom filter of the logs emitted by the transaction.
+        logsBloom: Bytes!
+    }
+
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
     # to a single block.
     input BlockFilterCriteria {
@@ -197,6 +239,9 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
+        # Receipts is the list of receipts of the transactions in this block. If
+        # transactions are unavailable for this block, this field will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Ethereum account at the current block's state.
@@ -291,6 +336,18 @@ const schema string = `
       estimateGas(data: CallData!): Long!
     }
 
+    # FeeHistory is the fee history of a range of blocks.
+    type FeeHistory {
+        # OldestBlock is the number of the first block of the range.
+        oldestBlock: Long!
+        # Reward is the list of gas prices paid at the requested percentiles of
+        # each block, weighted by the gas used by its transactions. It is null
+        # if no percentiles were requested.
+        reward: [[BigInt!]!]
+        # GasUsedRatio is the ratio of gas used to the gas limit of each block.
+        gasUsedRatio: [Float!]!
+    }
+
     type Query {
         # Block fetches an Ethereum block by number or by hash. If neither is
         # supplied, the most recent known block is returned.
@@ -304,9 +361,25 @@ const schema string = `
         transaction(hash: Bytes32!): Transaction
         # Logs returns log entries matching the provided filter.
         logs(filter: FilterCriteria!): [Log!]!
+        # Account fetches an Ethereum account at the block with the given
+        # number or hash. If neither is supplied, the state of the most recent
+        # known block is used.
+        account(address: Address!, block: Long, blockHash: Bytes32): Account!
+        # Call executes a local call operation at the state of the given block,
+        # defaulting to the most recent known block.
+        call(data: CallData!, block: Long): CallResult
+        # EstimateGas estimates the amount of gas that will be required for
+        # successful execution of a transaction at the state of the given
+        # block, defaulting to the most recent known block.
+        estimateGas(data: CallData!, block: Long): Long!
         # GasPrice returns the node's estimate of a gas price sufficient to
-        # ensure a transaction is mined in a timely fashion.
-        gasPrice: BigInt!
+        # ensure a transaction is mined in a timely fashion. The number of
+        # recent blocks sampled and the percentile of their prices to suggest
+        # default to the node's configuration.
+        gasPrice(blocks: Int, percentile: Int): BigInt!
+        # FeeHistory returns the fee history of blockCount blocks up to and
+        # including lastBlock, defaulting to the most recent known block.
+        feeHistory(blockCount: Int!, lastBlock: Long, rewardPercentiles: [Float!]): FeeHistory!
         # ProtocolVersion returns the current wire protocol version number.
         protocolVersion: Int!
         # Syncing returns information on the current synchronisation state.
@@ -317,4 +390,17 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription operations are served over WebSocket, using the graphql-ws
+    # protocol. Each event is delivered as the result of the operation's
+    # selection set, which may only contain a single field.
+    type Subscription {
+        # NewHeads delivers each new head block of the canonical chain.
+        newHeads: Block!
+        # Logs delivers each log entry of newly imported blocks matching the
+        # provided filter.
+        logs(filter: BlockFilterCriteria): Log!
+        # PendingTransactions delivers each transaction entering the pool.
+        pendingTransactions: Transaction!
+    }
 `
@@ -20,18 +20,21 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 )
 
 // Service encapsulates a GraphQL service.
 type Service struct {
-	endpoint string           // The host:port endpoint for this service.
+	endpoint string           // The host:port endpoint for this service (empty = served by the node).
+	prefix   string           // The path prefix to serve under if served by the node.
 	cors     []string         // Allowed CORS domains
 	vhosts   []string         // Recognised vhosts
 	timeouts rpc.HTTPTimeouts // Timeout settings for HTTP requests.
@@ -40,10 +43,16 @@ type Service struct {
 	listener net.Listener     // The listening socket.
 }
 
-// New constructs a new GraphQL service instance.
-func New(backend ethapi.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
+// New constructs a new GraphQL service instance. If endpoint is empty, no listener
+// is opened, rather the service is served on the HTTP endpoint of the node under
+// the given path prefix.
+func New(backend ethapi.Backend, endpoint string, prefix string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
+	if prefix == "" {
+		prefix = "/graphql"
+	}
 	return &Service{
 		endpoint: endpoint,
+		prefix:   prefix,
 		cors:     cors,
 		vhosts:   vhosts,
 		timeouts: timeouts,
@@ -61,7 +70,11 @@ func (s *Service) APIs() []rpc.API { return nil }
 // layer was also initialized to spawn any goroutines required by the service.
 func (s *Service) Start(server *p2p.Server) error {
 	var err error
-	s.handler, err = newHandler(s.backend)
+	if s.endpoint == "" {
+		s.handler, err = newPrefixHandler(s.backend, s.prefix, s.cors)
+		return err
+	}
+	s.handler, err = newHandler(s.backend, s.cors)
 	if err != nil {
 		return err
 	}
@@ -73,17 +86,48 @@ func (s *Service) Start(server *p2p.Server) error {
 	return nil
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(backend ethapi.Backend) (http.Handler, error) {
+// HTTPHandler implements node.HTTPService, returning the handler to serve on the
+// HTTP endpoint of the node if the service has no endpoint of its own.
+func (s *Service) HTTPHandler() (string, http.Handler) {
+	if s.endpoint != "" {
+		return "", nil
+	}
+	return s.prefix, rpc.NewHTTPHandler(s.handler, s.cors, s.vhosts, nil)
+}
+
+// newAPIHandler returns a new `http.Handler` that will answer GraphQL queries and
+// mutations over HTTP, and any operation including subscriptions over websocket
+// connections from the allowed origins.
+func newAPIHandler(backend ethapi.Backend, origins []string) (http.Handler, error) {
 	q := Resolver{backend}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return nil, err
 	}
+	events, err := graphql.ParseSchema(eventSchema, &subscriptionResolver{backend})
+	if err != nil {
+		return nil, err
+	}
 	h := &relay.Handler{Schema: s}
+	ws := newWSHandler(backend, s, events, origins)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			ws.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}), nil
+}
 
+// newHandler returns a new `http.Handler` that will answer GraphQL queries.
+// It additionally exports an interactive query browser on the / endpoint.
+func newHandler(backend ethapi.Backend, origins []string) (http.Handler, error) {
+	h, err := newAPIHandler(backend, origins)
+	if err != nil {
+		return nil, err
+	}
 	mux := http.NewServeMux()
 	mux.Handle("/", GraphiQL{})
 	mux.Handle("/graphql", h)
@@ -91,6 +135,22 @@ func newHandler(backend ethapi.Backend) (http.Handler, error) {
 	return mux, nil
 }
 
+// newPrefixHandler returns a new `http.Handler` that will answer GraphQL queries
+// under the given path prefix, exporting the interactive query browser on the
+// /ui subpath.
+func newPrefixHandler(backend ethapi.Backend, prefix string, origins []string) (http.Handler, error) {
+	h, err := newAPIHandler(backend, origins)
+	if err != nil {
+		return nil, err
+	}
+	prefix = "/" + strings.Trim(prefix, "/")
+	mux := http.NewServeMux()
+	mux.Handle(prefix, h)
+	mux.Handle(prefix+"/", h)
+	mux.Handle(prefix+"/ui", GraphiQL{path: prefix})
+	return mux, nil
+}
+
 // Stop terminates all goroutines belonging to the service, blocking until they
 // are all terminated.
 func (s *Service) Stop() error {
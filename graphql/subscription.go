@@ -0,0 +1,583 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+const (
+	// wsProtocol is the websocket subprotocol spoken by subscription clients.
+	wsProtocol = "graphql-ws"
+
+	// wsWriteTimeout is the time allowed to write a message to the client.
+	wsWriteTimeout = 10 * time.Second
+
+	// eventChanSize is the size of the channels receiving the chain and pool
+	// events of a subscription, and of the queue of events awaiting delivery.
+	eventChanSize = 256
+
+	// wsMaxOperations is the maximum number of operations a client may run
+	// concurrently on a single connection.
+	wsMaxOperations = 32
+)
+
+// Message types of the graphql-ws protocol.
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionError     = "connection_error"
+	wsConnectionTerminate = "connection_terminate"
+	wsStart               = "start"
+	wsStop                = "stop"
+	wsData                = "data"
+	wsError               = "error"
+	wsComplete            = "complete"
+)
+
+var (
+	errNoEvent         = errors.New("subscription fields are only resolved for events")
+	errSingleRootField = errors.New("subscriptions must select exactly one top-level field")
+	errTooManyOps      = errors.New("too many running operations")
+	errEventOverflow   = errors.New("subscription dropped, events not consumed fast enough")
+)
+
+// eventSchema is the schema subscription operations are executed against, once
+// for every event, with the Subscription type as the query root. The vendored
+// GraphQL engine does not execute subscriptions, so their operation keyword is
+// rewritten into a query before execution.
+var eventSchema = strings.Replace(schema, `
+        query: Query
+        mutation: Mutation
+        subscription: Subscription
+`, `
+        query: Subscription
+`, 1)
+
+// eventKey is the context key of the event a subscription operation is executed
+// for, or of the subscriptionRequest collecting the selected field if none.
+type eventKey struct{}
+
+// subscriptionRequest collects the top-level fields (and their arguments) selected
+// by a subscription operation, determined by executing it without an event.
+type subscriptionRequest struct {
+	lock   sync.Mutex
+	fields []string
+	filter *BlockFilterCriteria
+}
+
+// record notes the selection of a top-level field, returning the error to fail
+// its resolution with.
+func record(ctx context.Context, field string, filter *BlockFilterCriteria) error {
+	if req, ok := ctx.Value(eventKey{}).(*subscriptionRequest); ok {
+		req.lock.Lock()
+		req.fields = append(req.fields, field)
+		req.filter = filter
+		req.lock.Unlock()
+	}
+	return errNoEvent
+}
+
+// subscriptionResolver is the top-level object of subscription operations.
+type subscriptionResolver struct {
+	backend ethapi.Backend
+}
+
+func (r *subscriptionResolver) NewHeads(ctx context.Context) (*Block, error) {
+	header, ok := ctx.Value(eventKey{}).(*types.Header)
+	if !ok {
+		return nil, record(ctx, "newHeads", nil)
+	}
+	num := rpc.BlockNumber(header.Number.Uint64())
+	return &Block{
+		backend:   r.backend,
+		num:       &num,
+		hash:      header.Hash(),
+		header:    header,
+		canonical: unknown,
+	}, nil
+}
+
+func (r *subscriptionResolver) Logs(ctx context.Context, args struct{ Filter *BlockFilterCriteria }) (*Log, error) {
+	entry, ok := ctx.Value(eventKey{}).(*types.Log)
+	if !ok {
+		return nil, record(ctx, "logs", args.Filter)
+	}
+	return &Log{
+		backend:     r.backend,
+		transaction: &Transaction{backend: r.backend, hash: entry.TxHash},
+		log:         entry,
+	}, nil
+}
+
+func (r *subscriptionResolver) PendingTransactions(ctx context.Context) (*Transaction, error) {
+	tx, ok := ctx.Value(eventKey{}).(*types.Transaction)
+	if !ok {
+		return nil, record(ctx, "pendingTransactions", nil)
+	}
+	return &Transaction{
+		backend: r.backend,
+		hash:    tx.Hash(),
+		tx:      tx,
+	}, nil
+}
+
+// rewriteSubscription returns the document with the operation to execute turned
+// into a query if it is a subscription, and whether it was one.
+func rewriteSubscription(doc string, operationName string) (string, bool) {
+	var op *operationDef
+	for _, def := range scanOperations(doc) {
+		def := def
+		if (operationName == "" && op == nil) || (operationName != "" && def.name == operationName) {
+			op = &def
+		} else if operationName == "" {
+			return doc, false // ambiguous, let the engine report it
+		}
+	}
+	if op == nil || op.kind != "subscription" {
+		return doc, false
+	}
+	return doc[:op.pos] + "query" + doc[op.pos+len(op.kind):], true
+}
+
+// operationDef is an operation definition found in a GraphQL document.
+type operationDef struct {
+	kind string // Operation type, query for the shorthand form
+	name string // Operation name, empty if anonymous
+	pos  int    // Offset of the operation type keyword, -1 for the shorthand form
+}
+
+// scanOperations finds the operation definitions of a GraphQL document, skipping
+// over comments, strings and selection sets.
+func scanOperations(doc string) []operationDef {
+	var (
+		ops   []operationDef
+		depth int
+		start = true // Whether the next top-level token starts a definition
+	)
+	for i := 0; i < len(doc); {
+		switch c := doc[i]; {
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' && doc[i] != '\r' {
+				i++
+			}
+		case c == '"':
+			i = skipString(doc, i)
+		case c == '{' || c == '(' || c == '[':
+			if depth == 0 && start && c == '{' {
+				ops = append(ops, operationDef{kind: "query", pos: -1})
+				start = false
+			}
+			depth++
+			i++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+			i++
+			if depth == 0 && c == '}' {
+				start = true
+			}
+		case isNameStart(c):
+			end := scanName(doc, i)
+			if depth == 0 && start {
+				start = false
+				if kind := doc[i:end]; kind == "query" || kind == "mutation" || kind == "subscription" {
+					op := operationDef{kind: kind, pos: i}
+					if next := skipIgnored(doc, end); next < len(doc) && isNameStart(doc[next]) {
+						op.name = doc[next:scanName(doc, next)]
+					}
+					ops = append(ops, op)
+				}
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return ops
+}
+
+// skipString returns the offset following the string or block string starting
+// at the given offset.
+func skipString(doc string, i int) int {
+	if strings.HasPrefix(doc[i:], `"""`) {
+		if end := strings.Index(doc[i+3:], `"""`); end >= 0 {
+			return i + 3 + end + 3
+		}
+		return len(doc)
+	}
+	for i++; i < len(doc); i++ {
+		switch doc[i] {
+		case '\\':
+			i++
+		case '"', '\n', '\r':
+			return i + 1
+		}
+	}
+	return len(doc)
+}
+
+// skipIgnored returns the offset of the first token at or after the given one,
+// skipping over whitespace, commas and comments.
+func skipIgnored(doc string, i int) int {
+	for i < len(doc) {
+		switch doc[i] {
+		case ' ', '\t', '\n', '\r', ',':
+			i++
+		case '#':
+			for i < len(doc) && doc[i] != '\n' && doc[i] != '\r' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanName returns the offset following the name starting at the given offset.
+func scanName(doc string, i int) int {
+	for i < len(doc) && (isNameStart(doc[i]) || (doc[i] >= '0' && doc[i] <= '9')) {
+		i++
+	}
+	return i
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsOperation is the payload of a start message.
+type wsOperation struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsHandler serves GraphQL operations, including subscriptions, to clients
+// speaking the graphql-ws protocol over websocket.
+type wsHandler struct {
+	backend  ethapi.Backend
+	schema   *graphql.Schema // Schema answering queries and mutations
+	events   *graphql.Schema // Schema answering subscriptions, once per event
+	upgrader websocket.Upgrader
+}
+
+// newWSHandler creates a websocket handler accepting connections from the
+// allowed origins.
+func newWSHandler(backend ethapi.Backend, schema, events *graphql.Schema, origins []string) *wsHandler {
+	return &wsHandler{
+		backend: backend,
+		schema:  schema,
+		events:  events,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocol},
+			CheckOrigin:  rpc.WSOriginValidator(origins),
+		},
+	}
+}
+
+// ServeHTTP implements http.Handler, upgrading the request to a websocket
+// connection and serving it until closed.
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		handler: h,
+		conn:    conn,
+		ops:     make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConn is a websocket connection of a GraphQL client.
+type wsConn struct {
+	handler *wsHandler
+	conn    *websocket.Conn
+
+	writeLock sync.Mutex // Serializes writes to the connection
+
+	ops  map[string]context.CancelFunc // Cancel functions of the running operations by id
+	lock sync.Mutex                    // Protects the running operations
+	wg   sync.WaitGroup                // Tracks the goroutines of the running operations
+}
+
+// serve reads and handles the messages of the client until the connection is
+// closed or terminated.
+func (c *wsConn) serve() {
+	defer func() {
+		c.lock.Lock()
+		for _, cancel := range c.ops {
+			cancel()
+		}
+		c.lock.Unlock()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			c.send(wsMessage{Type: wsConnectionAck})
+
+		case wsConnectionTerminate:
+			return
+
+		case wsStart:
+			var op wsOperation
+			if err := json.Unmarshal(msg.Payload, &op); err != nil {
+				c.sendErrors(msg.ID, gqlerrors.Errorf("invalid operation: %v", err))
+				continue
+			}
+			c.start(msg.ID, &op)
+
+		case wsStop:
+			c.lock.Lock()
+			if cancel, ok := c.ops[msg.ID]; ok {
+				cancel()
+			}
+			c.lock.Unlock()
+
+		default:
+			payload, _ := json.Marshal(gqlerrors.Errorf("unknown message type %q", msg.Type))
+			c.send(wsMessage{ID: msg.ID, Type: wsConnectionError, Payload: payload})
+		}
+	}
+}
+
+// start runs an operation in the background until it completes, the client
+// stops it or the connection is closed.
+func (c *wsConn) start(id string, op *wsOperation) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.ops[id]; ok {
+		c.sendErrors(id, gqlerrors.Errorf("operation %q already running", id))
+		return
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.sendErrors(id, gqlerrors.Errorf("%v", errTooManyOps))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.ops[id] = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.run(ctx, id, op)
+
+		c.lock.Lock()
+		delete(c.ops, id)
+		c.lock.Unlock()
+		cancel()
+	}()
+}
+
+// run executes an operation, answering queries and mutations once and
+// subscriptions for every matching event.
+func (c *wsConn) run(ctx context.Context, id string, op *wsOperation) {
+	if errs := c.handler.schema.Validate(op.Query); len(errs) > 0 {
+		c.sendErrors(id, errs...)
+		return
+	}
+	doc, ok := rewriteSubscription(op.Query, op.OperationName)
+	if !ok {
+		c.sendData(id, c.handler.schema.Exec(ctx, op.Query, op.OperationName, op.Variables))
+		c.send(wsMessage{ID: id, Type: wsComplete})
+		return
+	}
+	// Find out which field the subscription selects, then deliver its events
+	req := new(subscriptionRequest)
+	res := c.handler.events.Exec(context.WithValue(ctx, eventKey{}, req), doc, op.OperationName, op.Variables)
+	switch {
+	case len(req.fields) == 0 && len(res.Errors) > 0:
+		c.sendErrors(id, res.Errors...)
+		return
+	case len(req.fields) != 1:
+		c.sendErrors(id, gqlerrors.Errorf("%v", errSingleRootField))
+		return
+	}
+	// Deliver the events queued by the subscription, which never waits for the
+	// client so that a slow one cannot hold up the event feeds
+	var (
+		events = make(chan interface{}, eventChanSize)
+		done   = make(chan error, 1)
+	)
+	go func() {
+		done <- c.handler.subscribe(ctx, req.fields[0], req.filter, events)
+	}()
+	for {
+		select {
+		case event := <-events:
+			if ctx.Err() == nil {
+				c.sendData(id, c.handler.events.Exec(context.WithValue(ctx, eventKey{}, event), doc, op.OperationName, op.Variables))
+			}
+		case err := <-done:
+			if err != nil {
+				c.sendErrors(id, gqlerrors.Errorf("%v", err))
+				return
+			}
+			c.send(wsMessage{ID: id, Type: wsComplete})
+			return
+		}
+	}
+}
+
+// queue adds an event to the delivery queue of a subscription, reporting whether
+// there was room for it.
+func queue(events chan<- interface{}, event interface{}) bool {
+	select {
+	case events <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribe queues the events of the given subscription field for delivery until
+// the context is canceled, the event feed fails or the queue overflows.
+func (h *wsHandler) subscribe(ctx context.Context, field string, filter *BlockFilterCriteria, events chan<- interface{}) error {
+	switch field {
+	case "newHeads":
+		ch := make(chan core.ChainHeadEvent, eventChanSize)
+		sub := h.backend.SubscribeChainHeadEvent(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-ch:
+				if !queue(events, ev.Block.Header()) {
+					return errEventOverflow
+				}
+			case err := <-sub.Err():
+				return err
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+	case "logs":
+		var (
+			addresses []common.Address
+			topics    [][]common.Hash
+		)
+		if filter != nil && filter.Addresses != nil {
+			addresses = *filter.Addresses
+		}
+		if filter != nil && filter.Topics != nil {
+			topics = *filter.Topics
+		}
+		ch := make(chan []*types.Log, eventChanSize)
+		sub := h.backend.SubscribeLogsEvent(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case logs := <-ch:
+				for _, entry := range filters.FilterLogs(logs, addresses, topics) {
+					if !queue(events, entry) {
+						return errEventOverflow
+					}
+				}
+			case err := <-sub.Err():
+				return err
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+	case "pendingTransactions":
+		ch := make(chan core.NewTxsEvent, eventChanSize)
+		sub := h.backend.SubscribeNewTxsEvent(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-ch:
+				for _, tx := range ev.Txs {
+					if !queue(events, tx) {
+						return errEventOverflow
+					}
+				}
+			case err := <-sub.Err():
+				return err
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unknown subscription field %q", field)
+}
+
+// sendData sends the result of an operation execution to the client.
+func (c *wsConn) sendData(id string, res *graphql.Response) {
+	payload, err := json.Marshal(res)
+	if err != nil {
+		c.sendErrors(id, gqlerrors.Errorf("failed to encode result: %v", err))
+		return
+	}
+	c.send(wsMessage{ID: id, Type: wsData, Payload: payload})
+}
+
+// sendErrors sends the errors failing an operation to the client.
+func (c *wsConn) sendErrors(id string, errs ...*gqlerrors.QueryError) {
+	payload, _ := json.Marshal(errs)
+	c.send(wsMessage{ID: id, Type: wsError, Payload: payload})
+}
+
+// send writes a message to the client. Write failures are ignored, the reader
+// notices the broken connection and tears it down.
+func (c *wsConn) send(msg wsMessage) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("Failed to write GraphQL websocket message", "err", err)
+	}
+}
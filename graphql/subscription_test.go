@@ -0,0 +1,296 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/gorilla/websocket"
+)
+
+// Tests that the subscription operation selected for execution is rewritten
+// into a query, leaving everything else untouched.
+func TestRewriteSubscription(t *testing.T) {
+	tests := []struct {
+		doc  string
+		name string
+		want string // Empty if no rewrite is expected
+	}{
+		{`subscription { newHeads { number } }`, "", `query { newHeads { number } }`},
+		{`# subscription
+subscription Heads($x: Int) { newHeads { number } }`, "", `# subscription
+query Heads($x: Int) { newHeads { number } }`},
+		{`{ block { number } }`, "", ``},
+		{`query { subscription: block { number } }`, "", ``},
+		{`query A { block { hash } } subscription B { newHeads { hash } }`, "B", `query A { block { hash } } query B { newHeads { hash } }`},
+		{`query A { block { hash } } subscription B { newHeads { hash } }`, "A", ``},
+		{`query A { block { hash } } subscription B { newHeads { hash } }`, "", ``},
+		{`query { logs(filter: {topics: [["}"]]}) { index } } subscription S { newHeads { hash } }`, "S", `query { logs(filter: {topics: [["}"]]}) { index } } query S { newHeads { hash } }`},
+		{`fragment subscription on Block { hash } subscription { newHeads { ...subscription } }`, "", `fragment subscription on Block { hash } query { newHeads { ...subscription } }`},
+	}
+	for i, tt := range tests {
+		doc, ok := rewriteSubscription(tt.doc, tt.name)
+		if ok != (tt.want != "") {
+			t.Errorf("test %d: rewrite mismatch: have %v, want %v", i, ok, tt.want != "")
+			continue
+		}
+		if ok && doc != tt.want {
+			t.Errorf("test %d: rewritten document mismatch:\nhave %s\nwant %s", i, doc, tt.want)
+		}
+	}
+}
+
+// subscriptionBackend is a backend only capable of delivering chain head, log
+// and pending transaction events.
+type subscriptionBackend struct {
+	ethapi.Backend
+	feed    event.Feed
+	logFeed event.Feed
+	txFeed  event.Feed
+}
+
+func (b *subscriptionBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+func (b *subscriptionBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.logFeed.Subscribe(ch)
+}
+
+func (b *subscriptionBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+// dialSubscriptions starts a GraphQL server on top of the backend and opens an
+// acknowledged websocket connection to it. The returned function reads the next
+// message, failing the test if it's not of the expected type.
+func dialSubscriptions(t *testing.T, backend ethapi.Backend) (*websocket.Conn, func(string) *wsMessage, func()) {
+	t.Helper()
+
+	handler, err := newHandler(backend, nil)
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		server.Close()
+		t.Fatalf("failed to dial: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	expect := func(typ string) *wsMessage {
+		t.Helper()
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read %s message: %v", typ, err)
+		}
+		if msg.Type != typ {
+			t.Fatalf("message type mismatch: have %s (%s), want %s", msg.Type, msg.Payload, typ)
+		}
+		return &msg
+	}
+	conn.WriteJSON(wsMessage{Type: wsConnectionInit})
+	expect(wsConnectionAck)
+
+	return conn, expect, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+// expectData reads the next data message of the given operation, checking that
+// it carries the wanted result without errors.
+func expectData(t *testing.T, expect func(string) *wsMessage, id string, want string) {
+	t.Helper()
+
+	msg := expect(wsData)
+	var res struct {
+		Data   json.RawMessage
+		Errors []interface{}
+	}
+	if err := json.Unmarshal(msg.Payload, &res); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if msg.ID != id || string(res.Data) != want || len(res.Errors) != 0 {
+		t.Fatalf("result mismatch: have %s %s, want %s", msg.ID, msg.Payload, want)
+	}
+}
+
+// sendUntilSubscribed keeps sending an event until a subscriber receives it, as
+// subscriptions are established asynchronously.
+func sendUntilSubscribed(t *testing.T, feed *event.Feed, ev interface{}) {
+	t.Helper()
+
+	for start := time.Now(); feed.Send(ev) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("subscription not established")
+		}
+	}
+}
+
+// Tests that new chain heads are delivered to subscribers over websocket.
+func TestSubscribeNewHeads(t *testing.T) {
+	backend := new(subscriptionBackend)
+	conn, expect, teardown := dialSubscriptions(t, backend)
+	defer teardown()
+
+	// Subscriptions must select a single field
+	payload, _ := json.Marshal(wsOperation{Query: `subscription { newHeads { number } pendingTransactions { hash } }`})
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsStart, Payload: payload})
+	expect(wsError)
+
+	payload, _ = json.Marshal(wsOperation{Query: `subscription { head: newHeads { number } }`})
+	conn.WriteJSON(wsMessage{ID: "2", Type: wsStart, Payload: payload})
+
+	// Wait for the subscription to be established, then deliver a few heads
+	sendUntilSubscribed(t, &backend.feed, core.ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})})
+	backend.feed.Send(core.ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)})})
+
+	for _, want := range []string{`{"head":{"number":"0x1"}}`, `{"head":{"number":"0x2"}}`} {
+		expectData(t, expect, "2", want)
+	}
+	// Stop the subscription and check that it's torn down
+	conn.WriteJSON(wsMessage{ID: "2", Type: wsStop})
+	if msg := expect(wsComplete); msg.ID != "2" {
+		t.Fatalf("completed operation mismatch: have %s, want 2", msg.ID)
+	}
+	if n := backend.feed.Send(core.ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3)})}); n != 0 {
+		t.Fatalf("stopped subscription still receiving events")
+	}
+}
+
+// Tests that only the logs matching the filter of a subscription are delivered
+// to it over websocket.
+func TestSubscribeLogs(t *testing.T) {
+	backend := new(subscriptionBackend)
+	conn, expect, teardown := dialSubscriptions(t, backend)
+	defer teardown()
+
+	var (
+		contract = common.HexToAddress("0xc0de")
+		other    = common.HexToAddress("0xdead")
+		topic    = common.HexToHash("0x01")
+		txHash   = common.HexToHash("0xfeed")
+	)
+	payload, _ := json.Marshal(wsOperation{Query: `subscription { logs(filter: {addresses: ["` + contract.Hex() + `"], topics: [["` + topic.Hex() + `"]]}) { index topics transaction { hash } } }`})
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsStart, Payload: payload})
+
+	// Deliver a batch mixing matching and non-matching logs, followed by one
+	// consisting of a single matching log
+	sendUntilSubscribed(t, &backend.logFeed, []*types.Log{
+		{Address: other, Topics: []common.Hash{topic}, TxHash: txHash, Index: 0},
+		{Address: contract, Topics: []common.Hash{topic}, TxHash: txHash, Index: 1},
+		{Address: contract, Topics: []common.Hash{common.HexToHash("0x02")}, TxHash: txHash, Index: 2},
+	})
+	backend.logFeed.Send([]*types.Log{{Address: contract, Topics: []common.Hash{topic, common.HexToHash("0x03")}, TxHash: txHash, Index: 3}})
+
+	expectData(t, expect, "1", `{"logs":{"index":1,"topics":["`+topic.Hex()+`"],"transaction":{"hash":"`+txHash.Hex()+`"}}}`)
+	expectData(t, expect, "1", `{"logs":{"index":3,"topics":["`+topic.Hex()+`","`+common.HexToHash("0x03").Hex()+`"],"transaction":{"hash":"`+txHash.Hex()+`"}}}`)
+
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsStop})
+	if msg := expect(wsComplete); msg.ID != "1" {
+		t.Fatalf("completed operation mismatch: have %s, want 1", msg.ID)
+	}
+}
+
+// Tests that pending transactions are delivered to subscribers over websocket,
+// one event per transaction.
+func TestSubscribePendingTransactions(t *testing.T) {
+	backend := new(subscriptionBackend)
+	conn, expect, teardown := dialSubscriptions(t, backend)
+	defer teardown()
+
+	payload, _ := json.Marshal(wsOperation{Query: `subscription { pendingTransactions { hash nonce } }`})
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsStart, Payload: payload})
+
+	txs := []*types.Transaction{
+		types.NewTransaction(1, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewTransaction(2, common.HexToAddress("0x02"), big.NewInt(1), 21000, big.NewInt(1), nil),
+	}
+	sendUntilSubscribed(t, &backend.txFeed, core.NewTxsEvent{Txs: txs})
+
+	for _, tx := range txs {
+		expectData(t, expect, "1", fmt.Sprintf(`{"pendingTransactions":{"hash":"%s","nonce":"0x%x"}}`, tx.Hash().Hex(), tx.Nonce()))
+	}
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsStop})
+	if msg := expect(wsComplete); msg.ID != "1" {
+		t.Fatalf("completed operation mismatch: have %s, want 1", msg.ID)
+	}
+	if n := backend.txFeed.Send(core.NewTxsEvent{Txs: txs}); n != 0 {
+		t.Fatalf("stopped subscription still receiving events")
+	}
+}
+
+// Tests that the number of operations running on a connection is capped.
+func TestSubscribeOperationLimit(t *testing.T) {
+	backend := new(subscriptionBackend)
+	conn, expect, teardown := dialSubscriptions(t, backend)
+	defer teardown()
+
+	payload, _ := json.Marshal(wsOperation{Query: `subscription { newHeads { number } }`})
+	for i := 0; i < wsMaxOperations; i++ {
+		conn.WriteJSON(wsMessage{ID: fmt.Sprint(i), Type: wsStart, Payload: payload})
+	}
+	conn.WriteJSON(wsMessage{ID: "over", Type: wsStart, Payload: payload})
+	if msg := expect(wsError); msg.ID != "over" {
+		t.Fatalf("rejected operation mismatch: have %s, want over", msg.ID)
+	}
+}
+
+// Tests that a subscription whose events are not consumed is dropped instead of
+// holding up the event feed.
+func TestSubscribeOverflow(t *testing.T) {
+	var (
+		backend = new(subscriptionBackend)
+		handler = newWSHandler(backend, nil, nil, nil)
+		events  = make(chan interface{}, 1)
+		errc    = make(chan error, 1)
+	)
+	go func() {
+		errc <- handler.subscribe(context.Background(), "newHeads", nil, events)
+	}()
+	head := core.ChainHeadEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})}
+	sendUntilSubscribed(t, &backend.feed, head)
+	backend.feed.Send(head)
+
+	select {
+	case err := <-errc:
+		if err != errEventOverflow {
+			t.Fatalf("subscription error mismatch: have %v, want %v", err, errEventOverflow)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("overflowing subscription not dropped")
+	}
+	if n := backend.feed.Send(head); n != 0 {
+		t.Fatalf("dropped subscription still receiving events")
+	}
+}
@@ -19,6 +19,7 @@ package ethapi
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/common"
@@ -36,9 +38,11 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -62,12 +66,63 @@ func NewPublicEthereumAPI(b Backend) *PublicEthereumAPI {
 	return &PublicEthereumAPI{b}
 }
 
-// GasPrice returns a suggestion for a gas price.
-func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error) {
-	price, err := s.b.SuggestPrice(ctx)
+// GasPriceOptions are the optional parameters of a gas price suggestion,
+// overriding the ones configured for the node's gas price oracle.
+type GasPriceOptions struct {
+	Blocks     *math.HexOrDecimal64 `json:"blocks"`     // Number of recent blocks to sample
+	Percentile *math.HexOrDecimal64 `json:"percentile"` // Percentile of the sampled prices to suggest
+}
+
+// GasPrice returns a suggestion for a gas price. If options are given, the price
+// is computed over the requested number of recent blocks at the requested
+// percentile, with any missing option defaulting to the node's configuration.
+func (s *PublicEthereumAPI) GasPrice(ctx context.Context, options *GasPriceOptions) (*hexutil.Big, error) {
+	if options == nil || (options.Blocks == nil && options.Percentile == nil) {
+		price, err := s.b.SuggestPrice(ctx)
+		return (*hexutil.Big)(price), err
+	}
+	blocks, percentile := 0, -1 // use the oracle's configuration by default
+	if options.Blocks != nil {
+		blocks = int(*options.Blocks)
+	}
+	if options.Percentile != nil {
+		percentile = int(*options.Percentile)
+	}
+	price, err := s.b.SuggestPriceWithOptions(ctx, blocks, percentile)
 	return (*hexutil.Big)(price), err
 }
 
+// feeHistoryResult is the fee history of a range of blocks.
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratio of the given number of blocks up to (and
+// including) lastBlock, along with the gas prices paid at the requested reward
+// percentiles of each block, weighted by the gas used by its transactions.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, reward, gasUsedRatio, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	results := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsedRatio,
+	}
+	if reward != nil {
+		results.Reward = make([][]*hexutil.Big, len(reward))
+		for i, prices := range reward {
+			results.Reward[i] = make([]*hexutil.Big, len(prices))
+			for j, price := range prices {
+				results.Reward[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return results, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -119,7 +174,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -127,7 +182,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -528,10 +583,10 @@ func (s *PublicBlockChainAPI) BlockNumber() hexutil.Uint64 {
 }
 
 // GetBalance returns the amount of wei for the given address in the state of the
-// given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
-// block numbers are also allowed.
-func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// given block number or hash. The rpc.LatestBlockNumber and rpc.PendingBlockNumber
+// meta block numbers are also allowed.
+func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -555,8 +610,8 @@ type StorageResult struct {
 }
 
 // GetProof returns the Merkle-proof for a given account and optionally some storage keys.
-func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -661,6 +716,29 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Ha
 	return nil, err
 }
 
+// GetBlockReceipts returns the receipts of all transactions in the block with the
+// given number or hash, in the same format as eth_getTransactionReceipt. The
+// backends return the receipts with their derived fields filled in.
+func (s *PublicBlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts of block #%d unavailable", block.NumberU64())
+	}
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), txs[i], uint64(i))
+	}
+	return result, nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
@@ -711,9 +789,9 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 	return nil
 }
 
-// GetCode returns the code stored at the given address in the state for the given block number.
-func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// GetCode returns the code stored at the given address in the state for the given block number or hash.
+func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -722,10 +800,10 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 }
 
 // GetStorageAt returns the storage from the state at the given address, key and
-// block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
-// numbers are also allowed.
-func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// block number or hash. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
+// block numbers are also allowed.
+func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -743,13 +821,47 @@ type CallArgs struct {
 	Data     *hexutil.Bytes  `json:"data"`
 }
 
-// account indicates the overriding fields of account during the execution of
-// a message call.
+// ToMessage converts the call arguments to a message, filling in the defaults of
+// any missing fields. The sender defaults to the zero address if unset.
+func (args *CallArgs) ToMessage(globalGasCap *big.Int) types.Message {
+	var addr common.Address
+	if args.From != nil {
+		addr = *args.From
+	}
+	// Set default gas & gas price if none were set
+	gas := uint64(math.MaxUint64 / 2)
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	if globalGasCap != nil && globalGasCap.Uint64() < gas {
+		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", globalGasCap)
+		gas = globalGasCap.Uint64()
+	}
+	gasPrice := new(big.Int).SetUint64(defaultGasPrice)
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+
+	value := new(big.Int)
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+
+	var data []byte
+	if args.Data != nil {
+		data = []byte(*args.Data)
+	}
+
+	return types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+}
+
+// OverrideAccount indicates the overriding fields of account during the execution
+// of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
 // set, message execution will only use the data in the given state. Otherwise
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
-type account struct {
+type OverrideAccount struct {
 	Nonce     *hexutil.Uint64              `json:"nonce"`
 	Code      *hexutil.Bytes               `json:"code"`
 	Balance   **hexutil.Big                `json:"balance"`
@@ -757,78 +869,97 @@ type account struct {
 	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, overrides map[common.Address]account, vmCfg vm.Config, timeout time.Duration, globalGasCap *big.Int) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
 
-	state, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
-		return nil, 0, false, err
-	}
-	// Set sender address or use a default if none specified
-	var addr common.Address
-	if args.From == nil {
-		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
-			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
-			}
-		}
-	} else {
-		addr = *args.From
+// Apply overrides the fields of specified accounts into the given state.
+func (diff *StateOverride) Apply(statedb *state.StateDB) error {
+	if diff == nil {
+		return nil
 	}
-	// Override the fields of specified contracts before execution.
-	for addr, account := range overrides {
+	for addr, account := range *diff {
 		// Override account nonce.
 		if account.Nonce != nil {
-			state.SetNonce(addr, uint64(*account.Nonce))
+			statedb.SetNonce(addr, uint64(*account.Nonce))
 		}
 		// Override account(contract) code.
 		if account.Code != nil {
-			state.SetCode(addr, *account.Code)
+			statedb.SetCode(addr, *account.Code)
 		}
 		// Override account balance.
 		if account.Balance != nil {
-			state.SetBalance(addr, (*big.Int)(*account.Balance))
+			statedb.SetBalance(addr, (*big.Int)(*account.Balance))
 		}
 		if account.State != nil && account.StateDiff != nil {
-			return nil, 0, false, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
 		}
 		// Replace entire state if caller requires.
 		if account.State != nil {
-			state.SetStorage(addr, *account.State)
+			statedb.SetStorage(addr, *account.State)
 		}
 		// Apply state diff into specified accounts.
 		if account.StateDiff != nil {
 			for key, value := range *account.StateDiff {
-				state.SetState(addr, key, value)
+				statedb.SetState(addr, key, value)
 			}
 		}
 	}
-	// Set default gas & gas price if none were set
-	gas := uint64(math.MaxUint64 / 2)
-	if args.Gas != nil {
-		gas = uint64(*args.Gas)
+	return nil
+}
+
+// BlockOverrides is a set of header fields to override during the execution of
+// a message call.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Time       *hexutil.Big    `json:"timestamp"`
+	Coinbase   *common.Address `json:"coinbase"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+}
+
+// Apply overrides the given header fields into the given EVM context.
+func (diff *BlockOverrides) Apply(vmctx *vm.Context) {
+	if diff == nil {
+		return
 	}
-	if globalGasCap != nil && globalGasCap.Uint64() < gas {
-		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", globalGasCap)
-		gas = globalGasCap.Uint64()
+	if diff.Number != nil {
+		vmctx.BlockNumber = diff.Number.ToInt()
 	}
-	gasPrice := new(big.Int).SetUint64(defaultGasPrice)
-	if args.GasPrice != nil {
-		gasPrice = args.GasPrice.ToInt()
+	if diff.Time != nil {
+		vmctx.Time = diff.Time.ToInt()
 	}
-
-	value := new(big.Int)
-	if args.Value != nil {
-		value = args.Value.ToInt()
+	if diff.Coinbase != nil {
+		vmctx.Coinbase = *diff.Coinbase
 	}
-
-	var data []byte
-	if args.Data != nil {
-		data = []byte(*args.Data)
+	if diff.Difficulty != nil {
+		vmctx.Difficulty = diff.Difficulty.ToInt()
+	}
+	if diff.GasLimit != nil {
+		vmctx.GasLimit = uint64(*diff.GasLimit)
 	}
+}
+
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, vmCfg *vm.Config, timeout time.Duration, globalGasCap *big.Int) (*core.ExecutionResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Set sender address or use a default if none specified
+	if args.From == nil {
+		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
+			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+				args.From = &accounts[0].Address
+			}
+		}
+	}
+	// Override the fields of specified contracts before execution.
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+	msg := args.ToMessage(globalGasCap)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -842,10 +973,11 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
-	// Get a new instance of the EVM.
-	evm, vmError, err := b.GetEVM(ctx, msg, state, header)
+	// Get a new instance of the EVM, with the sender funded to pay for the gas.
+	state.SetBalance(msg.From(), math.MaxBig256)
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -857,30 +989,99 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	result, err := core.ApplyMessageResult(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	// If the timer caused an abort, return an appropriate error message
 	if evm.Cancelled() {
-		return nil, 0, false, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+	}
+	return result, err
+}
+
+// revertError is an API error carrying the data returned by a reverted EVM
+// execution, along with the decoded revert reason in its message if available.
+type revertError struct {
+	error
+	reason string // revert reason hex encoded
+}
+
+// ErrorCode returns the JSON error code for a revertal.
+// See: https://github.com/ethereum/wiki/wiki/JSON-RPC-Error-Codes-Improvement-Proposal
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded revert reason.
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
+}
+
+// newRevertError creates a revertError instance with the provided revert data.
+func newRevertError(result *core.ExecutionResult) *revertError {
+	err := errors.New("execution reverted")
+	if reason, errUnpack := abi.UnpackRevert(result.Revert()); errUnpack == nil {
+		err = fmt.Errorf("execution reverted: %v", reason)
+	}
+	return &revertError{
+		error:  err,
+		reason: hexutil.Encode(result.Revert()),
 	}
-	return res, gas, failed, err
 }
 
-// Call executes the given transaction on the state for the given block number.
+// Call executes the given transaction on the state for the given block number or hash.
 //
 // Additionally, the caller can specify a batch of contract for fields overriding.
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *map[common.Address]account) (hexutil.Bytes, error) {
-	var accounts map[common.Address]account
-	if overrides != nil {
-		accounts = *overrides
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, nil, 5*time.Second, s.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	// If the call reverted, return the revert data and the reason if available
+	if result.Err == vm.ErrExecutionReverted {
+		return nil, newRevertError(result)
 	}
-	result, _, _, err := DoCall(ctx, s.b, args, blockNr, accounts, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
-	return (hexutil.Bytes)(result), err
+	return result.Return(), result.Err
+}
+
+// AccessListResult is the outcome of eth_createAccessList: the accounts and
+// storage slots touched by a call, along with the gas it used.
+type AccessListResult struct {
+	AccessList []vm.AccessTuple `json:"accessList"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// CreateAccessList executes the given call on the state of the given block
+// number or hash, defaulting to the pending block, and returns every account
+// and storage slot it touched, plus the gas used.
+//
+// A failing or reverted call still reports its accesses, along with the error.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*AccessListResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	tracer := vm.NewAccessListTracer()
+	result, err := DoCall(ctx, s.b, args, bNrOrHash, nil, &vm.Config{Debug: true, Tracer: tracer}, 5*time.Second, s.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	res := &AccessListResult{
+		AccessList: tracer.AccessList(),
+		GasUsed:    hexutil.Uint64(result.UsedGas),
+	}
+	if result.Err != nil {
+		res.Error = result.Err.Error()
+		if result.Err == vm.ErrExecutionReverted {
+			res.Error = newRevertError(result).Error()
+		}
+	}
+	return res, nil
 }
 
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, gasCap *big.Int) (hexutil.Uint64, error) {
@@ -907,19 +1108,19 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.Bl
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	executable := func(gas uint64) (bool, *core.ExecutionResult) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		_, _, failed, err := DoCall(ctx, b, args, rpc.PendingBlockNumber, nil, vm.Config{}, 0, gasCap)
-		if err != nil || failed {
-			return false
+		result, err := DoCall(ctx, b, args, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), nil, nil, 0, gasCap)
+		if err != nil || result.Failed() {
+			return false, result
 		}
-		return true
+		return true, result
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		if ok, _ := executable(mid); !ok {
 			lo = mid
 		} else {
 			hi = mid
@@ -927,8 +1128,16 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.Bl
 	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
-			return 0, fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction", cap)
+		if ok, result := executable(hi); !ok {
+			// If the execution failed for a reason other than running out of
+			// gas, report it, along with the revert reason if available
+			if result != nil && result.Err != vm.ErrOutOfGas {
+				if result.Err == vm.ErrExecutionReverted {
+					return 0, newRevertError(result)
+				}
+				return 0, result.Err
+			}
+			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
 		}
 	}
 	return hexutil.Uint64(hi), nil
@@ -940,6 +1149,195 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	return DoEstimateGas(ctx, s.b, args, rpc.PendingBlockNumber, s.b.RPCGasCap())
 }
 
+// BundleItem is a single element of a call bundle, either a message call or a
+// signed transaction.
+type BundleItem struct {
+	Call *CallArgs
+	Tx   *types.Transaction
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding signed transactions from
+// their hex encoded RLP form and message calls from call objects.
+func (item *BundleItem) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var blob hexutil.Bytes
+		if err := json.Unmarshal(input, &blob); err != nil {
+			return err
+		}
+		item.Tx = new(types.Transaction)
+		return rlp.DecodeBytes(blob, item.Tx)
+	}
+	item.Call = new(CallArgs)
+	return json.Unmarshal(input, item.Call)
+}
+
+// BundleConfig holds extra parameters to the simulation of a call bundle.
+type BundleConfig struct {
+	StateOverrides *StateOverride  `json:"stateOverrides"`
+	BlockOverrides *BlockOverrides `json:"blockOverrides"`
+	Tracer         *string         `json:"tracer"`
+}
+
+// BundleResult is the outcome of executing a single element of a call bundle.
+type BundleResult struct {
+	TxHash       *common.Hash    `json:"txHash,omitempty"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	ReturnValue  hexutil.Bytes   `json:"returnValue"`
+	Logs         []*types.Log    `json:"logs"`
+	Failed       bool            `json:"failed"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Trace        json.RawMessage `json:"trace,omitempty"`
+}
+
+// CallBundle executes an ordered list of message calls and signed transactions
+// on top of the state of the given block, each seeing the state modifications
+// of the previous ones.
+//
+// Signed transactions are fully validated, while message calls skip the nonce
+// check and their gas price defaults to zero, so their senders only need to be
+// funded with the transferred value. All elements share the gas limit of the
+// block, message calls without a gas allowance get all the gas left in it.
+// Elements failing validation are reported and skipped, without affecting the
+// state.
+//
+// Note, this function doesn't make any changes in the state/blockchain.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, items []BundleItem, blockNr rpc.BlockNumber, config *BundleConfig) ([]*BundleResult, error) {
+	if len(items) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if config == nil {
+		config = new(BundleConfig)
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := config.StateOverrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	// Setup the timeout of the entire bundle, same as with eth_call
+	timeout := 5 * time.Second
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	gasLimit := header.GasLimit
+	if config.BlockOverrides != nil && config.BlockOverrides.GasLimit != nil {
+		gasLimit = uint64(*config.BlockOverrides.GasLimit)
+	}
+	var (
+		signer  = types.MakeSigner(s.b.ChainConfig(), header.Number)
+		results = make([]*BundleResult, len(items))
+		gp      = new(core.GasPool).AddGas(gasLimit)
+	)
+	for i, item := range items {
+		// Convert the bundle element into a message to execute
+		var (
+			msg    core.Message
+			result = new(BundleResult)
+			thash  common.Hash
+		)
+		if item.Tx != nil {
+			if msg, err = item.Tx.AsMessage(signer); err != nil {
+				return nil, fmt.Errorf("bundle item %d: %v", i, err)
+			}
+			thash = item.Tx.Hash()
+			result.TxHash = &thash
+		} else {
+			if item.Call == nil {
+				return nil, fmt.Errorf("bundle item %d: missing call", i)
+			}
+			args := *item.Call
+			if args.GasPrice == nil {
+				args.GasPrice = new(hexutil.Big)
+			}
+			if args.Gas == nil {
+				gas := hexutil.Uint64(gp.Gas())
+				args.Gas = &gas
+			}
+			msg = args.ToMessage(s.b.RPCGasCap())
+
+			// Message calls have no hash to index their logs by, use their position
+			thash = common.BigToHash(big.NewInt(int64(i + 1)))
+		}
+		result.From, result.To = msg.From(), msg.To()
+		results[i] = result
+
+		// Assemble the EVM, setting up tracing and the block overrides if requested
+		vmCfg := new(vm.Config)
+		if config.Tracer != nil {
+			tracer, err := tracers.NewTracer(*config.Tracer)
+			if err != nil {
+				return nil, err
+			}
+			vmCfg.Debug, vmCfg.Tracer = true, tracer
+		}
+		evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, vmCfg)
+		if err != nil {
+			return nil, err
+		}
+		if config.BlockOverrides != nil {
+			vmctx := evm.Context
+			config.BlockOverrides.Apply(&vmctx)
+			evm = vm.NewEVM(vmctx, statedb, evm.ChainConfig(), *vmCfg)
+		}
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		// Execute the message, discarding any state changes if it's invalid
+		statedb.Prepare(thash, header.Hash(), i)
+		snapshot, gasLeft := statedb.Snapshot(), gp.Gas()
+
+		res, err := core.ApplyMessageResult(evm, msg, gp)
+		close(done)
+
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			statedb.RevertToSnapshot(snapshot)
+			*gp = core.GasPool(gasLeft)
+			result.Error = err.Error()
+			continue
+		}
+		statedb.Finalise(s.b.ChainConfig().IsEIP158(header.Number))
+
+		result.GasUsed, result.ReturnValue, result.Failed = hexutil.Uint64(res.UsedGas), res.ReturnData, res.Failed()
+		if res.Failed() {
+			result.Error = res.Err.Error()
+		}
+		if reason, err := abi.UnpackRevert(res.Revert()); err == nil {
+			result.RevertReason = reason
+		}
+		result.Logs = statedb.GetLogs(thash)
+		if item.Tx == nil {
+			for _, log := range result.Logs {
+				log.TxHash = common.Hash{}
+			}
+		}
+		if result.Logs == nil {
+			result.Logs = []*types.Log{}
+		}
+		if tracer, ok := vmCfg.Tracer.(tracers.ResultTracer); ok {
+			if result.Trace, err = tracer.GetResult(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
@@ -1128,8 +1526,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1223,18 +1621,18 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 	return nil
 }
 
-// GetTransactionCount returns the number of transactions the given address has sent for the given block number
-func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
+// GetTransactionCount returns the number of transactions the given address has sent for the given block number or hash
+func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
 	// Ask transaction pool for the nonce which includes pending transactions
-	if blockNr == rpc.PendingBlockNumber {
+	if blockNr, ok := blockNrOrHash.Number(); ok && blockNr == rpc.PendingBlockNumber {
 		nonce, err := s.b.GetPoolNonce(ctx, address)
 		if err != nil {
 			return nil, err
 		}
 		return (*hexutil.Uint64)(&nonce), nil
 	}
-	// Resolve block number and use its state to ask for the nonce
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	// Resolve block number or hash and use its state to ask for the nonce
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -1254,7 +1652,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx), nil
+		return NewRPCPendingTransaction(tx), nil
 	}
 
 	// Transaction unknown, return as such
@@ -1291,8 +1689,12 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
+	return marshalReceipt(receipts[index], blockHash, blockNumber, tx, index), nil
+}
 
+// marshalReceipt converts the receipt of the transaction at the given position
+// of a block into its RPC representation.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, tx *types.Transaction, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -1302,7 +1704,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1326,7 +1728,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1584,7 +1986,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, exists := accounts[from]; exists {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -104,6 +105,101 @@ func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// BanPeer disconnects a remote node and prevents it from reconnecting until the
+// ban expires. The node may be given as enode URL or hex node ID, the duration
+// defaults to one hour.
+func (api *PrivateAdminAPI) BanPeer(node string, duration *string, reason *string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, err := parseNodeID(node)
+	if err != nil {
+		return false, err
+	}
+	d := time.Hour
+	if duration != nil {
+		if d, err = time.ParseDuration(*duration); err != nil {
+			return false, fmt.Errorf("invalid duration: %v", err)
+		}
+		if d <= 0 {
+			return false, fmt.Errorf("invalid duration: %v", d)
+		}
+	}
+	why := "banned by admin"
+	if reason != nil {
+		why = *reason
+	}
+	if err := server.BanPeer(id, d, why); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanPeer lifts the ban of a remote node.
+func (api *PrivateAdminAPI) UnbanPeer(node string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, err := parseNodeID(node)
+	if err != nil {
+		return false, err
+	}
+	if err := server.UnbanPeer(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListBans retrieves all active node bans.
+func (api *PrivateAdminAPI) ListBans() ([]*enode.Ban, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Bans(), nil
+}
+
+// SetPeerPolicy replaces the policy restricting which peers may connect. Peers
+// violating the new policy are disconnected.
+func (api *PrivateAdminAPI) SetPeerPolicy(policy *p2p.PeerPolicy) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.SetPeerPolicy(policy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PeerPolicy retrieves the current peer policy.
+func (api *PrivateAdminAPI) PeerPolicy() (*p2p.PeerPolicy, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Policy(), nil
+}
+
+// parseNodeID parses a node given either as enode URL, node record or hex ID.
+func parseNodeID(node string) (enode.ID, error) {
+	if n, err := enode.Parse(enode.ValidSchemes, node); err == nil {
+		return n.ID(), nil
+	}
+	var id enode.ID
+	if err := id.UnmarshalText([]byte(node)); err != nil {
+		return id, fmt.Errorf("invalid enode or node ID: %v", err)
+	}
+	return id, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -186,7 +282,7 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, api.node.config.HTTPTimeouts); err != nil {
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, api.node.config.HTTPTimeouts, api.node.config.RPCLimits, api.node.config.HTTPJWTSecret); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -240,7 +336,7 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 		}
 	}
 
-	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll); err != nil {
+	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll, api.node.config.RPCLimits, api.node.config.WSJWTSecret); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -280,6 +376,16 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerStats retrieves the traffic counters of each connected peer, broken down
+// by protocol and message code, along with their sums over all peers.
+func (api *PublicAdminAPI) PeerStats() (*p2p.TrafficStats, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.TrafficStats(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -18,6 +18,8 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -136,6 +138,16 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPPathPrefix is the URL path under which the HTTP RPC interface is served,
+	// the root path if empty.
+	HTTPPathPrefix string `toml:",omitempty"`
+
+	// HTTPJWTSecret is the path to a file holding the hex encoded 32 byte secret
+	// used to authenticate HTTP RPC requests with HS256 JWT tokens. If the file
+	// does not exist, a random secret is generated and saved. If the path is
+	// empty, authentication is disabled.
+	HTTPJWTSecret string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -155,6 +167,11 @@ type Config struct {
 	// exposed.
 	WSModules []string `toml:",omitempty"`
 
+	// WSPathPrefix is the URL path under which websocket connections are accepted,
+	// the root path if empty. If the websocket and HTTP endpoints are the same, both
+	// are served on a single listener, upgrading websocket requests on this path.
+	WSPathPrefix string `toml:",omitempty"`
+
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
 	// than just the public ones.
 	//
@@ -162,6 +179,17 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSJWTSecret is the path to a file holding the hex encoded 32 byte secret
+	// used to authenticate websocket RPC handshakes with HS256 JWT tokens. If the
+	// file does not exist, a random secret is generated and saved. If the path
+	// is empty, authentication is disabled.
+	WSJWTSecret string `toml:",omitempty"`
+
+	// RPCLimits are the resource limits enforced on clients of the HTTP and websocket
+	// RPC interfaces, such as the maximum batch and response sizes. The zero value
+	// disables all limits.
+	RPCLimits rpc.Limits
+
 	// GraphQLHost is the host interface on which to start the GraphQL server. If this
 	// field is empty, no GraphQL API endpoint will be started.
 	GraphQLHost string `toml:",omitempty"`
@@ -176,6 +204,10 @@ type Config struct {
 	// useless for custom HTTP clients.
 	GraphQLCors []string `toml:",omitempty"`
 
+	// GraphQLPathPrefix is the URL path under which GraphQL queries are served if
+	// the GraphQL and HTTP endpoints are the same, "/graphql" if empty.
+	GraphQLPathPrefix string `toml:",omitempty"`
+
 	// GraphQLVirtualHosts is the list of virtual hostnames which are allowed on incoming requests.
 	// This is by default {'localhost'}. Using this prevents attacks like
 	// DNS rebinding, which bypasses SOP by simply masquerading as being within the same
@@ -391,6 +423,40 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// jwtSecret loads the hex encoded JWT secret from the given file, generating and
+// persisting a new random one if the file does not exist yet. An empty path
+// disables authentication and returns a nil secret.
+func (c *Config) jwtSecret(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if blob, err := ioutil.ReadFile(path); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+		}
+		if len(secret) != rpc.JWTSecretLength {
+			return nil, fmt.Errorf("invalid JWT secret length in %s: have %d bytes, want %d", path, len(secret), rpc.JWTSecretLength)
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No secret found, generate and store a new one
+	secret := make([]byte, rpc.JWTSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
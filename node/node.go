@@ -59,13 +59,14 @@ type Node struct {
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
 	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
-	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
-	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
-	httpListener  net.Listener // HTTP RPC listener socket to server API requests
-	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpEndpoint  string          // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
+	httpWhitelist []string        // HTTP RPC modules to allow through this endpoint
+	httpListener  net.Listener    // HTTP RPC listener socket to server API requests
+	httpHandler   *rpc.Server     // HTTP RPC request handler to process the API requests
+	httpRoutes    []rpc.HTTPRoute // HTTP handlers of services served next to the RPC API
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
-	wsListener net.Listener // Websocket RPC listener socket to server API requests
+	wsListener net.Listener // Websocket RPC listener socket to server API requests (nil if shared with HTTP)
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
 	stop chan struct{} // Channel to wait for termination notifications
@@ -291,17 +292,46 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts); err != nil {
-		n.stopIPC()
-		n.stopInProc()
-		return err
+	// Gather the HTTP handlers of the services to serve next to the RPC API
+	n.httpRoutes = nil
+	for kind, service := range services {
+		if service, ok := service.(HTTPService); ok {
+			if prefix, handler := service.HTTPHandler(); handler != nil {
+				if n.httpEndpoint == "" {
+					n.log.Warn("HTTP service not served, HTTP endpoint disabled", "service", kind)
+					continue
+				}
+				n.httpRoutes = append(n.httpRoutes, rpc.HTTPRoute{Prefix: prefix, Handler: handler})
+			}
+		}
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
-		n.stopHTTP()
+	// If the websocket endpoint coincides with the HTTP one, serve both on the same
+	// listener, upgrading websocket requests on the configured path.
+	var extra []rpc.HTTPRoute
+	if n.wsEndpoint != "" && n.wsEndpoint == n.httpEndpoint {
+		handler, route, err := n.newWSRoute(apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, n.config.RPCLimits, n.config.WSJWTSecret)
+		if err != nil {
+			n.stopIPC()
+			n.stopInProc()
+			return err
+		}
+		n.wsHandler = handler
+		extra = append(extra, route)
+	}
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts, n.config.RPCLimits, n.config.HTTPJWTSecret, extra...); err != nil {
+		n.stopWS()
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
+	if n.wsHandler == nil {
+		if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, n.config.RPCLimits, n.config.WSJWTSecret); err != nil {
+			n.stopHTTP()
+			n.stopIPC()
+			n.stopInProc()
+			return err
+		}
+	}
 	// All API endpoints started successfully
 	n.rpcAPIs = apis
 	return nil
@@ -358,17 +388,38 @@ func (n *Node) stopIPC() {
 	}
 }
 
-// startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) error {
+// startHTTP initializes and starts the HTTP RPC endpoint, additionally serving the
+// HTTP handlers of the services and any extra routes given.
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts, limits rpc.Limits, jwtSecretPath string, extra ...rpc.HTTPRoute) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	secret, err := n.config.jwtSecret(jwtSecretPath)
 	if err != nil {
 		return err
 	}
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	handler, err := rpc.NewAPIServer(apis, modules, false, limits)
+	if err != nil {
+		return err
+	}
+	routes := []rpc.HTTPRoute{{Prefix: n.config.HTTPPathPrefix, Handler: rpc.NewHTTPHandler(handler, cors, vhosts, secret)}}
+	for _, route := range n.httpRoutes {
+		// Service handlers share the listener, so they must share its authentication too
+		route.Handler = rpc.NewJWTHandler(secret, route.Handler)
+		routes = append(routes, route)
+	}
+	routes = append(routes, extra...)
+
+	listener, err := rpc.StartHTTPRouter(endpoint, timeouts, routes)
+	if err != nil {
+		handler.Stop()
+		return err
+	}
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "auth", secret != nil)
+	for _, route := range routes[1:] {
+		n.log.Info("HTTP route registered", "url", fmt.Sprintf("http://%s%s", endpoint, route.Prefix), "websocket", route.Websocket)
+	}
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -389,19 +440,47 @@ func (n *Node) stopHTTP() {
 		n.httpHandler.Stop()
 		n.httpHandler = nil
 	}
+	// Websocket requests served by the HTTP listener are gone with it
+	if n.wsListener == nil && n.wsHandler != nil {
+		n.stopWS()
+	}
+}
+
+// newWSRoute creates the websocket RPC request handler and the HTTP route serving
+// it under the configured path prefix.
+func (n *Node) newWSRoute(apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, limits rpc.Limits, jwtSecretPath string) (*rpc.Server, rpc.HTTPRoute, error) {
+	secret, err := n.config.jwtSecret(jwtSecretPath)
+	if err != nil {
+		return nil, rpc.HTTPRoute{}, err
+	}
+	handler, err := rpc.NewAPIServer(apis, modules, exposeAll, limits)
+	if err != nil {
+		return nil, rpc.HTTPRoute{}, err
+	}
+	route := rpc.HTTPRoute{
+		Prefix:    n.config.WSPathPrefix,
+		Handler:   rpc.NewWSHandler(handler, wsOrigins, secret),
+		Websocket: true,
+	}
+	return handler, route, nil
 }
 
 // startWS initializes and starts the websocket RPC endpoint.
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, limits rpc.Limits, jwtSecretPath string) error {
 	// Short circuit if the WS endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	handler, route, err := n.newWSRoute(apis, modules, wsOrigins, exposeAll, limits, jwtSecretPath)
+	if err != nil {
+		return err
+	}
+	listener, err := rpc.StartHTTPRouter(endpoint, rpc.DefaultHTTPTimeouts, []rpc.HTTPRoute{route})
 	if err != nil {
+		handler.Stop()
 		return err
 	}
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s%s", listener.Addr(), route.Prefix), "auth", jwtSecretPath != "")
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
 	n.wsListener = listener
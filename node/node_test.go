@@ -17,9 +17,13 @@
 package node
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -597,3 +601,135 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that HTTP and websocket RPC as well as the HTTP handlers of services can
+// be served on a single port, routed by path and exposing their own modules.
+func TestSharedHTTPEndpoint(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost, config.HTTPPathPrefix, config.HTTPModules = "127.0.0.1", "/rpc", []string{"http"}
+	config.WSHost, config.WSPathPrefix, config.WSModules = "127.0.0.1", "/ws", []string{"ws"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	constructor := func(*ServiceContext) (Service, error) {
+		return &HTTPTestService{
+			apis: []rpc.API{
+				{Namespace: "http", Version: "1", Service: new(OneMethodAPI)},
+				{Namespace: "ws", Version: "1", Service: new(OneMethodAPI)},
+			},
+			prefix:   "/custom",
+			response: "hello",
+		}, nil
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	if stack.wsListener != nil {
+		t.Fatalf("websocket endpoint opened a separate listener")
+	}
+	addr := stack.httpListener.Addr().String()
+
+	// Ensure each RPC route only exposes its own modules
+	tests := []struct {
+		url     string
+		allowed string
+		denied  string
+	}{
+		{"http://" + addr + "/rpc", "http_theOneMethod", "ws_theOneMethod"},
+		{"ws://" + addr + "/ws", "ws_theOneMethod", "http_theOneMethod"},
+	}
+	for i, test := range tests {
+		client, err := rpc.Dial(test.url)
+		if err != nil {
+			t.Fatalf("test %d: failed to dial %s: %v", i, test.url, err)
+		}
+		if err := client.Call(nil, test.allowed); err != nil {
+			t.Errorf("test %d: allowed call failed: %v", i, err)
+		}
+		if err := client.Call(nil, test.denied); err == nil {
+			t.Errorf("test %d: denied call succeeded", i)
+		}
+		client.Close()
+	}
+	// Ensure the service handler is served on its own path
+	resp, err := http.Get("http://" + addr + "/custom")
+	if err != nil {
+		t.Fatalf("failed to query service handler: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "hello" {
+		t.Errorf("service handler response mismatch: have %q, want %q", body, "hello")
+	}
+}
+
+// Tests that the HTTP handlers of services require the same JWT authentication as
+// the RPC API if they are served on an authenticated HTTP endpoint.
+func TestSharedHTTPEndpointJWT(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := bytes.Repeat([]byte{0x42}, rpc.JWTSecretLength)
+	path := filepath.Join(dir, "jwtsecret")
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		t.Fatalf("failed to write JWT secret: %v", err)
+	}
+	config := testNodeConfig()
+	config.HTTPHost, config.HTTPJWTSecret = "127.0.0.1", path
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	constructor := func(*ServiceContext) (Service, error) {
+		return &HTTPTestService{prefix: "/custom", response: "hello"}, nil
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	url := "http://" + stack.httpListener.Addr().String() + "/custom"
+
+	// Ensure unauthenticated requests are rejected
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("failed to query service handler: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status mismatch: have %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	// Ensure authenticated requests are served
+	req, _ := http.NewRequest("GET", url, nil)
+	if err := rpc.NewJWTAuth(secret)(req.Header); err != nil {
+		t.Fatalf("failed to authenticate request: %v", err)
+	}
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("failed to query service handler: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "hello" {
+		t.Errorf("service handler response mismatch: have %q, want %q", body, "hello")
+	}
+}
@@ -17,6 +17,7 @@
 package node
 
 import (
+	"net/http"
 	"path/filepath"
 	"reflect"
 
@@ -120,3 +121,12 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// HTTPService is an optional interface for services serving plain HTTP requests
+// on the HTTP endpoint of the node, next to the RPC API. If the endpoint requires
+// JWT authentication, so do the requests routed to the service.
+type HTTPService interface {
+	// HTTPHandler retrieves the path prefix and the handler to serve under it. It
+	// is called after the service is started. A nil handler serves nothing.
+	HTTPHandler() (prefix string, handler http.Handler)
+}
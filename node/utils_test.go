@@ -20,6 +20,7 @@
 package node
 
 import (
+	"net/http"
 	"reflect"
 
 	"github.com/ethereum/go-ethereum/p2p"
@@ -131,3 +132,21 @@ func (api *OneMethodAPI) TheOneMethod() {
 		api.fun()
 	}
 }
+
+// HTTPTestService is a service exposing the given APIs and serving a fixed
+// response on the HTTP endpoint of the node under the given path prefix.
+type HTTPTestService struct {
+	NoopService
+
+	apis     []rpc.API
+	prefix   string
+	response string
+}
+
+func (s *HTTPTestService) APIs() []rpc.API { return s.apis }
+
+func (s *HTTPTestService) HTTPHandler() (string, http.Handler) {
+	return s.prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(s.response))
+	})
+}
@@ -71,6 +71,8 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	banned      func(enode.ID) bool                         // reports whether a node is banned, may be nil
+	policy      func(*enode.Node, map[enode.ID]*Peer) error // checks the peer policy, may be nil
 	self        enode.ID
 	bootnodes   []*enode.Node // default dials when there are no peers
 	log         log.Logger
@@ -249,6 +251,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errBanned           = errors.New("banned")
 )
 
 func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
@@ -264,6 +267,10 @@ func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
 		return errNotWhitelisted
 	case s.hist.contains(string(n.ID().Bytes())):
 		return errRecentlyDialed
+	case s.banned != nil && s.banned(n.ID()):
+		return errBanned
+	case s.policy != nil:
+		return s.policy(n, peers)
 	}
 	return nil
 }
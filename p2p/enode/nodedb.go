@@ -40,6 +40,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbBanPrefix    = "ban:" // Identifier to prefix node bans with, the full key is "ban:<ID>"
 	dbDiscoverRoot = "v4"
 
 	// These fields are stored per ID and IP, the full key is "n:<ID>:v4:<IP>:findfail".
@@ -300,6 +301,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireBans()
 		case <-db.quit:
 			return
 		}
@@ -440,6 +442,84 @@ func nextNode(it iterator.Iterator) *Node {
 	return nil
 }
 
+// Ban describes a node which is not allowed to connect until the ban expires.
+type Ban struct {
+	ID     ID        `json:"id"`
+	Expiry time.Time `json:"expiry"`
+	Reason string    `json:"reason"`
+}
+
+// banEntry is the database representation of a Ban.
+type banEntry struct {
+	Expiry uint64 // Unix time
+	Reason string
+}
+
+// banKey returns the database key of a node ban.
+func banKey(id ID) []byte {
+	return append([]byte(dbBanPrefix), id[:]...)
+}
+
+// Ban retrieves the active ban of a node, or nil if the node is not banned.
+func (db *DB) Ban(id ID) *Ban {
+	blob, err := db.lvl.Get(banKey(id), nil)
+	if err != nil {
+		return nil
+	}
+	return db.decodeBan(id, blob, time.Now())
+}
+
+// decodeBan decodes a stored ban, deleting it if it is malformed or expired.
+func (db *DB) decodeBan(id ID, blob []byte, now time.Time) *Ban {
+	var entry banEntry
+	if err := rlp.DecodeBytes(blob, &entry); err != nil || int64(entry.Expiry) <= now.Unix() {
+		db.lvl.Delete(banKey(id), nil)
+		return nil
+	}
+	return &Ban{ID: id, Expiry: time.Unix(int64(entry.Expiry), 0), Reason: entry.Reason}
+}
+
+// UpdateBan bans a node until the given time, overwriting any previous ban.
+func (db *DB) UpdateBan(id ID, expiry time.Time, reason string) error {
+	blob, err := rlp.EncodeToBytes(&banEntry{Expiry: uint64(expiry.Unix()), Reason: reason})
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(banKey(id), blob, nil)
+}
+
+// DeleteBan lifts the ban of a node.
+func (db *DB) DeleteBan(id ID) error {
+	return db.lvl.Delete(banKey(id), nil)
+}
+
+// Bans returns all active bans.
+func (db *DB) Bans() []*Ban {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanPrefix)), nil)
+	defer it.Release()
+
+	var (
+		bans []*Ban
+		now  = time.Now()
+	)
+	for it.Next() {
+		var id ID
+		if len(it.Key()) != len(dbBanPrefix)+len(id) {
+			continue
+		}
+		copy(id[:], it.Key()[len(dbBanPrefix):])
+		if ban := db.decodeBan(id, it.Value(), now); ban != nil {
+			bans = append(bans, ban)
+		}
+	}
+	return bans
+}
+
+// expireBans deletes all expired bans from the database.
+func (db *DB) expireBans() {
+	db.Bans()
+}
+
 // close flushes and closes the database files.
 func (db *DB) Close() {
 	close(db.quit)
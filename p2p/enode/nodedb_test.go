@@ -462,3 +462,39 @@ func TestDBExpiration(t *testing.T) {
 		}
 	}
 }
+
+func TestDBBans(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		active  = ID{1}
+		expired = ID{2}
+		expiry  = time.Now().Add(time.Hour).Truncate(time.Second)
+	)
+	if err := db.UpdateBan(active, expiry, "bad block"); err != nil {
+		t.Fatalf("failed to store ban: %v", err)
+	}
+	if err := db.UpdateBan(expired, time.Now().Add(-time.Second), "timeout"); err != nil {
+		t.Fatalf("failed to store ban: %v", err)
+	}
+	ban := db.Ban(active)
+	if ban == nil {
+		t.Fatal("active ban not found")
+	}
+	if !ban.Expiry.Equal(expiry) || ban.Reason != "bad block" {
+		t.Errorf("wrong ban: %+v", ban)
+	}
+	if db.Ban(expired) != nil {
+		t.Error("expired ban returned")
+	}
+	if bans := db.Bans(); len(bans) != 1 || bans[0].ID != active {
+		t.Errorf("wrong ban list: %v", bans)
+	}
+	if err := db.DeleteBan(active); err != nil {
+		t.Fatalf("failed to delete ban: %v", err)
+	}
+	if db.Ban(active) != nil {
+		t.Error("deleted ban returned")
+	}
+}
@@ -236,3 +236,126 @@ func (c *meteredConn) Close() error {
 	activePeerCounter.Dec(1)
 	return err
 }
+
+// baseProtocol describes the base protocol for the traffic statistics, base
+// protocol messages (ping, pong, disconnect) are accounted under its name.
+var baseProtocol = Protocol{Name: "p2p", Version: baseProtocolVersion, Length: baseProtocolLength}
+
+// MsgStats contains the message and byte counters of traffic exchanged with a
+// peer. Byte counts are message payload sizes before compression.
+type MsgStats struct {
+	IngressMessages uint64 `json:"ingressMessages"`
+	IngressBytes    uint64 `json:"ingressBytes"`
+	EgressMessages  uint64 `json:"egressMessages"`
+	EgressBytes     uint64 `json:"egressBytes"`
+}
+
+// add accumulates the counters of another set of statistics.
+func (s *MsgStats) add(other *MsgStats) {
+	s.IngressMessages += other.IngressMessages
+	s.IngressBytes += other.IngressBytes
+	s.EgressMessages += other.EgressMessages
+	s.EgressBytes += other.EgressBytes
+}
+
+// ProtocolStats contains the traffic counters of a single protocol, both in
+// total and broken down by message code. Message codes are hex encoded and
+// relative to the protocol, i.e. they are not offset by preceding protocols.
+type ProtocolStats struct {
+	MsgStats
+	Messages map[string]*MsgStats `json:"messages"`
+}
+
+// add accumulates the counters of another set of protocol statistics.
+func (s *ProtocolStats) add(other *ProtocolStats) {
+	s.MsgStats.add(&other.MsgStats)
+	for code, stats := range other.Messages {
+		if s.Messages[code] == nil {
+			s.Messages[code] = new(MsgStats)
+		}
+		s.Messages[code].add(stats)
+	}
+}
+
+// PeerStats contains the traffic counters of a connected peer.
+type PeerStats struct {
+	ID            string                    `json:"id"`            // Unique node identifier
+	Name          string                    `json:"name"`          // Name of the node as advertised in the handshake
+	RemoteAddress string                    `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+	Traffic       MsgStats                  `json:"traffic"`       // Totals over all protocols
+	Protocols     map[string]*ProtocolStats `json:"protocols"`     // Counters per protocol name
+}
+
+// TrafficStats is the traffic summary of all connected peers.
+type TrafficStats struct {
+	Peers     []*PeerStats              `json:"peers"`     // Counters of individual peers, sorted by ID
+	Protocols map[string]*ProtocolStats `json:"protocols"` // Counters of all peers, summed per protocol
+}
+
+// peerTraffic accounts the messages exchanged with a single peer. If metrics
+// collection is enabled, the messages are also marked on per message code
+// meters shared by all peers.
+type peerTraffic struct {
+	total  MsgStats
+	protos map[string]map[uint64]*MsgStats
+	lock   sync.Mutex
+}
+
+func newPeerTraffic() *peerTraffic {
+	return &peerTraffic{protos: make(map[string]map[uint64]*MsgStats)}
+}
+
+// mark accounts a single message of the given protocol.
+func (t *peerTraffic) mark(proto Protocol, code uint64, size uint32, ingress bool) {
+	t.lock.Lock()
+	codes := t.protos[proto.Name]
+	if codes == nil {
+		codes = make(map[uint64]*MsgStats)
+		t.protos[proto.Name] = codes
+	}
+	stats := codes[code]
+	if stats == nil {
+		stats = new(MsgStats)
+		codes[code] = stats
+	}
+	if ingress {
+		t.total.IngressMessages++
+		t.total.IngressBytes += uint64(size)
+		stats.IngressMessages++
+		stats.IngressBytes += uint64(size)
+	} else {
+		t.total.EgressMessages++
+		t.total.EgressBytes += uint64(size)
+		stats.EgressMessages++
+		stats.EgressBytes += uint64(size)
+	}
+	t.lock.Unlock()
+
+	if metrics.Enabled {
+		name := MetricsOutboundTraffic
+		if ingress {
+			name = MetricsInboundTraffic
+		}
+		name = fmt.Sprintf("%s/%s/%d/0x%02x", name, proto.Name, proto.Version, code)
+		metrics.GetOrRegisterMeter(name, nil).Mark(int64(size))
+		metrics.GetOrRegisterMeter(name+"/packets", nil).Mark(1)
+	}
+}
+
+// stats returns a snapshot of the traffic counters.
+func (t *peerTraffic) stats() (MsgStats, map[string]*ProtocolStats) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	protos := make(map[string]*ProtocolStats, len(t.protos))
+	for name, codes := range t.protos {
+		proto := &ProtocolStats{Messages: make(map[string]*MsgStats, len(codes))}
+		for code, stats := range codes {
+			cpy := *stats
+			proto.Messages[fmt.Sprintf("0x%02x", code)] = &cpy
+			proto.MsgStats.add(stats)
+		}
+		protos[name] = proto
+	}
+	return t.total, protos
+}
@@ -115,6 +115,12 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// traffic accounts the messages exchanged with the peer
+	traffic *peerTraffic
+
+	// scorer records violations of the peer if set
+	scorer func(*Peer, Violation, string)
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -158,6 +164,14 @@ func (p *Peer) LocalAddr() net.Addr {
 	return p.rw.fd.LocalAddr()
 }
 
+// Penalize records a misbehaviour of the peer. Peers which misbehave
+// repeatedly are disconnected and banned for a while.
+func (p *Peer) Penalize(v Violation, reason string) {
+	if p.scorer != nil {
+		p.scorer(p, v, reason)
+	}
+}
+
 // Disconnect terminates the peer connection with the given reason.
 // It returns immediately and does not wait until the connection is closed.
 func (p *Peer) Disconnect(reason DiscReason) {
@@ -188,6 +202,7 @@ func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 		protoErr: make(chan error, len(protomap)+1), // protocols + pingLoop
 		closed:   make(chan struct{}),
 		log:      log.New("id", conn.node.ID(), "conn", conn.flags),
+		traffic:  newPeerTraffic(),
 	}
 	return p
 }
@@ -253,7 +268,7 @@ func (p *Peer) pingLoop() {
 	for {
 		select {
 		case <-ping.C:
-			if err := SendItems(p.rw, pingMsg); err != nil {
+			if err := p.sendBase(pingMsg); err != nil {
 				p.protoErr <- err
 				return
 			}
@@ -280,11 +295,24 @@ func (p *Peer) readLoop(errc chan<- error) {
 	}
 }
 
+// sendBase sends an empty base protocol message.
+func (p *Peer) sendBase(code uint64) error {
+	size, r, err := rlp.EncodeToReader([]interface{}{})
+	if err != nil {
+		return err
+	}
+	p.traffic.mark(baseProtocol, code, uint32(size), false)
+	return p.rw.WriteMsg(Msg{Code: code, Size: uint32(size), Payload: r})
+}
+
 func (p *Peer) handle(msg Msg) error {
+	if msg.Code < baseProtocolLength {
+		p.traffic.mark(baseProtocol, msg.Code, msg.Size, true)
+	}
 	switch {
 	case msg.Code == pingMsg:
 		msg.Discard()
-		go SendItems(p.rw, pongMsg)
+		go p.sendBase(pongMsg)
 	case msg.Code == discMsg:
 		var reason [1]DiscReason
 		// This is the last message. We don't need to discard or
@@ -300,6 +328,7 @@ func (p *Peer) handle(msg Msg) error {
 		if err != nil {
 			return fmt.Errorf("msg code out of range: %v", msg.Code)
 		}
+		p.traffic.mark(proto.Protocol, msg.Code-proto.offset, msg.Size, true)
 		select {
 		case proto.in <- msg:
 			return nil
@@ -354,6 +383,7 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		proto.closed = p.closed
 		proto.wstart = writeStart
 		proto.werr = writeErr
+		proto.traffic = p.traffic
 		var rw MsgReadWriter = proto
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name, p.Info().Network.RemoteAddress, p.Info().Network.LocalAddress)
@@ -392,16 +422,22 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+
+	traffic *peerTraffic // accounts written messages, nil if not running
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	if msg.Code >= rw.Length {
 		return newPeerError(errInvalidMsgCode, "not handled")
 	}
+	code := msg.Code
 	msg.Code += rw.offset
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
+		if err == nil && rw.traffic != nil {
+			rw.traffic.mark(rw.Protocol, code, msg.Size, false)
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -423,6 +459,18 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	}
 }
 
+// Stats returns the traffic counters of the peer, broken down by protocol and
+// message code.
+func (p *Peer) Stats() *PeerStats {
+	stats := &PeerStats{
+		ID:            p.ID().String(),
+		Name:          p.Name(),
+		RemoteAddress: p.RemoteAddr().String(),
+	}
+	stats.Traffic, stats.Protocols = p.traffic.stats()
+	return stats
+}
+
 // PeerInfo represents a short summary of the information known about a connected
 // peer. Sub-protocol independent fields are contained and initialized here, with
 // protocol specifics delegated to all connected sub-protocols.
@@ -440,6 +488,7 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Traffic   MsgStats               `json:"traffic"`   // Messages exchanged with the peer over all protocols
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -465,6 +514,7 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	info.Traffic, _ = p.traffic.stats()
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
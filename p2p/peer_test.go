@@ -122,6 +122,51 @@ func TestPeerProtoEncodeMsg(t *testing.T) {
 	}
 }
 
+func TestPeerTrafficStats(t *testing.T) {
+	done := make(chan struct{})
+	proto := Protocol{
+		Name:    "a",
+		Version: 1,
+		Length:  5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+				t.Error(err)
+			}
+			if err := ExpectMsg(rw, 2, []uint{2}); err != nil {
+				t.Error(err)
+			}
+			if err := SendItems(rw, 3, "foo"); err != nil {
+				t.Error(err)
+			}
+			close(done)
+			<-peer.closed
+			return nil
+		},
+	}
+	closer, rw, peer, _ := testPeer([]Protocol{proto})
+	defer closer()
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	Send(rw, baseProtocolLength+2, []uint{2})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	stats := peer.Stats()
+	want := MsgStats{IngressMessages: 2, IngressBytes: 4, EgressMessages: 1, EgressBytes: 5}
+	if stats.Traffic != want {
+		t.Errorf("wrong total traffic: have %+v, want %+v", stats.Traffic, want)
+	}
+	msgs := stats.Protocols["a"].Messages
+	if have, want := *msgs["0x02"], (MsgStats{IngressMessages: 2, IngressBytes: 4}); have != want {
+		t.Errorf("wrong traffic for code 2: have %+v, want %+v", have, want)
+	}
+	if have, want := *msgs["0x03"], (MsgStats{EgressMessages: 1, EgressBytes: 5}); have != want {
+		t.Errorf("wrong traffic for code 3: have %+v, want %+v", have, want)
+	}
+}
+
 func TestPeerPing(t *testing.T) {
 	closer, rw, _, _ := testPeer(nil)
 	defer closer()
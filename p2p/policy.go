@@ -0,0 +1,325 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// Prefix lengths of the subnets counted by MaxPerSubnet.
+	policySubnetBits4 = 24
+	policySubnetBits6 = 64
+)
+
+var (
+	errPolicyNet    = errors.New("rejected by peer policy: network")
+	errPolicyENR    = errors.New("rejected by peer policy: missing ENR entry")
+	errPolicySubnet = errors.New("rejected by peer policy: too many peers in subnet")
+)
+
+// PeerPolicy restricts the peers the server connects to. Inbound and dialed
+// connections are checked against separate rules, a nil rule set accepts any
+// peer. Trusted peers are exempt from the policy.
+type PeerPolicy struct {
+	Inbound  *PolicyRules `json:"inbound,omitempty"  toml:",omitempty"`
+	Outbound *PolicyRules `json:"outbound,omitempty" toml:",omitempty"`
+}
+
+// PolicyRules are the conditions a peer has to satisfy in order to be accepted.
+// Empty fields don't restrict anything.
+type PolicyRules struct {
+	// AllowNets and DenyNets are lists of CIDR masks. If AllowNets is set, the
+	// peer address must be contained in one of its networks. Peers in any of the
+	// DenyNets are rejected.
+	AllowNets []string `json:"allowNets,omitempty" toml:",omitempty"`
+	DenyNets  []string `json:"denyNets,omitempty"  toml:",omitempty"`
+
+	// AllowNames and DenyNames are regular expressions matched against the
+	// client name announced in the protocol handshake.
+	AllowNames []string `json:"allowNames,omitempty" toml:",omitempty"`
+	DenyNames  []string `json:"denyNames,omitempty"  toml:",omitempty"`
+
+	// RequireCaps lists capabilities the peer must support, either as bare
+	// protocol name ("eth") or with version ("eth/63").
+	RequireCaps []string `json:"requireCaps,omitempty" toml:",omitempty"`
+
+	// RequireENR lists keys which must be present in the node record of the
+	// peer. Peers whose record is unknown don't satisfy this condition.
+	RequireENR []string `json:"requireENR,omitempty" toml:",omitempty"`
+
+	// MaxPerSubnet limits the number of connected peers in the same /24 (IPv4)
+	// or /64 (IPv6) network.
+	MaxPerSubnet int `json:"maxPerSubnet,omitempty" toml:",omitempty"`
+}
+
+// peerPolicy is the compiled form of PeerPolicy.
+type peerPolicy struct {
+	config            *PeerPolicy
+	inbound, outbound *policyRules
+}
+
+type policyRules struct {
+	allowNets, denyNets   *netutil.Netlist
+	allowNames, denyNames []*regexp.Regexp
+	caps                  []Cap // a zero version matches any version
+	enr                   []string
+	maxPerSubnet          int
+}
+
+func newPeerPolicy(config *PeerPolicy) (*peerPolicy, error) {
+	pol := &peerPolicy{config: config}
+	if config == nil {
+		return pol, nil
+	}
+	var err error
+	if pol.inbound, err = newPolicyRules(config.Inbound); err != nil {
+		return nil, fmt.Errorf("inbound: %v", err)
+	}
+	if pol.outbound, err = newPolicyRules(config.Outbound); err != nil {
+		return nil, fmt.Errorf("outbound: %v", err)
+	}
+	return pol, nil
+}
+
+func newPolicyRules(config *PolicyRules) (*policyRules, error) {
+	if config == nil {
+		return nil, nil
+	}
+	var (
+		r   = &policyRules{enr: config.RequireENR, maxPerSubnet: config.MaxPerSubnet}
+		err error
+	)
+	if r.allowNets, err = parsePolicyNets(config.AllowNets); err != nil {
+		return nil, err
+	}
+	if r.denyNets, err = parsePolicyNets(config.DenyNets); err != nil {
+		return nil, err
+	}
+	if r.allowNames, err = parsePolicyNames(config.AllowNames); err != nil {
+		return nil, err
+	}
+	if r.denyNames, err = parsePolicyNames(config.DenyNames); err != nil {
+		return nil, err
+	}
+	for _, s := range config.RequireCaps {
+		cap := Cap{Name: s}
+		if i := strings.IndexByte(s, '/'); i >= 0 {
+			v, err := strconv.ParseUint(s[i+1:], 10, 32)
+			if err != nil || v == 0 {
+				return nil, fmt.Errorf("invalid capability %q", s)
+			}
+			cap = Cap{Name: s[:i], Version: uint(v)}
+		}
+		r.caps = append(r.caps, cap)
+	}
+	if r.maxPerSubnet < 0 {
+		return nil, fmt.Errorf("invalid subnet limit %d", r.maxPerSubnet)
+	}
+	return r, nil
+}
+
+func parsePolicyNets(masks []string) (*netutil.Netlist, error) {
+	if len(masks) == 0 {
+		return nil, nil
+	}
+	return netutil.ParseNetlist(strings.Join(masks, ","))
+}
+
+func parsePolicyNames(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %v", expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// rules returns the rules applying to connections in the given direction.
+func (pol *peerPolicy) rules(inbound bool) *policyRules {
+	if pol == nil {
+		return nil
+	}
+	if inbound {
+		return pol.inbound
+	}
+	return pol.outbound
+}
+
+// checkNode checks the address and record of a node before the protocol handshake.
+func (r *policyRules) checkNode(n *enode.Node, ip net.IP, peers map[enode.ID]*Peer) error {
+	if r == nil {
+		return nil
+	}
+	if ip == nil {
+		ip = n.IP()
+	}
+	if ip != nil {
+		if r.allowNets != nil && !r.allowNets.Contains(ip) {
+			return errPolicyNet
+		}
+		if r.denyNets != nil && r.denyNets.Contains(ip) {
+			return errPolicyNet
+		}
+	}
+	for _, key := range r.enr {
+		if err := n.Load(enr.WithEntry(key, new(rlp.RawValue))); err != nil {
+			return errPolicyENR
+		}
+	}
+	if r.maxPerSubnet > 0 && ip != nil {
+		bits := uint(policySubnetBits6)
+		if ip.To4() != nil {
+			bits = policySubnetBits4
+		}
+		count := 0
+		for id, p := range peers {
+			if id == n.ID() {
+				continue
+			}
+			if pip := netutil.AddrIP(p.RemoteAddr()); pip != nil && netutil.SameNet(bits, ip, pip) {
+				count++
+			}
+		}
+		if count >= r.maxPerSubnet {
+			return errPolicySubnet
+		}
+	}
+	return nil
+}
+
+// checkHandshake checks the client name and capabilities of a peer.
+func (r *policyRules) checkHandshake(name string, caps []Cap) error {
+	if r == nil {
+		return nil
+	}
+	if len(r.allowNames) > 0 && !matchAny(r.allowNames, name) {
+		return fmt.Errorf("rejected by peer policy: client name %q", name)
+	}
+	if matchAny(r.denyNames, name) {
+		return fmt.Errorf("rejected by peer policy: client name %q", name)
+	}
+	for _, want := range r.caps {
+		if !hasCap(caps, want) {
+			name := want.String()
+			if want.Version == 0 {
+				name = want.Name
+			}
+			return fmt.Errorf("rejected by peer policy: missing capability %s", name)
+		}
+	}
+	return nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCap(caps []Cap, want Cap) bool {
+	for _, cap := range caps {
+		if cap.Name == want.Name && (want.Version == 0 || cap.Version == want.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPeerPolicy replaces the peer policy of the server. Connected peers which
+// don't satisfy the new policy are disconnected. A nil policy accepts any peer.
+func (srv *Server) SetPeerPolicy(config *PeerPolicy) error {
+	if !srv.isRunning() {
+		return errServerStopped
+	}
+	pol, err := newPeerPolicy(config)
+	if err != nil {
+		return err
+	}
+	srv.policyLock.Lock()
+	srv.policy = pol
+	srv.policyLock.Unlock()
+
+	// Enforce the new policy on existing peers. Peers are re-admitted one by one
+	// so that subnet limits drop only the excess peers.
+	select {
+	case srv.peerOp <- func(peers map[enode.ID]*Peer) {
+		kept := make(map[enode.ID]*Peer, len(peers))
+		for id, p := range peers {
+			if !p.rw.is(trustedConn) {
+				if err := srv.checkPolicy(p.rw, kept, true); err != nil {
+					p.log.Debug("Dropping peer rejected by peer policy", "err", err)
+					p.Disconnect(DiscUselessPeer)
+					continue
+				}
+			}
+			kept[id] = p
+		}
+	}:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+	return nil
+}
+
+// Policy returns the current peer policy.
+func (srv *Server) Policy() *PeerPolicy {
+	if pol := srv.peerPolicy(); pol != nil {
+		return pol.config
+	}
+	return nil
+}
+
+func (srv *Server) peerPolicy() *peerPolicy {
+	srv.policyLock.RLock()
+	defer srv.policyLock.RUnlock()
+	return srv.policy
+}
+
+// checkPolicy checks a connection against the peer policy. The client name and
+// capabilities can only be checked once the protocol handshake is done.
+func (srv *Server) checkPolicy(c *conn, peers map[enode.ID]*Peer, handshakeDone bool) error {
+	r := srv.peerPolicy().rules(c.is(inboundConn))
+	if err := r.checkNode(c.node, netutil.AddrIP(c.fd.RemoteAddr()), peers); err != nil {
+		return err
+	}
+	if !handshakeDone {
+		return nil
+	}
+	return r.checkHandshake(c.name, c.caps)
+}
+
+// checkDialPolicy checks a node against the outbound peer policy before dialing.
+func (srv *Server) checkDialPolicy(n *enode.Node, peers map[enode.ID]*Peer) error {
+	return srv.peerPolicy().rules(false).checkNode(n, nil, peers)
+}
@@ -140,6 +140,62 @@ func (t *rlpx) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err
 	return their, nil
 }
 
+// RLPXConn is an RLPx network connection which is not managed by a Server. It
+// is meant for tools that need to speak the wire protocol directly, such as
+// protocol conformance test suites.
+type RLPXConn struct {
+	t *rlpx
+}
+
+// NewRLPXConn wraps the given network connection. The encryption handshake
+// must be performed by calling Handshake before any messages are exchanged.
+func NewRLPXConn(fd net.Conn) *RLPXConn {
+	return &RLPXConn{t: newRLPX(fd).(*rlpx)}
+}
+
+// Handshake performs the RLPx encryption handshake. If dial is non-nil, the
+// connection acts as the initiator and dial must be the remote public key.
+// The public key of the remote end is returned.
+func (c *RLPXConn) Handshake(prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+	return c.t.doEncHandshake(prv, dial)
+}
+
+// SetSnappy enables or disables snappy compression of message payloads. It
+// should be enabled once both sides have exchanged a protocol handshake which
+// advertises version 5 or higher.
+func (c *RLPXConn) SetSnappy(snappy bool) {
+	c.t.wmu.Lock()
+	defer c.t.wmu.Unlock()
+	c.t.rw.snappy = snappy
+}
+
+// ReadMsg reads a message from the connection. Message codes are not offset,
+// the base protocol occupies codes 0x00 to 0x0f. Unlike connections managed
+// by Server, no read deadline is applied, use SetDeadline instead.
+func (c *RLPXConn) ReadMsg() (Msg, error) {
+	c.t.rmu.Lock()
+	defer c.t.rmu.Unlock()
+	return c.t.rw.ReadMsg()
+}
+
+// WriteMsg sends a message over the connection. No write deadline is applied,
+// use SetDeadline instead.
+func (c *RLPXConn) WriteMsg(msg Msg) error {
+	c.t.wmu.Lock()
+	defer c.t.wmu.Unlock()
+	return c.t.rw.WriteMsg(msg)
+}
+
+// SetDeadline sets the read and write deadlines of the underlying connection.
+func (c *RLPXConn) SetDeadline(t time.Time) error {
+	return c.t.fd.SetDeadline(t)
+}
+
+// Close sends the given disconnect reason (if any) and closes the connection.
+func (c *RLPXConn) Close(reason DiscReason) {
+	c.t.close(reason)
+}
+
 func readProtocolHandshake(rw MsgReader) (*protoHandshake, error) {
 	msg, err := rw.ReadMsg()
 	if err != nil {
@@ -0,0 +1,219 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// banThreshold is the score at which a peer gets banned.
+	banThreshold = 100
+
+	// scoreHalfLife is the time after which half of a peer's score is forgiven.
+	scoreHalfLife = 10 * time.Minute
+
+	// maxTrackedScores is the number of scores kept before decayed ones are pruned.
+	maxTrackedScores = 1024
+
+	// defaultBanDuration is the duration of the first automatic ban of a peer.
+	// Every further ban doubles the duration, up to maxBanDuration.
+	defaultBanDuration = time.Hour
+	maxBanDuration     = 7 * 24 * time.Hour
+)
+
+// Violation is a kind of peer misbehaviour. Each violation adds a penalty to the
+// score of the peer, peers whose score reaches the ban threshold are disconnected
+// and banned for a while.
+type Violation uint8
+
+const (
+	ViolationProtocol  Violation = iota // Malformed or unexpected protocol message
+	ViolationBadBlock                   // Invalid block or header
+	ViolationTimeout                    // Request not answered in time
+	ViolationInvalidTx                  // Invalid transaction
+)
+
+var violationPenalties = map[Violation]float64{
+	ViolationProtocol:  50,
+	ViolationBadBlock:  100,
+	ViolationTimeout:   10,
+	ViolationInvalidTx: 20,
+}
+
+var violationNames = map[Violation]string{
+	ViolationProtocol:  "protocol violation",
+	ViolationBadBlock:  "bad block",
+	ViolationTimeout:   "timeout",
+	ViolationInvalidTx: "invalid transaction",
+}
+
+func (v Violation) String() string {
+	if name, ok := violationNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown violation %d", v)
+}
+
+// peerScore is the accumulated penalty of a single peer.
+type peerScore struct {
+	value   float64
+	updated mclock.AbsTime
+	bans    int // number of automatic bans applied so far
+}
+
+// peerScores tracks the scores of recently seen peers. Scores decay
+// exponentially, so that occasional violations are forgiven over time.
+type peerScores struct {
+	clock  mclock.Clock
+	scores map[enode.ID]*peerScore
+	lock   sync.Mutex
+}
+
+func newPeerScores(clock mclock.Clock) *peerScores {
+	return &peerScores{clock: clock, scores: make(map[enode.ID]*peerScore)}
+}
+
+// decay returns the value of a score at the given time.
+func (s *peerScore) decay(now mclock.AbsTime) float64 {
+	elapsed := time.Duration(now - s.updated)
+	return s.value * math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+}
+
+// add adds a penalty to the score of a peer. If the score reaches the ban
+// threshold, it is reset and the duration of the resulting ban is returned.
+func (ps *peerScores) add(id enode.ID, penalty float64) (score float64, ban time.Duration) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	now := ps.clock.Now()
+	s := ps.scores[id]
+	if s == nil {
+		if len(ps.scores) >= maxTrackedScores {
+			ps.prune(now)
+		}
+		s = new(peerScore)
+		ps.scores[id] = s
+	}
+	s.value = s.decay(now) + penalty
+	s.updated = now
+	if s.value < banThreshold {
+		return s.value, 0
+	}
+	score, s.value = s.value, 0
+	ban = defaultBanDuration << uint(s.bans)
+	if ban > maxBanDuration || ban <= 0 {
+		ban = maxBanDuration
+	}
+	s.bans++
+	return score, ban
+}
+
+// get returns the current score of a peer.
+func (ps *peerScores) get(id enode.ID) float64 {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if s := ps.scores[id]; s != nil {
+		return s.decay(ps.clock.Now())
+	}
+	return 0
+}
+
+// prune drops all scores which have mostly decayed and never led to a ban.
+func (ps *peerScores) prune(now mclock.AbsTime) {
+	for id, s := range ps.scores {
+		if s.bans == 0 && s.decay(now) < 1 {
+			delete(ps.scores, id)
+		}
+	}
+}
+
+// penalize records a violation of the given peer. If the score of the peer
+// reaches the ban threshold, it is banned and disconnected. Trusted peers are
+// never banned automatically.
+func (srv *Server) penalize(p *Peer, v Violation, reason string) {
+	penalty, ok := violationPenalties[v]
+	if !ok {
+		return
+	}
+	score, ban := srv.scores.add(p.ID(), penalty)
+	log := p.log.New("violation", v, "reason", reason, "score", score)
+	if ban == 0 {
+		log.Debug("Penalized peer")
+		return
+	}
+	if p.rw.is(trustedConn) {
+		log.Debug("Not banning trusted peer")
+		return
+	}
+	log.Info("Banning misbehaving peer", "duration", ban)
+	if err := srv.BanPeer(p.ID(), ban, fmt.Sprintf("%v: %s", v, reason)); err != nil {
+		log.Warn("Failed to ban peer", "err", err)
+	}
+}
+
+// BanPeer bans a node for the given duration and disconnects it if connected.
+// The ban is stored in the node database and survives restarts. Bans are not
+// enforced against trusted nodes.
+func (srv *Server) BanPeer(id enode.ID, duration time.Duration, reason string) error {
+	if !srv.isRunning() {
+		return errServerStopped
+	}
+	if err := srv.nodedb.UpdateBan(id, time.Now().Add(duration), reason); err != nil {
+		return err
+	}
+	select {
+	case srv.banned <- id:
+	case <-srv.quit:
+	}
+	return nil
+}
+
+// UnbanPeer lifts the ban of a node.
+func (srv *Server) UnbanPeer(id enode.ID) error {
+	if !srv.isRunning() {
+		return errServerStopped
+	}
+	return srv.nodedb.DeleteBan(id)
+}
+
+// Bans returns all active node bans.
+func (srv *Server) Bans() []*enode.Ban {
+	if !srv.isRunning() {
+		return nil
+	}
+	return srv.nodedb.Bans()
+}
+
+// isRunning reports whether the server is started.
+func (srv *Server) isRunning() bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return srv.running
+}
+
+// isBanned reports whether the given node is currently banned.
+func (srv *Server) isBanned(id enode.ID) bool {
+	return srv.nodedb.Ban(id) != nil
+}
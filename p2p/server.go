@@ -116,6 +116,10 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// PeerPolicy further restricts the peers which may connect. It can be
+	// replaced at runtime using SetPeerPolicy.
+	PeerPolicy *PeerPolicy `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -188,6 +192,13 @@ type Server struct {
 	delpeer                 chan peerDrop
 	checkpointPostHandshake chan *conn
 	checkpointAddPeer       chan *conn
+	banned                  chan enode.ID
+
+	// Misbehaviour scores of recently seen peers.
+	scores *peerScores
+
+	policyLock sync.RWMutex // protects policy
+	policy     *peerPolicy
 
 	// State of run loop and listenLoop.
 	lastLookup     time.Time
@@ -446,6 +457,9 @@ func (srv *Server) Start() (err error) {
 	if srv.Dialer == nil {
 		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
+	if srv.policy, err = newPeerPolicy(srv.PeerPolicy); err != nil {
+		return fmt.Errorf("invalid peer policy: %v", err)
+	}
 	srv.quit = make(chan struct{})
 	srv.delpeer = make(chan peerDrop)
 	srv.checkpointPostHandshake = make(chan *conn)
@@ -456,6 +470,8 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.banned = make(chan enode.ID)
+	srv.scores = newPeerScores(mclock.System{})
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
@@ -471,6 +487,8 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.localnode.ID(), srv.ntab, dynPeers, &srv.Config)
+	dialer.banned = srv.isBanned
+	dialer.policy = srv.checkDialPolicy
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -712,6 +730,13 @@ running:
 				p.rw.set(trustedConn, false)
 			}
 
+		case id := <-srv.banned:
+			// This channel is used by BanPeer to disconnect
+			// a peer which has just been banned.
+			if p, ok := peers[id]; ok && !p.rw.is(trustedConn) {
+				p.Disconnect(DiscUselessPeer)
+			}
+
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -742,6 +767,7 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(srv.log, c, srv.Protocols)
+				p.scorer = srv.penalize
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
@@ -804,9 +830,13 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
-	default:
-		return nil
+	case !c.is(trustedConn) && srv.isBanned(c.node.ID()):
+		return DiscUselessPeer
 	}
+	if !c.is(trustedConn) {
+		return srv.checkPolicy(c, peers, false)
+	}
+	return nil
 }
 
 func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
@@ -814,6 +844,12 @@ func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
 		return DiscUselessPeer
 	}
+	// Check the handshake against the peer policy.
+	if !c.is(trustedConn) {
+		if err := srv.peerPolicy().rules(c.is(inboundConn)).checkHandshake(c.name, c.caps); err != nil {
+			return err
+		}
+	}
 	// Repeat the post-handshake checks because the
 	// peer set might have changed since those checks were performed.
 	return srv.postHandshakeChecks(peers, inboundCount, c)
@@ -1087,6 +1123,31 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	return info
 }
 
+// TrafficStats returns the traffic counters of all connected peers.
+func (srv *Server) TrafficStats() *TrafficStats {
+	stats := &TrafficStats{
+		Peers:     make([]*PeerStats, 0, srv.PeerCount()),
+		Protocols: make(map[string]*ProtocolStats),
+	}
+	for _, peer := range srv.Peers() {
+		if peer == nil {
+			continue
+		}
+		peerStats := peer.Stats()
+		for name, proto := range peerStats.Protocols {
+			if stats.Protocols[name] == nil {
+				stats.Protocols[name] = &ProtocolStats{Messages: make(map[string]*MsgStats)}
+			}
+			stats.Protocols[name].add(proto)
+		}
+		stats.Peers = append(stats.Peers, peerStats)
+	}
+	sort.Slice(stats.Peers, func(i, j int) bool {
+		return stats.Peers[i].ID < stats.Peers[j].ID
+	})
+	return stats
+}
+
 // PeersInfo returns an array of metadata objects describing connected peers.
 func (srv *Server) PeersInfo() []*PeerInfo {
 	// Gather all the generic and sub-protocol specific infos
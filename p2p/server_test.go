@@ -19,6 +19,7 @@ package p2p
 import (
 	"crypto/ecdsa"
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"net"
@@ -724,3 +725,127 @@ func (l *fakeAddrListener) Accept() (net.Conn, error) {
 func (c *fakeAddrConn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
+
+func TestServerBans(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	remoteKey := newkey()
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remoteKey.PublicKey, fd)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	// Connect a peer and penalize it until it gets banned.
+	id := randomID()
+	if err := srv.checkpoint(newconn(id), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("could not add conn: %v", err)
+	}
+	var peer *Peer
+	for _, p := range srv.Peers() {
+		peer = p
+	}
+	peer.Penalize(ViolationTimeout, "slow")
+	if bans := srv.Bans(); len(bans) != 0 {
+		t.Fatalf("peer banned after single timeout: %v", bans)
+	}
+	peer.Penalize(ViolationBadBlock, "invalid header")
+	bans := srv.Bans()
+	if len(bans) != 1 || bans[0].ID != id {
+		t.Fatalf("wrong bans after bad block: %v", bans)
+	}
+	for i := 0; i < 100 && srv.PeerCount() != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := srv.PeerCount(); n != 0 {
+		t.Fatalf("banned peer not disconnected, %d peers", n)
+	}
+	if err := srv.checkpoint(newconn(id), srv.checkpointPostHandshake); err != DiscUselessPeer {
+		t.Errorf("wrong error for banned conn: %v", err)
+	}
+	// Lift the ban and check that the peer may connect again.
+	if err := srv.UnbanPeer(id); err != nil {
+		t.Fatalf("could not unban: %v", err)
+	}
+	if err := srv.checkpoint(newconn(id), srv.checkpointPostHandshake); err != nil {
+		t.Errorf("unexpected error for unbanned conn: %v", err)
+	}
+}
+
+func TestServerPeerPolicy(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			PeerPolicy: &PeerPolicy{
+				Inbound: &PolicyRules{
+					DenyNets:     []string{"10.0.0.0/8"},
+					DenyNames:    []string{"^bad/"},
+					RequireCaps:  []string{"eth"},
+					MaxPerSubnet: 2,
+				},
+			},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	remoteKey := newkey()
+	newconn := func(ip net.IP, name string, caps []Cap) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remoteKey.PublicKey, fd)
+		node := enode.SignNull(new(enr.Record), randomID())
+		addr := &net.TCPAddr{IP: ip, Port: 30303}
+		return &conn{fd: &fakeAddrConn{fd, addr}, transport: tx, flags: inboundConn, node: node, name: name, caps: caps, cont: make(chan error)}
+	}
+	eth := []Cap{{"eth", 63}}
+	tests := []struct {
+		conn *conn
+		err  error
+	}{
+		{newconn(net.IP{10, 1, 2, 3}, "good/v1", eth), errPolicyNet},
+		{newconn(net.IP{1, 2, 3, 1}, "bad/v1", eth), errors.New(`rejected by peer policy: client name "bad/v1"`)},
+		{newconn(net.IP{1, 2, 3, 1}, "good/v1", []Cap{{"les", 2}}), errors.New("rejected by peer policy: missing capability eth")},
+		{newconn(net.IP{1, 2, 3, 1}, "good/v1", eth), nil},
+		{newconn(net.IP{1, 2, 3, 2}, "good/v1", eth), nil},
+		{newconn(net.IP{1, 2, 3, 3}, "good/v1", eth), errPolicySubnet},
+		{newconn(net.IP{1, 2, 4, 1}, "good/v1", eth), nil},
+	}
+	for i, test := range tests {
+		err := srv.checkpoint(test.conn, srv.checkpointAddPeer)
+		if fmt.Sprint(err) != fmt.Sprint(test.err) {
+			t.Errorf("test %d: wrong error %q, want %q", i, err, test.err)
+		}
+	}
+	if n := srv.PeerCount(); n != 3 {
+		t.Fatalf("wrong peer count %d, want 3", n)
+	}
+	// Tighten the subnet limit and check that excess peers are dropped.
+	if err := srv.SetPeerPolicy(&PeerPolicy{Inbound: &PolicyRules{MaxPerSubnet: 1}}); err != nil {
+		t.Fatalf("could not set policy: %v", err)
+	}
+	for i := 0; i < 100 && srv.PeerCount() != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := srv.PeerCount(); n != 2 {
+		t.Fatalf("wrong peer count %d after policy update, want 2", n)
+	}
+	if err := srv.SetPeerPolicy(&PeerPolicy{Inbound: &PolicyRules{DenyNets: []string{"foo"}}}); err == nil {
+		t.Error("no error for invalid policy")
+	}
+}
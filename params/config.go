@@ -213,16 +213,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -294,11 +294,21 @@ type ChainConfig struct {
 	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
 	EWASMBlock          *big.Int `json:"ewasmBlock,omitempty"`          // EWASM switch block (nil = no fork, 0 = already activated)
 
+	// Precompiles activates custom precompiled contracts, registered by name with
+	// the EVM, at the given addresses.
+	Precompiles map[common.Address]*PrecompileConfig `json:"precompiles,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 }
 
+// PrecompileConfig is the activation of a custom precompiled contract.
+type PrecompileConfig struct {
+	Name  string   `json:"name"`  // Name the contract implementation is registered with
+	Block *big.Int `json:"block"` // Activation block (nil = not active, 0 = active from genesis)
+}
+
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
 type EthashConfig struct{}
 
@@ -345,6 +355,15 @@ func (c *ChainConfig) String() string {
 	)
 }
 
+// IsPrecompileActive returns whether the custom precompiled contract at the given
+// address is active at num.
+func (c *ChainConfig) IsPrecompileActive(addr common.Address, num *big.Int) bool {
+	if p := c.Precompiles[addr]; p != nil {
+		return isForked(p.Block, num)
+	}
+	return false
+}
+
 // IsHomestead returns whether num is either equal to the homestead block or greater.
 func (c *ChainConfig) IsHomestead(num *big.Int) bool {
 	return isForked(c.HomesteadBlock, num)
@@ -452,6 +471,40 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	for addr, p := range c.Precompiles {
+		if err := checkPrecompileCompatible(addr, p, newcfg.Precompiles[addr], head); err != nil {
+			return err
+		}
+	}
+	for addr, p := range newcfg.Precompiles {
+		if _, ok := c.Precompiles[addr]; !ok {
+			if err := checkPrecompileCompatible(addr, nil, p, head); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPrecompileCompatible checks whether the activation of a custom precompiled
+// contract can be changed to the new one without rewriting the chain up to head.
+func checkPrecompileCompatible(addr common.Address, stored, updated *PrecompileConfig, head *big.Int) *ConfigCompatError {
+	var (
+		storedBlock, updatedBlock *big.Int
+		storedName, updatedName   string
+	)
+	if stored != nil {
+		storedBlock, storedName = stored.Block, stored.Name
+	}
+	if updated != nil {
+		updatedBlock, updatedName = updated.Block, updated.Name
+	}
+	if isForkIncompatible(storedBlock, updatedBlock, head) {
+		return newCompatError(fmt.Sprintf("precompile %x activation block", addr), storedBlock, updatedBlock)
+	}
+	if isForked(storedBlock, head) && storedName != updatedName {
+		return newCompatError(fmt.Sprintf("precompile %x implementation", addr), storedBlock, updatedBlock)
+	}
 	return nil
 }
 
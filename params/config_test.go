@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +72,45 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored:  &ChainConfig{Precompiles: map[common.Address]*PrecompileConfig{{0x01}: {Name: "a", Block: big.NewInt(10)}}},
+			new:     &ChainConfig{Precompiles: map[common.Address]*PrecompileConfig{{0x01}: {Name: "b", Block: big.NewInt(20)}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Precompiles: map[common.Address]*PrecompileConfig{{0x01}: {Name: "a", Block: big.NewInt(10)}}},
+			new:    &ChainConfig{},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0100000000000000000000000000000000000000 activation block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{Precompiles: map[common.Address]*PrecompileConfig{{0x01}: {Name: "a", Block: big.NewInt(5)}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0100000000000000000000000000000000000000 activation block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(5),
+				RewindTo:     4,
+			},
+		},
+		{
+			stored: &ChainConfig{Precompiles: map[common.Address]*PrecompileConfig{{0x01}: {Name: "a", Block: big.NewInt(10)}}},
+			new:    &ChainConfig{Precompiles: map[common.Address]*PrecompileConfig{{0x01}: {Name: "b", Block: big.NewInt(10)}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0100000000000000000000000000000000000000 implementation",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -77,6 +78,8 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	connCtx  context.Context // base context of the connection handlers
+	limits   Limits          // resource limits enforced by the connection handlers
 
 	idCounter uint32
 
@@ -110,8 +113,8 @@ type clientConn struct {
 }
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
-	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	ctx := context.WithValue(c.connCtx, clientContextKey{}, c)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.limits)
 	return &clientConn{conn, handler}
 }
 
@@ -167,15 +170,54 @@ func Dial(rawurl string) (*Client, error) {
 // The context is used to cancel or time out the initial connection establishment. It does
 // not affect subsequent interactions with the client.
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	return DialOptions(ctx, rawurl)
+}
+
+// ClientOption is a configuration option for the RPC client.
+type ClientOption func(*clientConfig)
+
+// clientConfig holds the transport options of a client created by DialOptions.
+type clientConfig struct {
+	httpClient *http.Client
+	httpAuth   HTTPAuth
+}
+
+// HTTPAuth is a function which adds authentication headers to outgoing HTTP
+// requests and websocket handshakes. It is invoked anew for every request, so
+// it may mint short lived credentials.
+type HTTPAuth func(h http.Header) error
+
+// WithHTTPClient configures the http.Client used for HTTP connections.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.httpClient = c
+	}
+}
+
+// WithHTTPAuth configures the authentication of HTTP requests and websocket
+// handshakes, e.g. using NewJWTAuth. It is ignored by other transports.
+func WithHTTPAuth(a HTTPAuth) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.httpAuth = a
+	}
+}
+
+// DialOptions creates a new RPC client for the given URL, just like DialContext,
+// additionally configuring the transport with the given options.
+func DialOptions(ctx context.Context, rawurl string, options ...ClientOption) (*Client, error) {
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
 	}
+	cfg := new(clientConfig)
+	for _, option := range options {
+		option(cfg)
+	}
 	switch u.Scheme {
 	case "http", "https":
-		return DialHTTP(rawurl)
+		return dialHTTP(rawurl, cfg)
 	case "ws", "wss":
-		return DialWebsocket(ctx, rawurl, "")
+		return dialWebsocket(ctx, rawurl, "", cfg)
 	case "stdio":
 		return DialStdIO(ctx)
 	case "":
@@ -197,17 +239,19 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(context.Background(), conn, randomIDGenerator(), new(serviceRegistry), Limits{})
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(connCtx context.Context, conn ServerCodec, idgen func() ID, services *serviceRegistry, limits Limits) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		connCtx:     connCtx,
+		limits:      limits,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
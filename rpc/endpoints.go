@@ -22,8 +22,10 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
-// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
-func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts) (net.Listener, *Server, error) {
+// NewAPIServer creates an RPC server exposing the given APIs. If modules is non-empty,
+// only the listed namespaces are registered, otherwise all public APIs are. If
+// exposeAll is set, all APIs are registered regardless of the whitelist.
+func NewAPIServer(apis []API, modules []string, exposeAll bool, limits Limits) (*Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -31,55 +33,65 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetLimits(limits)
 	for _, api := range apis {
-		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-			log.Debug("HTTP registered", "namespace", api.Namespace)
+			log.Debug("RPC registered", "namespace", api.Namespace)
 		}
 	}
+	return handler, nil
+}
+
+// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules.
+// If jwtSecret is non-empty, requests need to be authenticated with a JWT token.
+func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts, limits Limits, jwtSecret []byte) (net.Listener, *Server, error) {
+	handler, err := NewAPIServer(apis, modules, false, limits)
+	if err != nil {
+		return nil, nil, err
+	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := StartHTTPRouter(endpoint, timeouts, []HTTPRoute{
+		{Handler: NewHTTPHandler(handler, cors, vhosts, jwtSecret)},
+	})
+	if err != nil {
 		return nil, nil, err
 	}
-	go NewHTTPServer(cors, vhosts, timeouts, handler).Serve(listener)
-	return listener, handler, err
+	return listener, handler, nil
 }
 
-// StartWSEndpoint starts a websocket endpoint
-func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
-
-	// Generate the whitelist based on the allowed modules
-	whitelist := make(map[string]bool)
-	for _, module := range modules {
-		whitelist[module] = true
-	}
-	// Register all the APIs exposed by the services
-	handler := NewServer()
-	for _, api := range apis {
-		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return nil, nil, err
-			}
-			log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
-		}
+// StartWSEndpoint starts a websocket endpoint. If jwtSecret is non-empty, the
+// handshake needs to be authenticated with a JWT token.
+func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, limits Limits, jwtSecret []byte) (net.Listener, *Server, error) {
+	handler, err := NewAPIServer(apis, modules, exposeAll, limits)
+	if err != nil {
+		return nil, nil, err
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := StartHTTPRouter(endpoint, DefaultHTTPTimeouts, []HTTPRoute{
+		{Handler: NewWSHandler(handler, wsOrigins, jwtSecret), Websocket: true},
+	})
+	if err != nil {
 		return nil, nil, err
 	}
-	go NewWSServer(wsOrigins, handler).Serve(listener)
-	return listener, handler, err
+	return listener, handler, nil
+}
 
+// StartHTTPRouter starts an HTTP listener on the given endpoint, dispatching the
+// incoming requests to the given routes based on their path.
+func StartHTTPRouter(endpoint string, timeouts HTTPTimeouts, routes []HTTPRoute) (net.Listener, error) {
+	router, err := newHTTPRouter(routes)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	go newHTTPServer(timeouts, router).Serve(listener)
+	return listener, nil
 }
 
 // StartIPCEndpoint starts an IPC endpoint.
@@ -36,6 +36,27 @@ func (e *subscriptionNotFoundError) Error() string {
 	return fmt.Sprintf("no %q subscription in %s namespace", e.subscription, e.namespace)
 }
 
+// authenticated client is not permitted to access the module
+type unauthorizedModuleError struct{ namespace string }
+
+func (e *unauthorizedModuleError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedModuleError) Error() string {
+	return fmt.Sprintf("access to the %s module is not permitted", e.namespace)
+}
+
+var (
+	errTooManyCalls     = &limitExceededError{"too many concurrent requests"}
+	errResponseTooLarge = &limitExceededError{"response too large"}
+)
+
+// request exceeds the resource limits of the server
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
 // Invalid JSON was received by the server.
 type parseError struct{ message string }
 
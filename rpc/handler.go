@@ -19,13 +19,16 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 // handler handles JSON-RPC messages. There is one handler per connection. Note that
@@ -61,6 +64,8 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limits         Limits // resource limits enforced on incoming calls
+	activeCalls    int32  // number of executing call goroutines, accessed atomically
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -71,7 +76,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, limits Limits) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -82,6 +87,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		rootCtx:        rootCtx,
 		cancelRoot:     cancelRoot,
 		allowSubscribe: true,
+		limits:         limits,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
 	}
@@ -101,6 +107,11 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject oversized batches without executing any of the calls:
+	if h.limits.BatchItems > 0 && len(msgs) > h.limits.BatchItems {
+		h.rejectCalls(msgs, true, &limitExceededError{fmt.Sprintf("batch too large (%d>%d)", len(msgs), h.limits.BatchItems)})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -112,12 +123,26 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	if len(calls) == 0 {
 		return
 	}
+	if h.overloaded() {
+		h.rejectCalls(calls, true, errTooManyCalls)
+		return
+	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
 		for _, msg := range calls {
+			// Skip any remaining calls once the response size limit is exceeded
+			if h.limits.ResponseSize > 0 && size > h.limits.ResponseSize {
+				if msg.isCall() {
+					answers = append(answers, msg.errorResponse(errResponseTooLarge))
+				}
+				continue
+			}
 			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+				answers = append(answers, h.limitResponseSize(answer, &size))
 			}
 		}
 		h.addSubscriptions(cp.notifiers)
@@ -135,11 +160,16 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
 		return
 	}
+	if h.overloaded() {
+		h.rejectCalls([]*jsonrpcMessage{msg}, false, errTooManyCalls)
+		return
+	}
 	h.startCallProc(func(cp *callProc) {
 		answer := h.handleCallMsg(cp, msg)
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
-			h.conn.Write(cp.ctx, answer)
+			var size int
+			h.conn.Write(cp.ctx, h.limitResponseSize(answer, &size))
 		}
 		for _, n := range cp.notifiers {
 			n.activate()
@@ -147,6 +177,48 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	})
 }
 
+// overloaded reports whether the connection already executes the maximum number
+// of concurrent calls permitted.
+func (h *handler) overloaded() bool {
+	return h.limits.ConnCalls > 0 && int(atomic.LoadInt32(&h.activeCalls)) >= h.limits.ConnCalls
+}
+
+// rejectCalls answers all calls among msgs with the given error without executing
+// them. If batch is set, the answers are sent as a batch response.
+func (h *handler) rejectCalls(msgs []*jsonrpcMessage, batch bool, err error) {
+	rpcLimitExceededMeter.Mark(1)
+
+	answers := make([]*jsonrpcMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.isCall() {
+			answers = append(answers, msg.errorResponse(err))
+		}
+	}
+	switch {
+	case len(answers) == 0:
+		return
+	case batch:
+		h.conn.Write(h.rootCtx, answers)
+	default:
+		h.conn.Write(h.rootCtx, answers[0])
+	}
+}
+
+// limitResponseSize adds the size of answer to the running total of the response,
+// replacing answer with an error if the total exceeds the response size limit.
+// As the answer is already assembled at this point, this only saves bandwidth.
+func (h *handler) limitResponseSize(answer *jsonrpcMessage, total *int) *jsonrpcMessage {
+	if h.limits.ResponseSize == 0 {
+		return answer
+	}
+	*total += len(answer.Result)
+	if *total > h.limits.ResponseSize {
+		rpcLimitExceededMeter.Mark(1)
+		return answer.errorResponse(errResponseTooLarge)
+	}
+	return answer
+}
+
 // close cancels all requests except for inflightReq and waits for
 // call goroutines to shut down.
 func (h *handler) close(err error, inflightReq *requestOp) {
@@ -200,6 +272,7 @@ func (h *handler) addSubscriptions(nn []*Notifier) {
 	for _, n := range nn {
 		if sub := n.takeSubscription(); sub != nil {
 			h.serverSubs[sub.ID] = sub
+			rpcSubscriptionGauge.Update(atomic.AddInt64(&rpcSubscriptions, 1))
 		}
 	}
 }
@@ -213,15 +286,18 @@ func (h *handler) cancelServerSubscriptions(err error) {
 		s.err <- err
 		close(s.err)
 		delete(h.serverSubs, id)
+		rpcSubscriptionGauge.Update(atomic.AddInt64(&rpcSubscriptions, -1))
 	}
 }
 
 // startCallProc runs fn in a new goroutine and starts tracking it in the h.calls wait group.
 func (h *handler) startCallProc(fn func(*callProc)) {
 	h.callWG.Add(1)
+	atomic.AddInt32(&h.activeCalls, 1)
 	go func() {
 		ctx, cancel := context.WithCancel(h.rootCtx)
 		defer h.callWG.Done()
+		defer atomic.AddInt32(&h.activeCalls, -1)
 		defer cancel()
 		fn(&callProc{ctx: ctx})
 	}()
@@ -311,6 +387,9 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if !namespaceAllowed(cp.ctx, msg.namespace()) {
+		return msg.errorResponse(&unauthorizedModuleError{msg.namespace()})
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -327,8 +406,23 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	start := time.Now()
+	answer := h.runMethod(cp.ctx, msg, callb, args)
 
-	return h.runMethod(cp.ctx, msg, callb, args)
+	// Collect the statistics of method calls, subscriptions are tracked separately
+	if callb != h.unsubscribeCb {
+		rpcRequestCounter.Inc(1)
+		if answer.Error != nil {
+			rpcFailureCounter.Inc(1)
+		} else {
+			rpcSuccessCounter.Inc(1)
+		}
+		rpcServingTimer.UpdateSince(start)
+		if metrics.Enabled {
+			newRPCServingTimer(msg.Method, answer.Error == nil).UpdateSince(start)
+		}
+	}
+	return answer
 }
 
 // handleSubscribe processes *_subscribe method calls.
@@ -384,6 +478,7 @@ func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	}
 	close(s.err)
 	delete(h.serverSubs, id)
+	rpcSubscriptionGauge.Update(atomic.AddInt64(&rpcSubscriptions, -1))
 	return true, nil
 }
 
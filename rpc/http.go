@@ -46,6 +46,7 @@ var acceptedContentTypes = []string{contentType, "application/json-rpc", "applic
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
+	auth      HTTPAuth
 	closeOnce sync.Once
 	closed    chan interface{}
 }
@@ -107,6 +108,19 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return dialHTTP(endpoint, &clientConfig{httpClient: client})
+}
+
+// DialHTTP creates a new RPC client that connects to an RPC server over HTTP.
+func DialHTTP(endpoint string) (*Client, error) {
+	return DialHTTPWithClient(endpoint, new(http.Client))
+}
+
+func dialHTTP(endpoint string, cfg *clientConfig) (*Client, error) {
+	client := cfg.httpClient
+	if client == nil {
+		client = new(http.Client)
+	}
 	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -116,15 +130,10 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 
 	initctx := context.Background()
 	return newClient(initctx, func(context.Context) (ServerCodec, error) {
-		return &httpConn{client: client, req: req, closed: make(chan interface{})}, nil
+		return &httpConn{client: client, req: req, auth: cfg.httpAuth, closed: make(chan interface{})}, nil
 	})
 }
 
-// DialHTTP creates a new RPC client that connects to an RPC server over HTTP.
-func DialHTTP(endpoint string) (*Client, error) {
-	return DialHTTPWithClient(endpoint, new(http.Client))
-}
-
 func (c *Client) sendHTTP(ctx context.Context, op *requestOp, msg interface{}) error {
 	hc := c.writeConn.(*httpConn)
 	respBody, err := hc.doRequest(ctx, msg)
@@ -174,6 +183,12 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	req := hc.req.WithContext(ctx)
 	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	req.ContentLength = int64(len(body))
+	if hc.auth != nil {
+		req.Header = cloneHeader(hc.req.Header)
+		if err := hc.auth(req.Header); err != nil {
+			return nil, err
+		}
+	}
 
 	resp, err := hc.client.Do(req)
 	if err != nil {
@@ -185,6 +200,18 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	return resp.Body, nil
 }
 
+// cloneHeader returns a deep copy of h. It is equivalent to http.Header.Clone,
+// which is not available before Go 1.13.
+func cloneHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, vv := range h {
+		vv2 := make([]string, len(vv))
+		copy(vv2, vv)
+		h2[k] = vv2
+	}
+	return h2
+}
+
 // httpServerConn turns a HTTP connection into a Conn.
 type httpServerConn struct {
 	io.Reader
@@ -213,11 +240,22 @@ func (t *httpServerConn) SetWriteDeadline(time.Time) error { return nil }
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv http.Handler) *http.Server {
+	return newHTTPServer(timeouts, NewHTTPHandler(srv, cors, vhosts, nil))
+}
+
+// NewHTTPHandler wraps an API provider into the checks of an HTTP RPC endpoint:
+// the CORS and virtual host restrictions and, if jwtSecret is non-empty, JWT
+// authentication. Responses are compressed if the client accepts it.
+func NewHTTPHandler(srv http.Handler, cors []string, vhosts []string, jwtSecret []byte) http.Handler {
 	// Wrap the CORS-handler within a host-handler
-	handler := newCorsHandler(srv, cors)
+	handler := newCorsHandler(NewJWTHandler(jwtSecret, srv), cors)
 	handler = newVHostHandler(vhosts, handler)
-	handler = newGzipHandler(handler)
+	return newGzipHandler(handler)
+}
 
+// newHTTPServer creates an HTTP server around the given handler, sanitizing the
+// configured timeouts.
+func newHTTPServer(timeouts HTTPTimeouts, handler http.Handler) *http.Server {
 	// Make sure timeout values are meaningful
 	if timeouts.ReadTimeout < time.Second {
 		log.Warn("Sanitizing invalid HTTP read timeout", "provided", timeouts.ReadTimeout, "updated", DefaultHTTPTimeouts.ReadTimeout)
@@ -0,0 +1,200 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// jwtExpiryTimeout is the maximum allowed difference between the issuance
+	// time of a token and the local time. Tokens are expected to be minted for
+	// every request (or connection), so the window is kept short.
+	jwtExpiryTimeout = 60 * time.Second
+
+	// JWTSecretLength is the required length of JWT secrets in bytes.
+	JWTSecretLength = 32
+)
+
+var (
+	errMissingToken     = errors.New("missing token")
+	errMalformedToken   = errors.New("malformed token")
+	errUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	errInvalidSignature = errors.New("invalid token signature")
+	errMissingIssuedAt  = errors.New("missing issued-at claim")
+	errStaleToken       = errors.New("stale token")
+	errFutureToken      = errors.New("future token")
+)
+
+// jwtHeader is the fixed header of the tokens accepted and created by this
+// package. Only HMAC-SHA256 signed tokens are supported.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// JWTClaims are the token claims understood by the RPC server.
+type JWTClaims struct {
+	// IssuedAt is the unix timestamp at which the token was minted. It must be
+	// within jwtExpiryTimeout of the server's local time.
+	IssuedAt int64 `json:"iat"`
+
+	// Modules restricts the token to the given API namespaces. If empty, all
+	// modules exposed on the endpoint are accessible.
+	Modules []string `json:"modules,omitempty"`
+}
+
+// allows reports whether the claims permit access to the given API namespace.
+// The meta information namespace is always accessible.
+func (c *JWTClaims) allows(namespace string) bool {
+	if len(c.Modules) == 0 || namespace == MetadataApi {
+		return true
+	}
+	for _, module := range c.Modules {
+		if module == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// NewJWTToken creates an HS256 signed token carrying the given claims.
+func NewJWTToken(secret []byte, claims JWTClaims) (string, error) {
+	blob, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(blob)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(jwtSign(secret, unsigned)), nil
+}
+
+// NewJWTAuth creates an HTTPAuth which authenticates the client with a freshly
+// minted token on every request, optionally restricted to the given modules.
+func NewJWTAuth(secret []byte, modules ...string) HTTPAuth {
+	return func(h http.Header) error {
+		token, err := NewJWTToken(secret, JWTClaims{IssuedAt: time.Now().Unix(), Modules: modules})
+		if err != nil {
+			return err
+		}
+		h.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// jwtSign computes the HMAC-SHA256 signature of the given token prefix.
+func jwtSign(secret []byte, unsigned string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+// parseJWT verifies the signature and freshness of the given token and returns
+// the claims it carries.
+func parseJWT(secret []byte, token string, now time.Time) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+	// Verify the header and signature before looking at the claims
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, errMalformedToken
+	}
+	if header.Alg != "HS256" {
+		return nil, errUnsupportedAlg
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	if !hmac.Equal(signature, jwtSign(secret, parts[0]+"."+parts[1])) {
+		return nil, errInvalidSignature
+	}
+	// Signature valid, decode the claims and check the token freshness
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	claims := new(JWTClaims)
+	if err := json.Unmarshal(rawClaims, claims); err != nil {
+		return nil, errMalformedToken
+	}
+	if claims.IssuedAt == 0 {
+		return nil, errMissingIssuedAt
+	}
+	issued := time.Unix(claims.IssuedAt, 0)
+	if now.Sub(issued) > jwtExpiryTimeout {
+		return nil, errStaleToken
+	}
+	if issued.Sub(now) > jwtExpiryTimeout {
+		return nil, errFutureToken
+	}
+	return claims, nil
+}
+
+// jwtClaimsKey is the context key under which authenticated claims are stored.
+type jwtClaimsKey struct{}
+
+// namespaceAllowed reports whether the claims stored in the context (if any)
+// permit access to the given API namespace.
+func namespaceAllowed(ctx context.Context, namespace string) bool {
+	claims, ok := ctx.Value(jwtClaimsKey{}).(*JWTClaims)
+	return !ok || claims.allows(namespace)
+}
+
+// jwtHandler is a handler which authenticates incoming requests with an HS256
+// JWT token passed in the Authorization header.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// NewJWTHandler wraps the given handler with JWT authentication. If no secret
+// is configured, authentication is disabled and next is returned directly.
+func NewJWTHandler(secret []byte, next http.Handler) http.Handler {
+	if len(secret) == 0 {
+		return next
+	}
+	return &jwtHandler{secret: secret, next: next}
+}
+
+// ServeHTTP implements http.Handler, rejecting requests without a valid token.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		http.Error(w, errMissingToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	claims, err := parseJWT(h.secret, token, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid token: %v", err), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jwtClaimsKey{}, claims)))
+}
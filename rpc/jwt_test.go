@@ -0,0 +1,196 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// Tests that tokens are verified for signature, algorithm and freshness.
+func TestJWTParse(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000000, 0)
+	token := func(iat int64) string {
+		token, err := NewJWTToken(testJWTSecret, JWTClaims{IssuedAt: iat})
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		return token
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"iat":1000000}`))
+	none := unsigned + "." + base64.RawURLEncoding.EncodeToString(jwtSign(testJWTSecret, unsigned))
+
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{token(now.Unix()), nil},
+		{token(now.Unix() - 59), nil},
+		{token(now.Unix() + 59), nil},
+		{token(now.Unix() - 61), errStaleToken},
+		{token(now.Unix() + 61), errFutureToken},
+		{token(0), errMissingIssuedAt},
+		{token(now.Unix())[:10], errMalformedToken},
+		{token(now.Unix()) + "!", errMalformedToken},
+		{token(now.Unix())[:len(token(now.Unix()))-2] + "AA", errInvalidSignature},
+		{none, errUnsupportedAlg},
+	}
+	for i, tt := range tests {
+		if _, err := parseJWT(testJWTSecret, tt.token, now); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if _, err := parseJWT([]byte("other secret"), token(now.Unix()), now); err != errInvalidSignature {
+		t.Errorf("foreign secret error mismatch: have %v, want %v", err, errInvalidSignature)
+	}
+}
+
+// Tests that HTTP requests are only served if authenticated and only for the
+// modules permitted by the token.
+func TestJWTHTTP(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer()
+	defer server.Stop()
+	httpsrv := httptest.NewServer(NewJWTHandler(testJWTSecret, server))
+	defer httpsrv.Close()
+
+	// Unauthenticated and wrongly authenticated requests are rejected
+	for i, auth := range []HTTPAuth{nil, NewJWTAuth([]byte("other secret"))} {
+		var options []ClientOption
+		if auth != nil {
+			options = append(options, WithHTTPAuth(auth))
+		}
+		client, err := DialOptions(context.Background(), httpsrv.URL, options...)
+		if err != nil {
+			t.Fatalf("test %d: failed to dial: %v", i, err)
+		}
+		err = client.Call(nil, "test_echo", "x", 1)
+		if err == nil || !strings.HasPrefix(err.Error(), "401 Unauthorized") {
+			t.Errorf("test %d: error mismatch: have %v, want 401 Unauthorized", i, err)
+		}
+		client.Close()
+	}
+	// Authenticated requests are served, limited to the permitted modules
+	client, err := DialOptions(context.Background(), httpsrv.URL, WithHTTPAuth(NewJWTAuth(testJWTSecret, "test")))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("authenticated call failed: %v", err)
+	}
+	if result.String != "x" || result.Int != 1 {
+		t.Fatalf("wrong result: %+v", result)
+	}
+	err = client.Call(nil, "nftest_echo", 1)
+	if e, ok := err.(Error); !ok || e.ErrorCode() != -32001 {
+		t.Fatalf("unpermitted module error mismatch: have %v, want code -32001", err)
+	}
+}
+
+// Tests that websocket connections require authentication during the handshake
+// and remain limited to the modules permitted by the token.
+func TestJWTWebsocket(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer()
+	defer server.Stop()
+	httpsrv := httptest.NewServer(NewJWTHandler(testJWTSecret, server.WebsocketHandler([]string{"*"})))
+	defer httpsrv.Close()
+	wsURL := "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+
+	if _, err := DialOptions(context.Background(), wsURL); err == nil {
+		t.Fatal("unauthenticated connection accepted")
+	} else if herr, ok := err.(wsHandshakeError); !ok || herr.status != "401 Unauthorized" {
+		t.Fatalf("handshake error mismatch: have %v, want 401 Unauthorized", err)
+	}
+	client, err := DialOptions(context.Background(), wsURL, WithHTTPAuth(NewJWTAuth(testJWTSecret, "nftest")))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	err = client.Call(nil, "test_echo", "x", 1)
+	if e, ok := err.(Error); !ok || e.ErrorCode() != -32001 {
+		t.Fatalf("unpermitted module error mismatch: have %v, want code -32001", err)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("meta information call failed: %v", err)
+	}
+	// Subscriptions to permitted modules work over the authenticated connection
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+	select {
+	case <-nc:
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timed out")
+	}
+}
+
+// Tests that the HTTP client mints a fresh token for every request.
+func TestJWTAuthFresh(t *testing.T) {
+	t.Parallel()
+
+	var tokens []string
+	httpsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		w.Header().Set("content-type", contentType)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	}))
+	defer httpsrv.Close()
+
+	client, err := DialOptions(context.Background(), httpsrv.URL, WithHTTPAuth(NewJWTAuth(testJWTSecret)))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		client.Call(nil, "test_echo")
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("request count mismatch: have %d, want 2", len(tokens))
+	}
+	for i, token := range tokens {
+		claims, err := parseJWT(testJWTSecret, strings.TrimPrefix(token, "Bearer "), time.Now())
+		if err != nil {
+			t.Errorf("request %d: invalid token %q: %v", i, token, err)
+		} else if len(claims.Modules) != 0 {
+			t.Errorf("request %d: unexpected module claims: %v", i, claims.Modules)
+		}
+	}
+}
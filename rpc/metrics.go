@@ -0,0 +1,49 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the metrics collected by the RPC server.
+
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	rpcRequestCounter     = metrics.NewRegisteredCounter("rpc/requests", nil)      // Counter of all served method calls
+	rpcSuccessCounter     = metrics.NewRegisteredCounter("rpc/success", nil)       // Counter of successfully served method calls
+	rpcFailureCounter     = metrics.NewRegisteredCounter("rpc/failure", nil)       // Counter of method calls returning an error
+	rpcServingTimer       = metrics.NewRegisteredTimer("rpc/duration/all", nil)    // Timer measuring the execution of all method calls
+	rpcSubscriptionGauge  = metrics.NewRegisteredGauge("rpc/subscriptions", nil)   // Gauge tracking the active server subscriptions
+	rpcWebsocketGauge     = metrics.NewRegisteredGauge("rpc/ws/connections", nil)  // Gauge tracking the open websocket connections
+	rpcLimitExceededMeter = metrics.NewRegisteredMeter("rpc/limits/exceeded", nil) // Meter counting requests rejected due to resource limits
+
+	rpcSubscriptions  int64 // Number of active server subscriptions, accessed atomically
+	rpcWebsocketConns int64 // Number of open websocket connections, accessed atomically
+)
+
+// newRPCServingTimer returns the timer measuring the execution of the given
+// method, separately tracking successful and failed calls. The timer's count
+// doubles as the per-method request (or error) counter.
+func newRPCServingTimer(method string, success bool) metrics.Timer {
+	flag := "success"
+	if !success {
+		flag = "failure"
+	}
+	return metrics.GetOrRegisterTimer(fmt.Sprintf("rpc/duration/%s/%s", method, flag), nil)
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HTTPRoute is a handler served under a path prefix of an HTTP endpoint.
+type HTTPRoute struct {
+	Prefix    string       // Path prefix of the route, empty for the root path
+	Handler   http.Handler // Handler serving the requests matching the prefix
+	Websocket bool         // Whether the route only serves websocket upgrade requests
+}
+
+// httpRouter dispatches HTTP requests to the route with the longest matching
+// path prefix. Websocket upgrade requests are preferably dispatched to websocket
+// routes, allowing plain and websocket RPC to be served under the same path.
+type httpRouter struct {
+	routes []HTTPRoute
+}
+
+// newHTTPRouter creates a router for the given routes, normalizing their path
+// prefixes and rejecting duplicate ones.
+func newHTTPRouter(routes []HTTPRoute) (*httpRouter, error) {
+	router := new(httpRouter)
+	for _, route := range routes {
+		route.Prefix = normalizePathPrefix(route.Prefix)
+		for _, have := range router.routes {
+			if have.Prefix == route.Prefix && have.Websocket == route.Websocket {
+				return nil, fmt.Errorf("duplicate HTTP route for path prefix %q", route.Prefix)
+			}
+		}
+		router.routes = append(router.routes, route)
+	}
+	return router, nil
+}
+
+// normalizePathPrefix converts a configured path prefix into the canonical form
+// with a leading and without a trailing slash, the root path being "/".
+func normalizePathPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return "/"
+	}
+	return "/" + prefix
+}
+
+// ServeHTTP implements http.Handler, dispatching the request to the best route.
+func (r *httpRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if route := r.match(req.URL.Path, isWebsocket(req)); route != nil {
+		route.Handler.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// match returns the route with the longest prefix matching the given path. If
+// websocket is set, websocket routes take precedence over plain ones, otherwise
+// they are not considered at all.
+func (r *httpRouter) match(path string, websocket bool) *HTTPRoute {
+	var best *HTTPRoute
+	for i := range r.routes {
+		route := &r.routes[i]
+		if route.Websocket && !websocket {
+			continue
+		}
+		if !matchPathPrefix(path, route.Prefix) {
+			continue
+		}
+		switch {
+		case best == nil:
+			best = route
+		case len(route.Prefix) > len(best.Prefix):
+			best = route
+		case len(route.Prefix) == len(best.Prefix) && route.Websocket:
+			best = route
+		}
+	}
+	return best
+}
+
+// matchPathPrefix reports whether the path lies under the given (normalized)
+// prefix, which only matches at path segment boundaries.
+func matchPathPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// isWebsocket reports whether the request asks for a websocket upgrade.
+func isWebsocket(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http"
+	"testing"
+)
+
+// Tests that requests are dispatched to the longest matching path prefix, with
+// websocket routes only considered (and preferred) for upgrade requests.
+func TestHTTPRouterMatch(t *testing.T) {
+	t.Parallel()
+
+	router, err := newHTTPRouter([]HTTPRoute{
+		{Prefix: ""},
+		{Prefix: "", Websocket: true},
+		{Prefix: "/graphql/"},
+		{Prefix: "ws", Websocket: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	tests := []struct {
+		path      string
+		websocket bool
+		want      int
+	}{
+		{"/", false, 0},
+		{"/", true, 1},
+		{"/graphql", false, 2},
+		{"/graphql/ui", false, 2},
+		{"/graphql", true, 2},
+		{"/graphqlx", false, 0},
+		{"/ws", false, 0},
+		{"/ws", true, 3},
+		{"/ws/x", true, 3},
+		{"/wsx", true, 1},
+	}
+	for i, tt := range tests {
+		route := router.match(tt.path, tt.websocket)
+		if route != &router.routes[tt.want] {
+			t.Errorf("test %d: path %q (websocket %v) matched wrong route: have %+v, want %+v", i, tt.path, tt.websocket, route, router.routes[tt.want])
+		}
+	}
+	// Without a root route, unmatched requests are not served
+	router, _ = newHTTPRouter([]HTTPRoute{{Prefix: "/rpc"}})
+	if route := router.match("/other", false); route != nil {
+		t.Errorf("unmatched path routed to %+v", route)
+	}
+}
+
+// Tests that routes serving the same kind of requests on the same path prefix
+// are rejected.
+func TestHTTPRouterDuplicate(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newHTTPRouter([]HTTPRoute{{Prefix: "/rpc"}, {Prefix: "/rpc", Websocket: true}}); err != nil {
+		t.Errorf("plain and websocket routes on the same prefix rejected: %v", err)
+	}
+	if _, err := newHTTPRouter([]HTTPRoute{{Prefix: "/rpc"}, {Prefix: "rpc/"}}); err == nil {
+		t.Error("duplicate route accepted")
+	}
+}
+
+// Tests that HTTP and websocket RPC can be served on the same listener, both
+// under the same and under different path prefixes.
+func TestHTTPRouterShared(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer()
+	defer server.Stop()
+
+	for _, prefixes := range [][2]string{{"", ""}, {"/rpc", "/ws"}} {
+		listener, err := StartHTTPRouter("127.0.0.1:0", DefaultHTTPTimeouts, []HTTPRoute{
+			{Prefix: prefixes[0], Handler: NewHTTPHandler(server, nil, []string{"*"}, nil)},
+			{Prefix: prefixes[1], Handler: NewWSHandler(server, []string{"*"}, nil), Websocket: true},
+		})
+		if err != nil {
+			t.Fatalf("failed to start router: %v", err)
+		}
+		for _, url := range []string{"http://" + listener.Addr().String() + prefixes[0], "ws://" + listener.Addr().String() + prefixes[1]} {
+			client, err := Dial(url)
+			if err != nil {
+				t.Fatalf("failed to dial %s: %v", url, err)
+			}
+			var result Result
+			if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+				t.Errorf("call via %s failed: %v", url, err)
+			} else if result.String != "x" || result.Int != 1 {
+				t.Errorf("call via %s returned wrong result: %+v", url, result)
+			}
+			client.Close()
+		}
+		// Paths outside the routes are not served
+		if prefixes[0] != "" {
+			resp, err := http.Get("http://" + listener.Addr().String() + "/other")
+			if err != nil {
+				t.Fatalf("failed to query unrouted path: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("unrouted path status mismatch: have %d, want %d", resp.StatusCode, http.StatusNotFound)
+			}
+		}
+		listener.Close()
+	}
+}
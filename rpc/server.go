@@ -40,10 +40,24 @@ const (
 	OptionSubscriptions = 1 << iota // support pub sub
 )
 
+// Limits configures the resource limits enforced by a Server on its clients. A zero
+// value disables the respective limit, so no limits are enforced by default.
+//
+// The response size limit is checked after the response has been assembled, so it
+// limits the bandwidth used, not the memory needed to serve a request. ConnCalls
+// only has an effect on connection oriented transports (websocket and IPC), as
+// every HTTP request is served by a new handler.
+type Limits struct {
+	BatchItems   int // Maximum number of requests in a batch
+	ResponseSize int // Maximum size of a response (or all responses of a batch) in bytes
+	ConnCalls    int // Maximum number of concurrently executing calls per connection
+}
+
 // Server is an RPC server.
 type Server struct {
 	services serviceRegistry
 	idgen    func() ID
+	limits   Limits
 	run      int32
 	codecs   mapset.Set
 }
@@ -66,12 +80,24 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimits configures the resource limits enforced on connections. It must be
+// called before the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.limits = limits
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec)
+}
+
+// serveCodec serves the given codec like ServeCodec, deriving the context of all
+// method calls from connCtx.
+func (s *Server) serveCodec(connCtx context.Context, codec ServerCodec) {
 	defer codec.Close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +109,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(connCtx, codec, s.idgen, &s.services, s.limits)
 	<-codec.Closed()
 	c.Close()
 }
@@ -97,7 +123,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.limits)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -19,6 +19,7 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"net"
@@ -45,7 +46,7 @@ func TestServerRegisterName(t *testing.T) {
 		t.Fatalf("Expected service calc to be registered")
 	}
 
-	wantCallbacks := 7
+	wantCallbacks := 8
 	if len(svc.callbacks) != wantCallbacks {
 		t.Errorf("Expected %d callbacks for service 'service', got %d", wantCallbacks, len(svc.callbacks))
 	}
@@ -150,3 +151,98 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+// Tests that batches exceeding the item limit are rejected without being executed.
+func TestServerBatchLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{BatchItems: 2})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	for _, items := range []int{2, 3} {
+		batch := make([]BatchElem, items)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i}, Result: new(Result)}
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("batch of %d: call failed: %v", items, err)
+		}
+		for i, elem := range batch {
+			err, _ := elem.Error.(Error)
+			switch {
+			case items <= 2 && elem.Error != nil:
+				t.Errorf("batch of %d: item %d failed: %v", items, i, elem.Error)
+			case items > 2 && (err == nil || err.ErrorCode() != -32005):
+				t.Errorf("batch of %d: item %d error mismatch: have %v, want limit exceeded", items, i, elem.Error)
+			}
+		}
+	}
+}
+
+// Tests that responses exceeding the size limit are replaced by errors, and that
+// the remainder of a batch is skipped once the limit is reached.
+func TestServerResponseLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{ResponseSize: 50})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Single responses are limited individually
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("small response failed: %v", err)
+	}
+	err := client.Call(&result, "test_echo", strings.Repeat("x", 50), 1)
+	if e, ok := err.(Error); !ok || e.ErrorCode() != -32005 {
+		t.Fatalf("large response error mismatch: have %v, want limit exceeded", err)
+	}
+	// Batch responses are limited in total
+	batch := make([]BatchElem, 3)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i}, Result: new(Result)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch call failed: %v", err)
+	}
+	if batch[0].Error != nil {
+		t.Errorf("first batch item failed: %v", batch[0].Error)
+	}
+	for i, elem := range batch[1:] {
+		if e, ok := elem.Error.(Error); !ok || e.ErrorCode() != -32005 {
+			t.Errorf("batch item %d error mismatch: have %v, want limit exceeded", i+1, elem.Error)
+		}
+	}
+}
+
+// Tests that calls exceeding the per-connection concurrency limit are rejected.
+func TestServerConnCallsLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{ConnCalls: 1})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Occupy the only call slot of the connection. Messages are handled in order,
+	// so the notification is guaranteed to be executing when the call arrives.
+	if err := client.Notify(context.Background(), "test_sleep", 500*time.Millisecond); err != nil {
+		t.Fatalf("failed to send notification: %v", err)
+	}
+	err := client.Call(nil, "test_echo", "x", 1)
+	if e, ok := err.(Error); !ok || e.ErrorCode() != -32005 {
+		t.Fatalf("concurrent call error mismatch: have %v, want limit exceeded", err)
+	}
+	// Once the slot is released, calls are served again
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := client.Call(nil, "test_echo", "x", 1)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("call not served after slot release: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
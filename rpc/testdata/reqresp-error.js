@@ -0,0 +1,4 @@
+// This test checks that the error code and data of method errors are returned.
+
+--> {"jsonrpc":"2.0","id":1,"method":"test_returnError","params":[]}
+<-- {"jsonrpc":"2.0","id":1,"error":{"code":444,"message":"testError","data":"testError data"}}
@@ -81,6 +81,16 @@ func (s *testService) Rets() (string, error) {
 	return "", nil
 }
 
+type testError struct{}
+
+func (testError) Error() string          { return "testError" }
+func (testError) ErrorCode() int         { return 444 }
+func (testError) ErrorData() interface{} { return "testError data" }
+
+func (s *testService) ReturnError() error {
+	return testError{}
+}
+
 func (s *testService) InvalidRets1() (error, string) {
 	return nil, ""
 }
@@ -25,6 +25,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/log"
@@ -62,11 +63,34 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			log.Debug("WebSocket upgrade failed", "err", err)
 			return
 		}
+		// Carry the authenticated claims over into the connection context, the
+		// request context itself is canceled when the handler returns.
+		connCtx := context.Background()
+		if claims, ok := r.Context().Value(jwtClaimsKey{}).(*JWTClaims); ok {
+			connCtx = context.WithValue(connCtx, jwtClaimsKey{}, claims)
+		}
+		rpcWebsocketGauge.Update(atomic.AddInt64(&rpcWebsocketConns, 1))
+		defer func() { rpcWebsocketGauge.Update(atomic.AddInt64(&rpcWebsocketConns, -1)) }()
+
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, OptionMethodInvocation|OptionSubscriptions)
+		s.serveCodec(connCtx, codec)
 	})
 }
 
+// NewWSHandler wraps the server into the handler of a websocket RPC endpoint,
+// accepting connections from the allowed origins. If jwtSecret is non-empty, the
+// handshake needs to be authenticated with a JWT token.
+func NewWSHandler(srv *Server, allowedOrigins []string, jwtSecret []byte) http.Handler {
+	return NewJWTHandler(jwtSecret, srv.WebsocketHandler(allowedOrigins))
+}
+
+// WSOriginValidator returns the origin check of websocket endpoints accepting
+// connections from the allowed origins, for use by websocket services served
+// outside of the RPC server.
+func WSOriginValidator(allowedOrigins []string) func(*http.Request) bool {
+	return wsHandshakeValidator(allowedOrigins)
+}
+
 // wsHandshakeValidator returns a handler that verifies the origin during the
 // websocket upgrade process. When a '*' is specified as an allowed origins all
 // connections are accepted.
@@ -130,6 +154,10 @@ func (e wsHandshakeError) Error() string {
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
 func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, new(clientConfig))
+}
+
+func dialWebsocket(ctx context.Context, endpoint, origin string, cfg *clientConfig) (*Client, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
@@ -140,6 +168,12 @@ func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error
 		WriteBufferPool: wsBufferPool,
 	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		header := cloneHeader(header)
+		if cfg.httpAuth != nil {
+			if err := cfg.httpAuth(header); err != nil {
+				return nil, err
+			}
+		}
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
 			hErr := wsHandshakeError{err: err}
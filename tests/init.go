@@ -19,6 +19,7 @@ package tests
 import (
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -142,6 +143,16 @@ var Forks = map[string]*params.ChainConfig{
 	},
 }
 
+// AvailableForks returns the names of the supported forks, sorted alphabetically.
+func AvailableForks() []string {
+	var forks []string
+	for fork := range Forks {
+		forks = append(forks, fork)
+	}
+	sort.Strings(forks)
+	return forks
+}
+
 // UnsupportedForkError is returned when a test requests a fork that isn't implemented.
 type UnsupportedForkError struct {
 	Name string